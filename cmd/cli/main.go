@@ -1,64 +1,170 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/adzimzf/sqlite-go/db"
 	"github.com/adzimzf/sqlite-go/executor"
+	"github.com/adzimzf/sqlite-go/vfs"
 	"log"
 	"os"
 	"strings"
 )
 
-// Usage: your_program.sh sample.db .dbinfo
+// the settings of a new database, see db.CreateOptions
+var (
+	pageSize      = flag.Uint("page-size", db.DefaultPageSize, "page size of a new database")
+	encoding      = flag.String("encoding", "utf8", "text encoding of a new database: utf8, utf16le or utf16be")
+	reservedBytes = flag.Uint("reserved-bytes", 0, "bytes reserved at the end of every page of a new database")
+	applicationID = flag.Uint("application-id", 0, "application id of a new database")
+	userVersion   = flag.Uint("user-version", 0, "user version of a new database")
+	autoVacuum    = flag.String("auto-vacuum", "none", "auto-vacuum mode of a new database: none, full or incremental")
+)
+
+// Usage: your_program.sh [flags] sample.db .dbinfo
+// The .create command creates a new empty database, a statement writing to a missing database creates it too.
 func main() {
-	databaseFilePath := os.Args[1]
-	command := os.Args[2]
-	databaseFile, err := os.Open(databaseFilePath)
+	flag.Parse()
+	databaseFilePath := flag.Arg(0)
+	command := flag.Arg(1)
+
+	// in sqlite the 3rd argument will doesn't start with a dot (.) it's a sql query.
+	var sqlInfo db.QueryInfo
+	openFlags := vfs.OpenReadOnly
+	if !strings.HasPrefix(command, ".") {
+		var err error
+		sqlInfo, err = db.ExtractQueryInfo(command)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if sqlInfo.QueryType != db.SELECT {
+			openFlags = vfs.OpenReadWrite
+		}
+	}
+
+	exists, err := vfs.OS.Exists(databaseFilePath)
 	if err != nil {
 		log.Fatal(err)
 	}
+	if command == ".create" || !exists && openFlags == vfs.OpenReadWrite {
+		database, err := db.Create(vfs.OS, databaseFilePath, createOptions())
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := database.Close(); err != nil {
+			log.Fatal(err)
+		}
+		if command == ".create" {
+			return
+		}
+	}
 
-	databaseHeader, err := db.ReadDatabaseHeader(databaseFile)
+	// a hot journal left behind by a crash must be played back before reading
+	hotJournal, err := vfs.OS.Exists(databaseFilePath + "-journal")
 	if err != nil {
 		log.Fatal(err)
 	}
+	if hotJournal {
+		openFlags = vfs.OpenReadWrite
+	}
 
-	rootPage, err := db.NewTableLeafPage(databaseFile, int(databaseHeader.PageSize), 1)
+	databaseFile, err := vfs.OS.Open(databaseFilePath, openFlags)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	database, err := db.NewDB(databaseFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rootPage, err := database.FindSQLiteMaster()
+	if err != nil {
+		log.Fatal(err)
+	}
 	records, err := rootPage.GetRecords()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// in sqlite the 3rd argument will doesn't start with a dot (.) it's a sql query.
 	if !strings.HasPrefix(command, ".") {
+		switch sqlInfo.QueryType {
+		case db.INSERT:
+			if _, err := executor.ExecuteInsertQuery(database, sqlInfo); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case db.DELETE:
+			if _, err := executor.ExecuteDeleteQuery(database, sqlInfo); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case db.UPDATE:
+			if _, err := executor.ExecuteUpdateQuery(database, sqlInfo); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case db.CREATE_TABLE:
+			if err := executor.ExecuteCreateTable(database, sqlInfo); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case db.DROP_TABLE:
+			if err := executor.ExecuteDropTable(database, sqlInfo); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case db.DROP_INDEX:
+			if err := executor.ExecuteDropIndex(database, sqlInfo); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case db.ALTER_TABLE:
+			if err := executor.ExecuteAlterTable(database, sqlInfo); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case db.VACUUM:
+			if err := executor.ExecuteVacuum(database, sqlInfo); err != nil {
+				log.Fatal(err)
+			}
+			return
+		case db.PRAGMA:
+			rows, err := executor.ExecutePragma(database, sqlInfo)
+			for _, row := range rows {
+				fmt.Println(row.String())
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
 
-		sqlInfo, err := db.ExtractQueryInfo(command)
+		if err := database.BeginRead(); err != nil {
+			log.Fatal(err)
+		}
+		defer database.EndRead()
+		query, err := executor.PrepareSelectQuery(database, sqlInfo)
 		if err != nil {
-			log.Println(err)
+			log.Fatal(err)
 		}
-		rows, err := executor.ExecuteSelectQuery(databaseFile, sqlInfo)
+		fmt.Println(db.HeaderString(sqlInfo.SelectFields))
+		err = query.Stream(func(row db.RecordTuple) error {
+			fmt.Println(row.String())
+			return nil
+		})
 		if err != nil {
-			return
+			log.Fatal(err)
 		}
-
-		fmt.Println(rows.RowsString(sqlInfo.SelectFields))
+		fmt.Println()
 
 		return
 	}
 	switch command {
 	case ".dbinfo":
-
-		// You can use print statements as follows for debugging, they'll be visible when running tests.
-		fmt.Println("Logs from your program will appear here!")
-
-		// Uncomment this to pass the first stage
-		fmt.Printf("database page size: %v\n", databaseHeader.PageSize)
-
-		fmt.Printf("number of tables: %v\n", len(records))
+		if err := database.WriteDBInfo(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
 	case ".tables":
 		for _, record := range records {
 			data, f, err := record.FieldData(0)
@@ -93,3 +199,36 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// createOptions returns the settings of a new database given by the flags.
+func createOptions() db.CreateOptions {
+	encodings := map[string]db.TextEncoding{
+		db.EncodingUTF8.String():    db.EncodingUTF8,
+		db.EncodingUTF16le.String(): db.EncodingUTF16le,
+		db.EncodingUTF16be.String(): db.EncodingUTF16be,
+	}
+	textEncoding, ok := encodings[strings.ToLower(*encoding)]
+	if !ok {
+		log.Fatalf("unknown encoding: %s", *encoding)
+	}
+	if *reservedBytes > 255 {
+		log.Fatalf("too many reserved bytes: %d", *reservedBytes)
+	}
+	autoVacuumModes := map[string]db.AutoVacuumMode{
+		"none":        db.AutoVacuumNone,
+		"full":        db.AutoVacuumFull,
+		"incremental": db.AutoVacuumIncremental,
+	}
+	autoVacuumMode, ok := autoVacuumModes[strings.ToLower(*autoVacuum)]
+	if !ok {
+		log.Fatalf("unknown auto-vacuum mode: %s", *autoVacuum)
+	}
+	return db.CreateOptions{
+		PageSize:      uint32(*pageSize),
+		Encoding:      textEncoding,
+		ReservedBytes: byte(*reservedBytes),
+		ApplicationID: uint32(*applicationID),
+		UserVersion:   uint32(*userVersion),
+		AutoVacuum:    autoVacuumMode,
+	}
+}
@@ -7,4 +7,10 @@ const (
 	SqliteMasterName   = "sqlite_master"
 	SqliteInternalName = "sqlite_sequence"
 	DefaultPageSize    = 4096
+	// SQLiteVersionNumber is the sqlite version which file format is written,
+	// it's stored in the header of every database written to.
+	SQLiteVersionNumber = 3046000
+	// PendingByte is the offset of the byte locked by the sqlite file locks,
+	// the page holding it is never used.
+	PendingByte = 0x40000000
 )
@@ -0,0 +1,490 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// AutoVacuumMode tells whether the free pages of the database are given back to the file system.
+// See https://www.sqlite.org/pragma.html#pragma_auto_vacuum
+type AutoVacuumMode int
+
+const (
+	// AutoVacuumNone keeps the free pages in the freelist until VACUUM.
+	AutoVacuumNone AutoVacuumMode = 0
+	// AutoVacuumFull moves the last pages to the free pages and truncates the file on every commit.
+	AutoVacuumFull AutoVacuumMode = 1
+	// AutoVacuumIncremental only truncates the file on PRAGMA incremental_vacuum.
+	AutoVacuumIncremental AutoVacuumMode = 2
+)
+
+// AutoVacuum returns the auto-vacuum mode of the database.
+func (d *DB) AutoVacuum() AutoVacuumMode {
+	switch {
+	case !d.pager.autoVacuum():
+		return AutoVacuumNone
+	case d.pager.header.IncrementalVacuum != 0:
+		return AutoVacuumIncremental
+	}
+	return AutoVacuumFull
+}
+
+// SetAutoVacuum changes the auto-vacuum mode of the database. A database switches between the full
+// and the incremental modes right away, but like sqlite the pointer-map pages are only added or
+// removed when the database is rebuilt, so turning auto-vacuum on or off is done by the next VACUUM.
+func (d *DB) SetAutoVacuum(mode AutoVacuumMode) error {
+	if mode < AutoVacuumNone || mode > AutoVacuumIncremental {
+		return fmt.Errorf("invalid auto-vacuum mode: %d", mode)
+	}
+	current := d.AutoVacuum()
+	if (mode == AutoVacuumNone) != (current == AutoVacuumNone) {
+		d.nextAutoVacuum = &mode
+		return nil
+	}
+	d.nextAutoVacuum = nil
+	if mode == current {
+		return nil
+	}
+	if err := d.pager.Begin(); err != nil {
+		return err
+	}
+	d.pager.header.IncrementalVacuum = 0
+	if mode == AutoVacuumIncremental {
+		d.pager.header.IncrementalVacuum = 1
+	}
+	return d.pager.Commit()
+}
+
+// IncrementalVacuum truncates the file of a database in the incremental auto-vacuum mode by up to
+// pages free pages in a single transaction, by every free page when pages is 0 or less.
+// It does nothing in the other modes.
+// See https://www.sqlite.org/pragma.html#pragma_incremental_vacuum
+func (d *DB) IncrementalVacuum(pages int) error {
+	if err := d.pager.Begin(); err != nil {
+		return err
+	}
+	if err := d.pager.IncrementalVacuum(pages); err != nil {
+		d.pager.Rollback()
+		return err
+	}
+	return d.pager.Commit()
+}
+
+// IncrementalVacuum removes up to pages free pages from the end of the database, every free page
+// when pages is 0 or less. A free last page is taken out of the freelist, any other last page is
+// moved to a free page which stays in the file once every free page has been removed.
+// It must be called within a write transaction.
+func (p *Pager) IncrementalVacuum(pages int) error {
+	if !p.autoVacuum() || p.header.IncrementalVacuum == 0 || p.header.FreelistCount == 0 {
+		return nil
+	}
+	finalSize, err := p.vacuumedSize()
+	if err != nil {
+		return err
+	}
+	for i := 0; pages <= 0 || i < pages; i++ {
+		if p.header.FreelistCount == 0 || p.header.DatabaseSize <= finalSize {
+			return nil
+		}
+		if err := p.vacuumLastPage(finalSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vacuumLastPage removes the last page of the database: a free page is taken out of the freelist,
+// any other page is moved to a free page before finalSize first.
+func (p *Pager) vacuumLastPage(finalSize uint32) error {
+	last := p.header.DatabaseSize
+	entryType, parent, err := p.readPtrmap(last)
+	if err != nil {
+		return err
+	}
+	switch entryType {
+	case ptrmapRootPage:
+		return fmt.Errorf("root page %d is after the largest root page %d", last, p.header.LargestRootPage)
+	case ptrmapFreePage:
+		if err := p.removeFreePage(last); err != nil {
+			return err
+		}
+	default:
+		free, err := p.freelistPages()
+		if err != nil {
+			return err
+		}
+		target := uint32(0)
+		for _, pageNumber := range free {
+			if pageNumber <= finalSize {
+				target = pageNumber
+				break
+			}
+		}
+		if target == 0 {
+			return fmt.Errorf("no free page left before page %d", finalSize)
+		}
+		if err := p.removeFreePage(target); err != nil {
+			return err
+		}
+		if err := p.relocatePage(last, target, entryType, parent); err != nil {
+			return err
+		}
+	}
+
+	size := last - 1
+	for size == p.pendingBytePage() || p.isPtrmapPage(size) {
+		size--
+	}
+	p.header.DatabaseSize = size
+	return nil
+}
+
+// autoVacuumCommit moves the pages after the end the database has without any free page to the
+// free pages before it, then drops the freelist and truncates the database.
+func (p *Pager) autoVacuumCommit() error {
+	finalSize, err := p.vacuumedSize()
+	if err != nil {
+		return err
+	}
+	free, err := p.freelistPages()
+	if err != nil {
+		return err
+	}
+	// the freelist is dropped at the end, so the free pages are used without updating it
+	var targets []uint32
+	for _, pageNumber := range free {
+		if pageNumber <= finalSize {
+			targets = append(targets, pageNumber)
+		}
+	}
+
+	for pageNumber := p.header.DatabaseSize; pageNumber > finalSize; pageNumber-- {
+		if pageNumber == p.pendingBytePage() || p.isPtrmapPage(pageNumber) {
+			continue
+		}
+		entryType, parent, err := p.readPtrmap(pageNumber)
+		if err != nil {
+			return err
+		}
+		if entryType == ptrmapFreePage {
+			continue
+		}
+		if entryType == ptrmapRootPage {
+			return fmt.Errorf("root page %d is after the largest root page %d", pageNumber, p.header.LargestRootPage)
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("no free page left before page %d", finalSize)
+		}
+		target := targets[len(targets)-1]
+		targets = targets[:len(targets)-1]
+		if err := p.relocatePage(pageNumber, target, entryType, parent); err != nil {
+			return err
+		}
+	}
+	p.header.FreelistTrunkPage = 0
+	p.header.FreelistCount = 0
+	p.header.DatabaseSize = finalSize
+	return nil
+}
+
+// vacuumedSize returns the size of the database once every free page has been removed, without
+// the pointer-map pages which aren't needed anymore either.
+func (p *Pager) vacuumedSize() (uint32, error) {
+	size := int64(p.header.DatabaseSize)
+	free := int64(p.header.FreelistCount)
+	if free >= size {
+		return 0, fmt.Errorf("the freelist has %d pages in a database of %d pages", free, size)
+	}
+	entries := int64(p.limits.usableSize / ptrmapEntrySize)
+	ptrmaps := (free - size + int64(p.ptrmapPage(uint32(size))) + entries) / entries
+	finalSize := size - free - ptrmaps
+	pending := int64(p.pendingBytePage())
+	if size > pending && finalSize < pending {
+		finalSize--
+	}
+	for finalSize > 1 && (finalSize == pending || p.isPtrmapPage(uint32(finalSize))) {
+		finalSize--
+	}
+	if finalSize < 1 || finalSize > size {
+		return 0, fmt.Errorf("invalid size of the vacuumed database: %d pages", finalSize)
+	}
+	return uint32(finalSize), nil
+}
+
+// allocateRoot returns the page after the largest root page for the root of a new b-tree, the root
+// pages of an auto-vacuum database are the first pages so they never move when the file shrinks.
+// A page of another b-tree is moved to a new page first.
+func (p *Pager) allocateRoot() (uint32, error) {
+	root := p.header.LargestRootPage + 1
+	for root == p.pendingBytePage() || p.isPtrmapPage(root) {
+		root++
+	}
+	if root > p.header.DatabaseSize {
+		pageNumber, err := p.extend()
+		if err != nil {
+			return 0, err
+		}
+		if pageNumber != root {
+			return 0, fmt.Errorf("root page %d is not the last page %d", root, pageNumber)
+		}
+	} else {
+		entryType, parent, err := p.readPtrmap(root)
+		if err != nil {
+			return 0, err
+		}
+		switch entryType {
+		case ptrmapRootPage:
+			return 0, fmt.Errorf("root page %d is after the largest root page %d", root, p.header.LargestRootPage)
+		case ptrmapFreePage:
+			if err := p.removeFreePage(root); err != nil {
+				return 0, err
+			}
+		default:
+			pageNumber, err := p.Allocate()
+			if err != nil {
+				return 0, err
+			}
+			if err := p.relocatePage(root, pageNumber, entryType, parent); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := p.Write(root, make([]byte, p.limits.pageSize)); err != nil {
+		return 0, err
+	}
+	p.header.LargestRootPage = root
+	return root, p.writePtrmap(root, ptrmapRootPage, 0)
+}
+
+// DropBTree returns every page of the b-tree rooted at the page to the freelist, like FreeBTree.
+// The root pages of an auto-vacuum database must stay the first pages: the largest root page moves
+// to the freed root and its former page number is returned so the schema can be updated,
+// 0 when no root page moved. It must be called within a write transaction.
+func (p *Pager) DropBTree(rootPage uint32) (uint32, error) {
+	if !p.autoVacuum() {
+		return 0, p.FreeBTree(rootPage)
+	}
+	largest := p.header.LargestRootPage
+	if rootPage > largest {
+		return 0, fmt.Errorf("root page %d is after the largest root page %d", rootPage, largest)
+	}
+	if err := p.freeBTreePages(rootPage); err != nil {
+		return 0, err
+	}
+	moved := uint32(0)
+	if rootPage != largest {
+		if err := p.relocatePage(largest, rootPage, ptrmapRootPage, 0); err != nil {
+			return 0, err
+		}
+		moved = largest
+	}
+	if err := p.FreePage(largest); err != nil {
+		return 0, err
+	}
+
+	largest--
+	for largest == p.pendingBytePage() || p.isPtrmapPage(largest) {
+		largest--
+	}
+	p.header.LargestRootPage = largest
+	return moved, nil
+}
+
+// relocatePage copies the page to another page, entryType and parent are its pointer-map entry.
+// The pointer-map entries of the pages it points to and the pointer to it in its parent are updated,
+// the former page is left as is.
+func (p *Pager) relocatePage(from, to uint32, entryType byte, parent uint32) error {
+	page, err := p.Get(from)
+	if err != nil {
+		return err
+	}
+	data := make([]byte, len(page.Data))
+	copy(data, page.Data)
+	p.Unpin(page)
+	if err := p.Write(to, data); err != nil {
+		return err
+	}
+	if err := p.writePtrmap(to, entryType, parent); err != nil {
+		return err
+	}
+
+	switch entryType {
+	case ptrmapRootPage, ptrmapBTree:
+		node, err := p.loadNode(to)
+		if err != nil {
+			return err
+		}
+		if err := p.writeChildPtrmaps(node); err != nil {
+			return err
+		}
+	case ptrmapOverflow1, ptrmapOverflow2:
+		if next := binary.BigEndian.Uint32(data[:4]); next != 0 {
+			if err := p.writePtrmap(next, ptrmapOverflow2, to); err != nil {
+				return err
+			}
+		}
+	}
+	if entryType == ptrmapRootPage {
+		return nil
+	}
+	return p.replacePointer(parent, from, to, entryType)
+}
+
+// replacePointer replaces the pointer to the page from with to in its parent page.
+func (p *Pager) replacePointer(parent, from, to uint32, entryType byte) error {
+	if entryType == ptrmapOverflow2 {
+		page, err := p.Get(parent)
+		if err != nil {
+			return err
+		}
+		defer p.Unpin(page)
+		if binary.BigEndian.Uint32(page.Data[:4]) != from {
+			return fmt.Errorf("overflow page %d is not the next page of overflow page %d", from, parent)
+		}
+		data := make([]byte, len(page.Data))
+		copy(data, page.Data)
+		binary.BigEndian.PutUint32(data[:4], to)
+		return p.Write(parent, data)
+	}
+
+	node, err := p.loadNode(parent)
+	if err != nil {
+		return err
+	}
+	found := false
+	for i, cell := range node.cells {
+		offset := -1
+		switch entryType {
+		case ptrmapBTree:
+			if !node.isLeaf() && node.child(i) == from {
+				offset = 0
+			}
+		case ptrmapOverflow1:
+			overflow, err := p.cellOverflowPage(node.pageType, cell)
+			if err != nil {
+				return err
+			}
+			if overflow == from {
+				offset = len(cell) - 4
+			}
+		}
+		if offset >= 0 {
+			newCell := make([]byte, len(cell))
+			copy(newCell, cell)
+			binary.BigEndian.PutUint32(newCell[offset:], to)
+			node.cells[i] = newCell
+			found = true
+		}
+	}
+	if entryType == ptrmapBTree && !node.isLeaf() && node.rightMost == from {
+		node.rightMost = to
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("page %d is not referenced by its parent page %d", from, parent)
+	}
+	return p.writeNode(node)
+}
+
+// freelistPages returns the pages of the freelist, every trunk page followed by its leaves.
+func (p *Pager) freelistPages() ([]uint32, error) {
+	var pages []uint32
+	for trunkNumber := p.header.FreelistTrunkPage; trunkNumber != 0; {
+		if trunkNumber == 1 || trunkNumber > p.header.DatabaseSize || len(pages) >= int(p.header.DatabaseSize) {
+			return nil, fmt.Errorf("invalid freelist trunk page: %d", trunkNumber)
+		}
+		trunk, err := p.Get(trunkNumber)
+		if err != nil {
+			return nil, err
+		}
+		leafCount := binary.BigEndian.Uint32(trunk.Data[4:8])
+		if int(leafCount) > p.limits.usableSize/4-2 {
+			p.Unpin(trunk)
+			return nil, fmt.Errorf("freelist trunk page %d has too many leaves: %d", trunkNumber, leafCount)
+		}
+		pages = append(pages, trunkNumber)
+		for i := uint32(0); i < leafCount; i++ {
+			pages = append(pages, binary.BigEndian.Uint32(trunk.Data[8+4*i:]))
+		}
+		next := binary.BigEndian.Uint32(trunk.Data[0:4])
+		p.Unpin(trunk)
+		trunkNumber = next
+	}
+	return pages, nil
+}
+
+// removeFreePage takes the page out of the freelist. A leaf is replaced by the last leaf of its trunk
+// page, a trunk page by its last leaf, which takes the other leaves, or by the next trunk page.
+func (p *Pager) removeFreePage(pageNumber uint32) error {
+	previous := uint32(0)
+	for trunkNumber := p.header.FreelistTrunkPage; trunkNumber != 0; {
+		if trunkNumber == 1 || trunkNumber > p.header.DatabaseSize {
+			return fmt.Errorf("invalid freelist trunk page: %d", trunkNumber)
+		}
+		trunk, err := p.Get(trunkNumber)
+		if err != nil {
+			return err
+		}
+		p.Unpin(trunk)
+		next := binary.BigEndian.Uint32(trunk.Data[0:4])
+		leafCount := binary.BigEndian.Uint32(trunk.Data[4:8])
+		if int(leafCount) > p.limits.usableSize/4-2 {
+			return fmt.Errorf("freelist trunk page %d has too many leaves: %d", trunkNumber, leafCount)
+		}
+
+		if trunkNumber == pageNumber {
+			replacement := next
+			if leafCount > 0 {
+				replacement = binary.BigEndian.Uint32(trunk.Data[4+4*leafCount:])
+				data := make([]byte, p.limits.pageSize)
+				binary.BigEndian.PutUint32(data[0:4], next)
+				binary.BigEndian.PutUint32(data[4:8], leafCount-1)
+				copy(data[8:], trunk.Data[8:4+4*leafCount])
+				if err := p.Write(replacement, data); err != nil {
+					return err
+				}
+			}
+			if err := p.setNextTrunk(previous, replacement); err != nil {
+				return err
+			}
+			p.header.FreelistCount--
+			return nil
+		}
+
+		for i := uint32(0); i < leafCount; i++ {
+			if binary.BigEndian.Uint32(trunk.Data[8+4*i:]) != pageNumber {
+				continue
+			}
+			data := make([]byte, len(trunk.Data))
+			copy(data, trunk.Data)
+			copy(data[8+4*i:12+4*i], data[4+4*leafCount:8+4*leafCount])
+			binary.BigEndian.PutUint32(data[4+4*leafCount:], 0)
+			binary.BigEndian.PutUint32(data[4:8], leafCount-1)
+			if err := p.Write(trunkNumber, data); err != nil {
+				return err
+			}
+			p.header.FreelistCount--
+			return nil
+		}
+		previous, trunkNumber = trunkNumber, next
+	}
+	return fmt.Errorf("page %d is not in the freelist", pageNumber)
+}
+
+// setNextTrunk points the trunk page, or the header when it's 0, to the next trunk page.
+func (p *Pager) setNextTrunk(trunkNumber, next uint32) error {
+	if trunkNumber == 0 {
+		p.header.FreelistTrunkPage = next
+		return nil
+	}
+	trunk, err := p.Get(trunkNumber)
+	if err != nil {
+		return err
+	}
+	defer p.Unpin(trunk)
+	data := make([]byte, len(trunk.Data))
+	copy(data, trunk.Data)
+	binary.BigEndian.PutUint32(data[0:4], next)
+	return p.Write(trunkNumber, data)
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoVacuum(t *testing.T) {
+	database, err := CreateDB(vfs.NewMemFile(nil), CreateOptions{PageSize: 512, AutoVacuum: AutoVacuumIncremental})
+	require.NoError(t, err)
+	require.Equal(t, AutoVacuumIncremental, database.AutoVacuum())
+	for _, query := range []string{"create table fruits (name text)", "create table seeds (name text)"} {
+		info, err := ExtractQueryInfo(query)
+		require.NoError(t, err)
+		require.NoError(t, database.CreateTable(info.DDL.Table, false))
+	}
+	// page 2 is the first pointer-map page
+	schema, err := database.FindTableSchema("seeds")
+	require.NoError(t, err)
+	require.Equal(t, int64(4), schema.PageID)
+
+	fruits, err := database.FindTablePage("fruits")
+	require.NoError(t, err)
+	seeds, err := database.FindTablePage("seeds")
+	require.NoError(t, err)
+	record, err := EncodeRecord([]any{string(bytes.Repeat([]byte("x"), 1500))}, EncodingUTF8)
+	require.NoError(t, err)
+	require.NoError(t, database.Begin())
+	for rowID := int64(1); rowID <= 200; rowID++ {
+		require.NoError(t, fruits.Insert(rowID, record))
+		require.NoError(t, seeds.Insert(rowID, record))
+	}
+	require.NoError(t, database.Commit())
+	size := database.Header().DatabaseSize
+	require.Greater(t, size, uint32(1000))
+
+	// the incremental mode keeps the free pages until they are asked for
+	require.NoError(t, database.DropTable("fruits", false))
+	require.Equal(t, size, database.Header().DatabaseSize)
+	free := database.Header().FreelistCount
+	require.NoError(t, database.IncrementalVacuum(10))
+	require.Equal(t, size-10, database.Header().DatabaseSize)
+	require.Equal(t, free-10, database.Header().FreelistCount)
+	messages, err := database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+
+	// the largest root page moved to the root of the dropped table
+	schema, err = database.FindTableSchema("seeds")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), schema.PageID)
+	require.Equal(t, uint32(3), database.Header().LargestRootPage)
+
+	// the full mode truncates the file on every commit
+	require.NoError(t, database.SetAutoVacuum(AutoVacuumFull))
+	require.Equal(t, uint32(0), database.Header().FreelistCount)
+	seeds, err = database.FindTablePage("seeds")
+	require.NoError(t, err)
+	require.NoError(t, database.Begin())
+	for rowID := int64(1); rowID <= 150; rowID++ {
+		require.NoError(t, seeds.Delete(rowID))
+	}
+	require.NoError(t, database.Commit())
+	require.Equal(t, uint32(0), database.Header().FreelistCount)
+	require.Less(t, database.Header().DatabaseSize, size/4)
+	messages, err = database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+
+	// turning auto-vacuum off waits for VACUUM
+	require.NoError(t, database.SetAutoVacuum(AutoVacuumNone))
+	require.Equal(t, AutoVacuumFull, database.AutoVacuum())
+	require.NoError(t, database.Vacuum())
+	require.Equal(t, AutoVacuumNone, database.AutoVacuum())
+	messages, err = database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
@@ -0,0 +1,311 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+)
+
+// IndexPage represents an index b-tree page, either an interior or a leaf page.
+// Unlike the table b-tree, the interior cells hold real keys as well.
+type IndexPage struct {
+	Header       TableHeader
+	CellPointers []uint16
+	Cells        []IndexCell
+	encoding     TextEncoding
+}
+
+// IndexCell holds a key record, the last field of the key is the rowid of the table row.
+// LeftChild is only set for the cells of an interior page.
+// The payload beyond the local part is stored from the OverflowPage.
+type IndexCell struct {
+	LeftChild    uint32
+	Size         int64
+	Payload      []byte
+	OverflowPage uint32
+}
+
+// NewIndexPage reads the index page and the overflow pages of its cells.
+func NewIndexPage(pager *Pager, pageNumber int) (*IndexPage, error) {
+	pinned, err := pager.Get(uint32(pageNumber))
+	if err != nil {
+		return nil, err
+	}
+	defer pager.Unpin(pinned)
+	page, err := parseIndexPage(pinned.Data, pageNumber, pager.limits)
+	if err != nil {
+		return nil, err
+	}
+	page.encoding = pager.encoding
+
+	for i, cell := range page.Cells {
+		if cell.OverflowPage == 0 {
+			continue
+		}
+		page.Cells[i].Payload, err = readOverflowPayload(pager, cell.Payload, cell.OverflowPage, cell.Size)
+		if err != nil {
+			return nil, fmt.Errorf("page %d cell %d: %w", pageNumber, i, err)
+		}
+	}
+	return page, nil
+}
+
+func parseIndexPage(pageData []byte, pageNumber int, limits payloadLimits) (*IndexPage, error) {
+	page, err := parseBTreePage(pageData, pageNumber)
+	if err != nil {
+		return nil, err
+	}
+	if page.Header.PageType != BTREE_LEAF_INDEX && page.Header.PageType != BTREE_INTERNAL_PAGE {
+		return nil, fmt.Errorf("page %d isn't an index page: type %d", pageNumber, page.Header.PageType)
+	}
+
+	index := &IndexPage{
+		Header:       page.Header,
+		CellPointers: page.CellPointers,
+	}
+	for i := range page.CellPointers {
+		cell, err := page.Cell(i, limits)
+		if err != nil {
+			return nil, err
+		}
+		index.Cells = append(index.Cells, IndexCell{
+			LeftChild:    cell.LeftChild,
+			Size:         cell.Size,
+			Payload:      cell.Payload,
+			OverflowPage: cell.OverflowPage,
+		})
+	}
+	return index, nil
+}
+
+// IsLeaf reports whether the page is an index leaf page.
+func (p *IndexPage) IsLeaf() bool {
+	return p.Header.PageType == BTREE_LEAF_INDEX
+}
+
+// Record decodes the key of the cell i, the RowID is taken from the last field of the key.
+func (p *IndexPage) Record(i int) (Record, error) {
+	cell := p.Cells[i]
+	header, err := NewRecordHeader(cell.Payload)
+	if err != nil {
+		return Record{}, err
+	}
+	record := Record{Header: header, Payload: cell.Payload, Encoding: p.encoding}
+	if len(header.Fields) == 0 {
+		return Record{}, fmt.Errorf("page %d cell %d has an empty index key", p.Header.PageNumber, i)
+	}
+
+	rowID, err := record.IntField(len(header.Fields) - 1)
+	if err != nil {
+		return Record{}, err
+	}
+	record.RowID = rowID
+	return record, nil
+}
+
+// IndexBTree is an index b-tree starting from its root page.
+type IndexBTree struct {
+	pager     *Pager
+	RootPage  int
+	Name      string
+	TableName string
+	Unique    bool
+	Columns   []IndexColumnInfo
+	// withoutRowID is set for a WITHOUT ROWID table and its indexes, the keys end with the PRIMARY KEY
+	withoutRowID bool
+}
+
+func NewIndexBTree(pager *Pager, rootPage int) *IndexBTree {
+	return &IndexBTree{pager: pager, RootPage: rootPage}
+}
+
+// Cursor returns a cursor over the index entries, it isn't positioned yet.
+func (t *IndexBTree) Cursor() *BTreeCursor {
+	cursor := NewBTreeCursor(t.pager, t.RootPage)
+	cursor.desc = t.desc()
+	cursor.withoutRowID = t.withoutRowID
+	return cursor
+}
+
+// desc tells which columns of the key are sorted in descending order.
+func (t *IndexBTree) desc() []bool {
+	desc := make([]bool, len(t.Columns))
+	for i, column := range t.Columns {
+		desc[i] = column.Desc
+	}
+	return desc
+}
+
+// Scan calls fn for every index entry ordered by key, the scan stops as soon as fn returns false.
+func (t *IndexBTree) Scan(fn func(record Record) (bool, error)) error {
+	cursor := t.Cursor()
+	defer cursor.Close()
+	err := cursor.First()
+	for ; err == nil && cursor.Valid(); err = cursor.Next() {
+		record, err := cursor.Record()
+		if err != nil {
+			return err
+		}
+		kontinue, err := fn(record)
+		if err != nil || !kontinue {
+			return err
+		}
+	}
+	return err
+}
+
+// Seek calls fn for every index entry which key starts with the given prefix ordered by key.
+// The first matching entry is found with a binary search on every level of the tree.
+func (t *IndexBTree) Seek(prefix []any, fn func(record Record) (bool, error)) error {
+	cursor := t.Cursor()
+	defer cursor.Close()
+	found, err := cursor.SeekKey(prefix)
+	if err != nil || !found {
+		return err
+	}
+	for ; err == nil && cursor.Valid(); err = cursor.Next() {
+		record, err := cursor.Record()
+		if err != nil {
+			return err
+		}
+		cmp, err := compareKey(record, prefix, t.desc())
+		if err != nil || cmp != 0 {
+			return err
+		}
+		kontinue, err := fn(record)
+		if err != nil || !kontinue {
+			return err
+		}
+	}
+	return err
+}
+
+// seek descends from the root to the entry equal to the key or to the leaf where the key belongs.
+// It returns the path to the node, the node and the index of the first cell greater than or equal
+// to the key, found reports whether that cell is the key.
+func (t *IndexBTree) seek(key []any) (path []btreePathEntry, node *btreeNode, i int, found bool, err error) {
+	var searchErr error
+	search := func(node *btreeNode) int {
+		return sort.Search(len(node.cells), func(i int) bool {
+			if searchErr != nil {
+				return true
+			}
+			cmp, err := t.compareCell(node, i, key)
+			if err != nil {
+				searchErr = err
+			}
+			return cmp >= 0
+		})
+	}
+
+	if node, err = t.pager.loadNode(uint32(t.RootPage)); err != nil {
+		return nil, nil, 0, false, err
+	}
+	for {
+		if node.pageType != BTREE_LEAF_INDEX && node.pageType != BTREE_INTERNAL_PAGE {
+			return nil, nil, 0, false, fmt.Errorf("page %d isn't an index page: type %d", node.number, node.pageType)
+		}
+		i = search(node)
+		if searchErr != nil {
+			return nil, nil, 0, false, searchErr
+		}
+		if i < len(node.cells) {
+			cmp, err := t.compareCell(node, i, key)
+			if err != nil {
+				return nil, nil, 0, false, err
+			}
+			found = cmp == 0
+		}
+		if found || node.isLeaf() {
+			return path, node, i, found, nil
+		}
+		if len(path) >= maxBTreeDepth {
+			return nil, nil, 0, false, fmt.Errorf("index b-tree rooted at page %d is deeper than %d pages", t.RootPage, maxBTreeDepth)
+		}
+		path = append(path, btreePathEntry{node: node, childIndex: i})
+		if node, err = t.pager.loadNode(node.child(i)); err != nil {
+			return nil, nil, 0, false, err
+		}
+	}
+}
+
+// compareCell compares the entry of the cell i with the key.
+func (t *IndexBTree) compareCell(node *btreeNode, i int, key []any) (int, error) {
+	cellPayload, err := t.pager.cellPayload(node.pageType, node.cells[i])
+	if err != nil {
+		return 0, err
+	}
+	header, err := NewRecordHeader(cellPayload)
+	if err != nil {
+		return 0, err
+	}
+	return compareKey(Record{Header: header, Payload: cellPayload, Encoding: t.pager.encoding}, key, t.desc())
+}
+
+// Insert adds the key to the index, the last value of the key is the rowid of the table row.
+// It must be called within a write transaction.
+func (t *IndexBTree) Insert(key []any) error {
+	payload, err := EncodeRecord(key, t.pager.encoding)
+	if err != nil {
+		return err
+	}
+
+	// the entries are unique thanks to the rowid, the key goes before the first greater entry
+	path, node, i, found, err := t.seek(key)
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("index %s already has the entry %v", t.Name, key)
+	}
+	cell, err := t.pager.buildCell(BTREE_LEAF_INDEX, 0, 0, payload)
+	if err != nil {
+		return err
+	}
+	node.insertCells(i, cell)
+	return t.pager.balance(path, node)
+}
+
+// Delete removes the key from the index, the last value of the key is the rowid of the table row.
+// An entry of an interior page is replaced by the entry preceding it, the last entry of the
+// right-most leaf of its left subtree. It must be called within a write transaction.
+func (t *IndexBTree) Delete(key []any) error {
+	path, node, i, found, err := t.seek(key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("index %s doesn't have the entry %v", t.Name, key)
+	}
+	if err := t.pager.freeOverflow(node.pageType, node.cells[i]); err != nil {
+		return err
+	}
+	if node.isLeaf() {
+		node.removeCell(i)
+		return t.pager.balance(path, node)
+	}
+
+	interior, cellIndex := node, i
+	path = append(path, btreePathEntry{node: node, childIndex: i})
+	if node, err = t.pager.loadNode(node.child(i)); err != nil {
+		return err
+	}
+	for !node.isLeaf() {
+		if len(path) >= maxBTreeDepth {
+			return fmt.Errorf("index b-tree rooted at page %d is deeper than %d pages", t.RootPage, maxBTreeDepth)
+		}
+		path = append(path, btreePathEntry{node: node, childIndex: len(node.cells)})
+		if node, err = t.pager.loadNode(node.rightMost); err != nil {
+			return err
+		}
+	}
+	if len(node.cells) == 0 {
+		return fmt.Errorf("index %s page %d is empty", t.Name, node.number)
+	}
+
+	// the overflow pages of the predecessor move along with it
+	predecessor := node.cells[len(node.cells)-1]
+	node.removeCell(len(node.cells) - 1)
+	interior.cells[cellIndex] = append(interior.cells[cellIndex][:4:4], predecessor...)
+	interior.dirty = true
+	return t.pager.balance(path, node)
+}
@@ -0,0 +1,266 @@
+package db
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// maxBTreeDepth is the deepest b-tree we are willing to walk,
+// anything deeper is most likely a corrupted file with a page cycle.
+const maxBTreeDepth = 20
+
+// TableInteriorPage represents an interior page of a table b-tree.
+// It doesn't contain any record, only the rowid keys used to find the children.
+type TableInteriorPage struct {
+	Header       TableHeader
+	CellPointers []uint16
+	Cells        []TableInteriorCell
+}
+
+// TableInteriorCell points to the child page containing the rowids
+// less than or equal to RowID.
+type TableInteriorCell struct {
+	LeftChild uint32
+	RowID     int64
+}
+
+func NewTableInteriorPage(pager *Pager, pageNumber int) (*TableInteriorPage, error) {
+	page, err := pager.Get(uint32(pageNumber))
+	if err != nil {
+		return nil, err
+	}
+	defer pager.Unpin(page)
+	return parseTableInteriorPage(page.Data, pageNumber)
+}
+
+func parseTableInteriorPage(pageData []byte, pageNumber int) (*TableInteriorPage, error) {
+	page, err := parseBTreePage(pageData, pageNumber)
+	if err != nil {
+		return nil, err
+	}
+	if page.Header.PageType != BTREE_INTERNAL_TABLE {
+		return nil, fmt.Errorf("page %d isn't a table interior page: type %d", pageNumber, page.Header.PageType)
+	}
+
+	interior := &TableInteriorPage{
+		Header:       page.Header,
+		CellPointers: page.CellPointers,
+	}
+	for i := range page.CellPointers {
+		cell, err := page.Cell(i, payloadLimits{})
+		if err != nil {
+			return nil, err
+		}
+		interior.Cells = append(interior.Cells, TableInteriorCell{
+			LeftChild: cell.LeftChild,
+			RowID:     cell.RowID,
+		})
+	}
+	return interior, nil
+}
+
+// Children returns every child page number from the left-most to the right-most.
+func (t *TableInteriorPage) Children() []uint32 {
+	children := make([]uint32, 0, len(t.Cells)+1)
+	for _, cell := range t.Cells {
+		children = append(children, cell.LeftChild)
+	}
+	return append(children, t.Header.RightMostPointer)
+}
+
+// TableBTree is a table b-tree starting from its root page,
+// the root can either be a leaf page or an interior page.
+type TableBTree struct {
+	pager    *Pager
+	RootPage int
+}
+
+func NewTableBTree(pager *Pager, rootPage int) *TableBTree {
+	return &TableBTree{pager: pager, RootPage: rootPage}
+}
+
+// Cursor returns a cursor over the rows of the table, it isn't positioned yet.
+func (t *TableBTree) Cursor() *BTreeCursor {
+	return NewBTreeCursor(t.pager, t.RootPage)
+}
+
+func (t *TableBTree) GetRecords() ([]Record, error) {
+	return t.GetRecordsFields(nil)
+}
+
+// GetRecordsFields returns every record of the table only for certain fields,
+// an empty filter will return all.
+func (t *TableBTree) GetRecordsFields(fields []int64) ([]Record, error) {
+	var records []Record
+	cursor := t.Cursor()
+	defer cursor.Close()
+	err := cursor.First()
+	for ; err == nil && cursor.Valid(); err = cursor.Next() {
+		record, err := cursor.Record()
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record.SelectFields(fields))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// NewRowID returns the rowid of a new row: one more than the largest rowid of the table.
+// Once the largest possible rowid is used, unused rowids are picked randomly like sqlite does.
+func (t *TableBTree) NewRowID() (int64, error) {
+	cursor := t.Cursor()
+	defer cursor.Close()
+	if err := cursor.Last(); err != nil {
+		return 0, err
+	}
+	if !cursor.Valid() {
+		return 1, nil
+	}
+	maxRowID, err := cursor.RowID()
+	if err != nil {
+		return 0, err
+	}
+	if maxRowID < math.MaxInt64 {
+		return maxRowID + 1, nil
+	}
+
+	for i := 0; i < 100; i++ {
+		rowID := rand.Int63n(math.MaxInt64) + 1
+		found, err := cursor.SeekRowID(rowID)
+		if err != nil {
+			return 0, err
+		}
+		if !found {
+			return rowID, nil
+		}
+	}
+	return 0, fmt.Errorf("database or disk is full")
+}
+
+// seek descends from the root to the leaf where the rowid belongs. It returns the path to the leaf,
+// the leaf and the index of the first cell with a rowid greater than or equal to the rowid.
+func (t *TableBTree) seek(rowID int64) ([]btreePathEntry, *btreeNode, int, error) {
+	var searchErr error
+	search := func(node *btreeNode) int {
+		return sort.Search(len(node.cells), func(i int) bool {
+			cell, _, err := decodeCell(node.pageType, node.cells[i], t.pager.limits)
+			if err != nil && searchErr == nil {
+				searchErr = err
+			}
+			return cell.RowID >= rowID
+		})
+	}
+
+	node, err := t.pager.loadNode(uint32(t.RootPage))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	var path []btreePathEntry
+	for !node.isLeaf() {
+		if len(path) >= maxBTreeDepth {
+			return nil, nil, 0, fmt.Errorf("table b-tree rooted at page %d is deeper than %d pages", t.RootPage, maxBTreeDepth)
+		}
+		i := search(node)
+		if searchErr != nil {
+			return nil, nil, 0, searchErr
+		}
+		path = append(path, btreePathEntry{node: node, childIndex: i})
+		if node, err = t.pager.loadNode(node.child(i)); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	if node.pageType != BTREE_LEAF_TABLE {
+		return nil, nil, 0, fmt.Errorf("page %d isn't a table leaf page: type %d", node.number, node.pageType)
+	}
+
+	i := search(node)
+	if searchErr != nil {
+		return nil, nil, 0, searchErr
+	}
+	return path, node, i, nil
+}
+
+// seekRow is like seek but the rowid must exist.
+func (t *TableBTree) seekRow(rowID int64) ([]btreePathEntry, *btreeNode, int, error) {
+	path, node, i, err := t.seek(rowID)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if found, err := t.cellHasRowID(node, i, rowID); err != nil || !found {
+		if err == nil {
+			err = fmt.Errorf("rowid %d doesn't exist", rowID)
+		}
+		return nil, nil, 0, err
+	}
+	return path, node, i, nil
+}
+
+func (t *TableBTree) cellHasRowID(node *btreeNode, i int, rowID int64) (bool, error) {
+	if i >= len(node.cells) {
+		return false, nil
+	}
+	cell, _, err := decodeCell(node.pageType, node.cells[i], t.pager.limits)
+	if err != nil {
+		return false, err
+	}
+	return cell.RowID == rowID, nil
+}
+
+// Insert adds the record to the table with the rowid, the rowid must not be used yet.
+// It must be called within a write transaction.
+func (t *TableBTree) Insert(rowID int64, record []byte) error {
+	path, node, i, err := t.seek(rowID)
+	if err != nil {
+		return err
+	}
+	if found, err := t.cellHasRowID(node, i, rowID); err != nil || found {
+		if err == nil {
+			err = fmt.Errorf("rowid %d already exists", rowID)
+		}
+		return err
+	}
+
+	cell, err := t.pager.buildCell(BTREE_LEAF_TABLE, 0, rowID, record)
+	if err != nil {
+		return err
+	}
+	node.insertCells(i, cell)
+	return t.pager.balance(path, node)
+}
+
+// Update replaces the record of the row with the rowid.
+// It must be called within a write transaction.
+func (t *TableBTree) Update(rowID int64, record []byte) error {
+	path, node, i, err := t.seekRow(rowID)
+	if err != nil {
+		return err
+	}
+	if err := t.pager.freeOverflow(node.pageType, node.cells[i]); err != nil {
+		return err
+	}
+	cell, err := t.pager.buildCell(BTREE_LEAF_TABLE, 0, rowID, record)
+	if err != nil {
+		return err
+	}
+	node.cells[i] = cell
+	return t.pager.balance(path, node)
+}
+
+// Delete removes the row with the rowid, its overflow pages go to the freelist.
+// It must be called within a write transaction.
+func (t *TableBTree) Delete(rowID int64) error {
+	path, node, i, err := t.seekRow(rowID)
+	if err != nil {
+		return err
+	}
+	if err := t.pager.freeOverflow(node.pageType, node.cells[i]); err != nil {
+		return err
+	}
+	node.removeCell(i)
+	return t.pager.balance(path, node)
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableBTreeInteriorPages(t *testing.T) {
+	// written by sqlite3 with 512 bytes pages, the 120 rows of numbers don't fit in a single page
+	data, err := os.ReadFile("testdata/interior.db")
+	require.NoError(t, err)
+	file := vfs.NewMemFile(data)
+	database, err := NewDB(file)
+	require.NoError(t, err)
+	tree, err := database.FindTablePage("numbers")
+	require.NoError(t, err)
+
+	root, err := ReadBTreePage(file, int(database.header.PageSize), uint32(tree.RootPage))
+	require.NoError(t, err)
+	require.Equal(t, BTREE_INTERNAL_TABLE, BTreePageType(root[0]))
+
+	records, err := tree.GetRecords()
+	require.NoError(t, err)
+	require.Len(t, records, 120)
+	for i, record := range records {
+		require.Equal(t, int64(i+1), record.RowID)
+		n, err := record.IntField(0)
+		require.NoError(t, err)
+		require.Equal(t, int64(i+1), n)
+		name, _, err := record.FieldData(1)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("n%056d", i+1), name)
+	}
+
+	// the empty table is a single leaf page without any cell
+	tree, err = database.FindTablePage("empty")
+	require.NoError(t, err)
+	records, err = tree.GetRecords()
+	require.NoError(t, err)
+	require.Empty(t, records)
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"fmt"
+)
+
+// WithoutRowIDTable is the b-tree of a WITHOUT ROWID table: an index b-tree which entries are the
+// records of the rows, keyed by the PRIMARY KEY column stored first.
+// See https://www.sqlite.org/withoutrowid.html
+type WithoutRowIDTable struct {
+	index  *IndexBTree
+	schema TableSchemaInfo
+}
+
+// FindWithoutRowIDTable returns the b-tree of the WITHOUT ROWID table.
+func (d *DB) FindWithoutRowIDTable(schema TableSchemaInfo) (*WithoutRowIDTable, error) {
+	if !schema.WithoutRowID {
+		return nil, fmt.Errorf("table %s isn't a WITHOUT ROWID table", schema.Name)
+	}
+	if schema.PageID < 1 {
+		return nil, fmt.Errorf("invalid root page: %d", schema.PageID)
+	}
+	index := NewIndexBTree(d.pager, int(schema.PageID))
+	index.Name = schema.Name
+	index.TableName = schema.Name
+	index.Unique = true
+	index.Columns = []IndexColumnInfo{{Name: schema.PrimaryKey.Name}}
+	index.withoutRowID = true
+	return &WithoutRowIDTable{index: index, schema: schema}, nil
+}
+
+// Cursor returns a cursor over the rows ordered by PRIMARY KEY, it isn't positioned yet.
+// The records have their fields in the stored order, see TableSchemaInfo.TableRecord.
+func (t *WithoutRowIDTable) Cursor() *BTreeCursor {
+	return t.index.Cursor()
+}
+
+// Insert adds the row given with the values of every column in table order.
+// It must be called within a write transaction.
+func (t *WithoutRowIDTable) Insert(row []any) error {
+	key := row[t.schema.PrimaryKey.Idx]
+	if key == nil {
+		return fmt.Errorf("NOT NULL constraint failed: %s.%s", t.schema.Name, t.schema.PrimaryKey.Name)
+	}
+	found := false
+	err := t.index.Seek([]any{key}, func(record Record) (bool, error) {
+		found = true
+		return false, nil
+	})
+	if err != nil {
+		return err
+	}
+	if found {
+		return fmt.Errorf("UNIQUE constraint failed: %s.%s", t.schema.Name, t.schema.PrimaryKey.Name)
+	}
+	return t.index.Insert(t.schema.StoredRow(row))
+}
+
+// Delete removes the row having the PRIMARY KEY of the row given with the values of every column
+// in table order. It must be called within a write transaction.
+func (t *WithoutRowIDTable) Delete(row []any) error {
+	return t.index.Delete([]any{row[t.schema.PrimaryKey.Idx]})
+}
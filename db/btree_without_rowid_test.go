@@ -0,0 +1,70 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithoutRowID(t *testing.T) {
+	database, err := CreateDB(vfs.NewMemFile(nil), CreateOptions{PageSize: 512})
+	require.NoError(t, err)
+	_, err = ExtractQueryInfo("create table t (a text) without rowid")
+	require.EqualError(t, err, "PRIMARY KEY missing on table t")
+	info, err := ExtractQueryInfo("create table fruits (size integer, name text primary key, color text) without rowid")
+	require.NoError(t, err)
+	require.True(t, info.DDL.Table.WithoutRowID)
+	require.NoError(t, database.CreateTable(info.DDL.Table, false))
+
+	schema, err := database.FindTableSchema("fruits")
+	require.NoError(t, err)
+	_, ok := schema.RowIDColumn()
+	require.False(t, ok)
+	table, err := database.FindWithoutRowIDTable(schema)
+	require.NoError(t, err)
+	require.NoError(t, database.Begin())
+	for i := 300; i > 0; i-- {
+		require.NoError(t, table.Insert([]any{int64(i), fmt.Sprintf("fruit %03d", i), "red"}))
+	}
+	require.EqualError(t, table.Insert([]any{int64(1), "fruit 001", "green"}), "UNIQUE constraint failed: fruits.name")
+	require.EqualError(t, table.Insert([]any{int64(1), nil, "green"}), "NOT NULL constraint failed: fruits.name")
+	require.NoError(t, table.Delete([]any{nil, "fruit 002", nil}))
+	require.NoError(t, database.Commit())
+
+	// the rows are ordered by PRIMARY KEY, which is stored first
+	cursor := table.Cursor()
+	defer cursor.Close()
+	found, err := cursor.SeekKey([]any{"fruit 003"})
+	require.NoError(t, err)
+	require.True(t, found)
+	record, err := cursor.Record()
+	require.NoError(t, err)
+	name, err := record.Value(0)
+	require.NoError(t, err)
+	require.Equal(t, "fruit 003", name)
+	record = schema.TableRecord(record)
+	size, err := record.Value(0)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), size)
+
+	require.NoError(t, database.DropColumn("fruits", "size"))
+	schema, err = database.FindTableSchema("fruits")
+	require.NoError(t, err)
+	cursor, err = database.TableCursor(schema)
+	require.NoError(t, err)
+	defer cursor.Close()
+	rows := 0
+	for err = cursor.First(); err == nil && cursor.Valid(); err = cursor.Next() {
+		rows++
+		record, err := cursor.Record()
+		require.NoError(t, err)
+		require.Len(t, record.Header.Fields, 2)
+	}
+	require.NoError(t, err)
+	require.Equal(t, 299, rows)
+	messages, err := database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
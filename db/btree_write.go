@@ -0,0 +1,616 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// btreeNode is a b-tree page decoded for writing. The cells are kept as raw bytes and the page is
+// rebuilt from them when it's written, so a written page never has any freeblock nor fragment.
+type btreeNode struct {
+	number    uint32
+	pageType  BTreePageType
+	cells     [][]byte
+	rightMost uint32
+	// dirty is set on the pages of the path which have been modified and must be balanced too
+	dirty bool
+}
+
+// btreePathEntry is a page on the way from the root to a leaf and the index of the child followed.
+type btreePathEntry struct {
+	node       *btreeNode
+	childIndex int
+}
+
+func (n *btreeNode) isLeaf() bool {
+	return n.pageType == BTREE_LEAF_TABLE || n.pageType == BTREE_LEAF_INDEX
+}
+
+func (n *btreeNode) headerSize() int {
+	if n.isLeaf() {
+		return 8
+	}
+	return 12
+}
+
+// child returns the page on the left of the cell i, i equal to the number of cells is the right-most page.
+func (n *btreeNode) child(i int) uint32 {
+	if i == len(n.cells) {
+		return n.rightMost
+	}
+	return binary.BigEndian.Uint32(n.cells[i][:4])
+}
+
+// insertCells inserts the cells before the cell i.
+func (n *btreeNode) insertCells(i int, cells ...[]byte) {
+	newCells := make([][]byte, 0, len(n.cells)+len(cells))
+	newCells = append(newCells, n.cells[:i]...)
+	newCells = append(newCells, cells...)
+	n.cells = append(newCells, n.cells[i:]...)
+}
+
+// removeCell removes the cell i.
+func (n *btreeNode) removeCell(i int) {
+	n.cells = append(n.cells[:i:i], n.cells[i+1:]...)
+}
+
+// capacity returns how many bytes the cells and their pointers can take in the page.
+func (p *Pager) capacity(pageNumber uint32, pageType BTreePageType) int {
+	node := btreeNode{pageType: pageType}
+	return p.limits.usableSize - btreeHeaderOffset(int(pageNumber)) - node.headerSize()
+}
+
+func (p *Pager) fits(n *btreeNode) bool {
+	return cellsSize(n.cells) <= p.capacity(n.number, n.pageType)
+}
+
+// underfull reports whether the cells take less than a third of the page, such a page is
+// merged with a sibling or takes some of its cells.
+func (p *Pager) underfull(n *btreeNode) bool {
+	return cellsSize(n.cells)*3 < p.capacity(n.number, n.pageType)
+}
+
+// cellsSize returns how many bytes the cells and their pointers take.
+func cellsSize(cells [][]byte) int {
+	used := 0
+	for _, cell := range cells {
+		used += len(cell) + 2
+	}
+	return used
+}
+
+// loadNode reads the page to modify it.
+func (p *Pager) loadNode(pageNumber uint32) (*btreeNode, error) {
+	page, err := p.readBTreePage(pageNumber)
+	if err != nil {
+		return nil, err
+	}
+	node := &btreeNode{
+		number:    pageNumber,
+		pageType:  page.Header.PageType,
+		rightMost: page.Header.RightMostPointer,
+	}
+	for i, ptr := range page.CellPointers {
+		if int(ptr) >= p.limits.usableSize {
+			return nil, fmt.Errorf("page %d cell %d has an invalid cell pointer: %d", pageNumber, i, ptr)
+		}
+		_, size, err := decodeCell(page.Header.PageType, page.Data[ptr:p.limits.usableSize], p.limits)
+		if err != nil {
+			return nil, fmt.Errorf("page %d cell %d: %w", pageNumber, i, err)
+		}
+		node.cells = append(node.cells, page.Data[int(ptr):int(ptr)+size])
+	}
+	return node, nil
+}
+
+// writeNode rebuilds the page from its cells, the cells are packed at the end of the usable space.
+func (p *Pager) writeNode(n *btreeNode) error {
+	if !p.fits(n) {
+		return fmt.Errorf("page %d cells don't fit in the page", n.number)
+	}
+	old, err := p.Get(n.number)
+	if err != nil {
+		return err
+	}
+	defer p.Unpin(old)
+
+	data := make([]byte, p.limits.pageSize)
+	offset := btreeHeaderOffset(int(n.number))
+	// keep the database header of page 1 and the reserved space at the end of the page
+	copy(data[:offset], old.Data[:offset])
+	copy(data[p.limits.usableSize:], old.Data[p.limits.usableSize:])
+
+	data[offset] = byte(n.pageType)
+	binary.BigEndian.PutUint16(data[offset+3:], uint16(len(n.cells)))
+	if !n.isLeaf() {
+		binary.BigEndian.PutUint32(data[offset+8:], n.rightMost)
+	}
+
+	content := p.limits.usableSize
+	pointers := offset + n.headerSize()
+	for i, cell := range n.cells {
+		content -= len(cell)
+		copy(data[content:], cell)
+		binary.BigEndian.PutUint16(data[pointers+i*2:], uint16(content))
+	}
+	// a content area starting at 65536 is stored as 0
+	binary.BigEndian.PutUint16(data[offset+5:], uint16(content))
+	if err := p.Write(n.number, data); err != nil {
+		return err
+	}
+	return p.writeChildPtrmaps(n)
+}
+
+// CreateBTree allocates the root page of a new empty b-tree, pageType is the type of its leaves
+// and tells a table b-tree from an index b-tree. It must be called within a write transaction.
+func (p *Pager) CreateBTree(pageType BTreePageType) (uint32, error) {
+	if pageType != BTREE_LEAF_TABLE && pageType != BTREE_LEAF_INDEX {
+		return 0, fmt.Errorf("invalid b-tree leaf page type: %d", pageType)
+	}
+	allocate := p.Allocate
+	if p.autoVacuum() {
+		allocate = p.allocateRoot
+	}
+	pageNumber, err := allocate()
+	if err != nil {
+		return 0, err
+	}
+	return pageNumber, p.writeNode(&btreeNode{number: pageNumber, pageType: pageType})
+}
+
+// appendEntry adds an entry after the last one of the b-tree, its rowid or its key must be greater
+// than every other. The pages of the right-most path are split when they are full but never
+// rebalanced, so adding the entries in order leaves every page but the right-most ones full.
+func (p *Pager) appendEntry(rootPage uint32, rowID int64, payload []byte) error {
+	node, err := p.loadNode(rootPage)
+	if err != nil {
+		return err
+	}
+	var path []btreePathEntry
+	for !node.isLeaf() {
+		if len(path) >= maxBTreeDepth {
+			return fmt.Errorf("b-tree rooted at page %d is deeper than %d pages", rootPage, maxBTreeDepth)
+		}
+		path = append(path, btreePathEntry{node: node, childIndex: len(node.cells)})
+		if node, err = p.loadNode(node.rightMost); err != nil {
+			return err
+		}
+	}
+	cell, err := p.buildCell(node.pageType, 0, rowID, payload)
+	if err != nil {
+		return err
+	}
+	node.insertCells(len(node.cells), cell)
+	for len(path) > 0 && !p.fits(node) {
+		dividers, err := p.split(node)
+		if err != nil {
+			return err
+		}
+		parent := path[len(path)-1].node
+		parent.insertCells(len(parent.cells), dividers...)
+		node, path = parent, path[:len(path)-1]
+	}
+	if len(path) > 0 {
+		return p.writeNode(node)
+	}
+	return p.balance(nil, node)
+}
+
+// buildCell builds a cell of the page type, the part of the payload which doesn't fit
+// in the page is written to a chain of overflow pages.
+func (p *Pager) buildCell(pageType BTreePageType, leftChild uint32, rowID int64, payload []byte) ([]byte, error) {
+	var cell []byte
+	if pageType == BTREE_INTERNAL_TABLE || pageType == BTREE_INTERNAL_PAGE {
+		cell = binary.BigEndian.AppendUint32(cell, leftChild)
+	}
+	if pageType == BTREE_INTERNAL_TABLE {
+		return AppendVarint(cell, rowID), nil
+	}
+
+	cell = AppendVarint(cell, int64(len(payload)))
+	if pageType == BTREE_LEAF_TABLE {
+		cell = AppendVarint(cell, rowID)
+	}
+	local := p.limits.localSize(pageType, int64(len(payload)))
+	cell = append(cell, payload[:local]...)
+	if local == len(payload) {
+		return cell, nil
+	}
+
+	firstPage, err := p.writeOverflow(payload[local:])
+	if err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.AppendUint32(cell, firstPage), nil
+}
+
+// writeOverflow writes the payload to new overflow pages and returns the first page of the chain.
+func (p *Pager) writeOverflow(payload []byte) (uint32, error) {
+	chunkSize := p.limits.usableSize - 4
+	pageNumbers := make([]uint32, (len(payload)+chunkSize-1)/chunkSize)
+	for i := range pageNumbers {
+		pageNumber, err := p.Allocate()
+		if err != nil {
+			return 0, err
+		}
+		pageNumbers[i] = pageNumber
+	}
+
+	for i, pageNumber := range pageNumbers {
+		data := make([]byte, p.limits.pageSize)
+		if i+1 < len(pageNumbers) {
+			binary.BigEndian.PutUint32(data, pageNumbers[i+1])
+		}
+		copy(data[4:p.limits.usableSize], payload[i*chunkSize:])
+		if err := p.Write(pageNumber, data); err != nil {
+			return 0, err
+		}
+		// the parent of the first page is the page of the cell, set when the cell is written
+		if i > 0 {
+			if err := p.writePtrmap(pageNumber, ptrmapOverflow2, pageNumbers[i-1]); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return pageNumbers[0], nil
+}
+
+// cellPayload returns the whole payload of a raw cell, reading its overflow pages if any.
+func (p *Pager) cellPayload(pageType BTreePageType, cell []byte) ([]byte, error) {
+	decoded, _, err := decodeCell(pageType, cell, p.limits)
+	if err != nil {
+		return nil, err
+	}
+	if decoded.OverflowPage == 0 {
+		return decoded.Payload, nil
+	}
+	return readOverflowPayload(p, decoded.Payload, decoded.OverflowPage, decoded.Size)
+}
+
+// balance writes the modified node and the modified pages of the path to it.
+//
+// A node whose cells don't fit in the page anymore is split into as many pages as needed, the new
+// pages take the first cells and the node keeps the last ones so the pointer of the parent to it
+// stays valid. A divider cell is inserted in the parent for every new page.
+// A node whose cells take less than a third of the page is merged with a sibling when they fit in
+// one page, otherwise the cells of both are shared evenly between them.
+// Both change the parent, which is balanced in turn. The root page never moves: when it overflows
+// its content is moved to a new child page first, when it's left without any cell it takes the
+// content of its only child.
+func (p *Pager) balance(path []btreePathEntry, node *btreeNode) error {
+	for {
+		if len(path) == 0 {
+			if p.fits(node) {
+				return p.balanceRoot(node)
+			}
+			childNumber, err := p.Allocate()
+			if err != nil {
+				return err
+			}
+			child := &btreeNode{number: childNumber, pageType: node.pageType, cells: node.cells, rightMost: node.rightMost}
+			root := &btreeNode{number: node.number, pageType: interiorPageType(node.pageType), rightMost: childNumber, dirty: true}
+			path = []btreePathEntry{{node: root, childIndex: 0}}
+			node = child
+			continue
+		}
+
+		parent := path[len(path)-1]
+		switch {
+		case !p.fits(node):
+			dividers, err := p.split(node)
+			if err != nil {
+				return err
+			}
+			parent.node.insertCells(parent.childIndex, dividers...)
+			parent.node.dirty = true
+		case p.underfull(node) && len(parent.node.cells) > 0:
+			if err := p.rebalance(parent.node, parent.childIndex, node); err != nil {
+				return err
+			}
+		default:
+			if err := p.writeNode(node); err != nil {
+				return err
+			}
+		}
+
+		// the pages of the path which haven't been modified are left as they are
+		for len(path) > 0 && !path[len(path)-1].node.dirty {
+			path = path[:len(path)-1]
+		}
+		if len(path) == 0 {
+			return nil
+		}
+		node = path[len(path)-1].node
+		path = path[:len(path)-1]
+	}
+}
+
+// balanceRoot writes the root page. An interior root without any cell is replaced by its only
+// child when the child content fits in the root page, the page 1 being smaller it may not.
+func (p *Pager) balanceRoot(root *btreeNode) error {
+	for !root.isLeaf() && len(root.cells) == 0 {
+		child, err := p.loadNode(root.rightMost)
+		if err != nil {
+			return err
+		}
+		newRoot := &btreeNode{number: root.number, pageType: child.pageType, cells: child.cells, rightMost: child.rightMost}
+		if !p.fits(newRoot) {
+			break
+		}
+		if err := p.FreePage(child.number); err != nil {
+			return err
+		}
+		root = newRoot
+	}
+	return p.writeNode(root)
+}
+
+// rebalance merges the underfull node child i of the parent with its left sibling, or its right
+// sibling for the first child. When both don't fit in one page their cells are shared evenly.
+func (p *Pager) rebalance(parent *btreeNode, childIndex int, node *btreeNode) error {
+	d := childIndex - 1
+	if childIndex == 0 {
+		d = 0
+	}
+	left, right := node, node
+	siblingNumber := parent.child(d)
+	if childIndex == d {
+		siblingNumber = parent.child(d + 1)
+	}
+	sibling, err := p.loadNode(siblingNumber)
+	if err != nil {
+		return err
+	}
+	if sibling.pageType != node.pageType {
+		return fmt.Errorf("page %d and its sibling page %d have different types: %d and %d",
+			node.number, sibling.number, node.pageType, sibling.pageType)
+	}
+	if childIndex == d {
+		right = sibling
+	} else {
+		left = sibling
+	}
+
+	// the divider goes down between the cells of both pages, except in the table leaves
+	// where it's only a copy of a rowid
+	divider := parent.cells[d]
+	cells := make([][]byte, 0, len(left.cells)+len(right.cells)+1)
+	cells = append(cells, left.cells...)
+	switch node.pageType {
+	case BTREE_LEAF_TABLE:
+	case BTREE_LEAF_INDEX:
+		cells = append(cells, divider[4:])
+	default:
+		cells = append(cells, append(binary.BigEndian.AppendUint32(nil, left.rightMost), divider[4:]...))
+	}
+	cells = append(cells, right.cells...)
+
+	merged := &btreeNode{number: right.number, pageType: node.pageType, cells: cells, rightMost: right.rightMost}
+	if p.fits(merged) {
+		if err := p.writeNode(merged); err != nil {
+			return err
+		}
+		if err := p.FreePage(left.number); err != nil {
+			return err
+		}
+		parent.removeCell(d)
+		parent.dirty = true
+		return nil
+	}
+
+	pivot := p.evenPivot(merged)
+	if pivot < 0 {
+		return p.writeNode(node)
+	}
+	newLeft := &btreeNode{number: left.number, pageType: node.pageType, cells: cells[:pivot]}
+	newRight := &btreeNode{number: right.number, pageType: node.pageType, cells: cells[pivot:], rightMost: right.rightMost}
+	var newDivider []byte
+	switch node.pageType {
+	case BTREE_LEAF_TABLE:
+		last, _, err := decodeCell(node.pageType, cells[pivot-1], p.limits)
+		if err != nil {
+			return err
+		}
+		newDivider = AppendVarint(binary.BigEndian.AppendUint32(nil, left.number), last.RowID)
+	case BTREE_LEAF_INDEX:
+		newRight.cells = cells[pivot+1:]
+		newDivider = append(binary.BigEndian.AppendUint32(nil, left.number), cells[pivot]...)
+	default:
+		newRight.cells = cells[pivot+1:]
+		newLeft.rightMost = merged.child(pivot)
+		newDivider = append(binary.BigEndian.AppendUint32(nil, left.number), cells[pivot][4:]...)
+	}
+	if err := p.writeNode(newLeft); err != nil {
+		return err
+	}
+	if err := p.writeNode(newRight); err != nil {
+		return err
+	}
+	parent.cells[d] = newDivider
+	parent.dirty = true
+	return nil
+}
+
+// evenPivot returns where to cut the cells of the node so both pages are about as full,
+// the cell at the pivot is the first cell of the right page for the table leaves and the divider
+// moved up to the parent otherwise. It returns -1 when the cells can't be cut in two pages.
+func (p *Pager) evenPivot(node *btreeNode) int {
+	capacity := p.capacity(0, node.pageType)
+	movesUp := 0
+	if node.pageType != BTREE_LEAF_TABLE {
+		movesUp = 1
+	}
+
+	pivot, best := -1, 0
+	total := cellsSize(node.cells)
+	leftSize := 0
+	for i := 1; i+movesUp < len(node.cells); i++ {
+		leftSize += len(node.cells[i-1]) + 2
+		rightSize := total - leftSize
+		if movesUp == 1 {
+			rightSize -= len(node.cells[i]) + 2
+		}
+		if leftSize > capacity || rightSize > capacity {
+			continue
+		}
+		imbalance := leftSize - rightSize
+		if imbalance < 0 {
+			imbalance = -imbalance
+		}
+		if pivot < 0 || imbalance < best {
+			pivot, best = i, imbalance
+		}
+	}
+	return pivot
+}
+
+// FreeBTree returns every page of the b-tree rooted at the page to the freelist, the overflow pages
+// of its cells too. It must be called within a write transaction.
+func (p *Pager) FreeBTree(rootPage uint32) error {
+	if err := p.freeBTreePages(rootPage); err != nil {
+		return err
+	}
+	return p.FreePage(rootPage)
+}
+
+// freeBTreePages returns every page of the b-tree rooted at the page to the freelist but the root.
+func (p *Pager) freeBTreePages(rootPage uint32) error {
+	node, err := p.loadNode(rootPage)
+	if err != nil {
+		return err
+	}
+	for i, cell := range node.cells {
+		if !node.isLeaf() {
+			if err := p.FreeBTree(node.child(i)); err != nil {
+				return err
+			}
+		}
+		// the cells of the interior pages of a table don't have any payload
+		if node.pageType != BTREE_INTERNAL_TABLE {
+			if err := p.freeOverflow(node.pageType, cell); err != nil {
+				return err
+			}
+		}
+	}
+	if !node.isLeaf() {
+		return p.FreeBTree(node.rightMost)
+	}
+	return nil
+}
+
+// freeOverflow returns the overflow pages of the cell to the freelist.
+func (p *Pager) freeOverflow(pageType BTreePageType, cell []byte) error {
+	decoded, _, err := decodeCell(pageType, cell, p.limits)
+	if err != nil {
+		return err
+	}
+	remaining := decoded.Size - int64(len(decoded.Payload))
+	for pageNumber := decoded.OverflowPage; remaining > 0; {
+		if pageNumber == 0 {
+			return fmt.Errorf("overflow chain ends %d bytes before the end of the payload", remaining)
+		}
+		page, err := p.Get(pageNumber)
+		if err != nil {
+			return err
+		}
+		next := binary.BigEndian.Uint32(page.Data[:4])
+		p.Unpin(page)
+		if err := p.FreePage(pageNumber); err != nil {
+			return err
+		}
+		pageNumber = next
+		remaining -= int64(p.limits.usableSize - 4)
+	}
+	return nil
+}
+
+// interiorPageType returns the interior page type of the b-tree the page belongs to.
+func interiorPageType(pageType BTreePageType) BTreePageType {
+	if pageType == BTREE_LEAF_TABLE || pageType == BTREE_INTERNAL_TABLE {
+		return BTREE_INTERNAL_TABLE
+	}
+	return BTREE_INTERNAL_PAGE
+}
+
+// split writes the node cells to as many pages as needed and returns the divider cells
+// to insert in the parent, one for each new page.
+func (p *Pager) split(node *btreeNode) ([][]byte, error) {
+	// the table leaf cells stay in the leaves and the dividers only hold a rowid,
+	// every other page type moves a cell up to the parent between two pages
+	movesUp := node.pageType != BTREE_LEAF_TABLE
+	capacity := p.capacity(0, node.pageType)
+
+	// every piece is the range of cells [start, end) of a page, with movesUp
+	// the cell at end is the divider moved up to the parent
+	type piece struct{ start, end int }
+	var pieces []piece
+	start, used := 0, 0
+	for i, cell := range node.cells {
+		if used+len(cell)+2 <= capacity {
+			used += len(cell) + 2
+			continue
+		}
+		if i == start {
+			return nil, fmt.Errorf("page %d cell %d doesn't fit in a page", node.number, i)
+		}
+		pieces = append(pieces, piece{start, i})
+		start, used = i, len(cell)+2
+		if movesUp {
+			start, used = i+1, 0
+		}
+	}
+	pieces = append(pieces, piece{start, len(node.cells)})
+
+	// the last divider has no cell left after it: the previous page gives its last cell as
+	// the divider and the former divider becomes the last page
+	if last := len(pieces) - 1; movesUp && pieces[last].start == pieces[last].end {
+		pieces[last-1].end--
+		pieces[last].start = pieces[last-1].end + 1
+		if pieces[last-1].start == pieces[last-1].end {
+			return nil, fmt.Errorf("page %d can't be split", node.number)
+		}
+	}
+
+	dividers := make([][]byte, 0, len(pieces)-1)
+	for i, piece := range pieces {
+		page := &btreeNode{
+			number:    node.number,
+			pageType:  node.pageType,
+			cells:     node.cells[piece.start:piece.end],
+			rightMost: node.rightMost,
+		}
+		if i == len(pieces)-1 {
+			if err := p.writeNode(page); err != nil {
+				return nil, err
+			}
+			break
+		}
+
+		pageNumber, err := p.Allocate()
+		if err != nil {
+			return nil, err
+		}
+		page.number = pageNumber
+
+		var divider []byte
+		switch node.pageType {
+		case BTREE_LEAF_TABLE:
+			last, _, err := decodeCell(node.pageType, page.cells[len(page.cells)-1], p.limits)
+			if err != nil {
+				return nil, err
+			}
+			divider = AppendVarint(binary.BigEndian.AppendUint32(nil, pageNumber), last.RowID)
+		case BTREE_LEAF_INDEX:
+			divider = append(binary.BigEndian.AppendUint32(nil, pageNumber), node.cells[piece.end]...)
+		default:
+			// the left child of the divider becomes the right-most page of the new page
+			page.rightMost = node.child(piece.end)
+			divider = append(binary.BigEndian.AppendUint32(nil, pageNumber), node.cells[piece.end][4:]...)
+		}
+		if err := p.writeNode(page); err != nil {
+			return nil, err
+		}
+		dividers = append(dividers, divider)
+	}
+	return dividers, nil
+}
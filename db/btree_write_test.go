@@ -0,0 +1,135 @@
+package db
+
+import (
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableBTreeInsert(t *testing.T) {
+	data, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	file := vfs.NewMemFile(data)
+	database, err := NewDB(file)
+	require.NoError(t, err)
+	tree, err := database.FindTablePage("oranges")
+	require.NoError(t, err)
+
+	// random rowids and a few payloads big enough to spill into overflow pages split the pages on every level
+	r := rand.New(rand.NewSource(1))
+	want := map[int64]string{}
+	require.NoError(t, database.Begin())
+	for i := 0; i < 3000; i++ {
+		rowID := r.Int63n(1 << 40)
+		if _, ok := want[rowID]; ok {
+			continue
+		}
+		name := strings.Repeat("o", r.Intn(200))
+		if i%100 == 0 {
+			name = strings.Repeat("O", 10000)
+		}
+		record, err := EncodeRecord([]any{nil, name, "juicy"}, EncodingUTF8)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(rowID, record))
+		want[rowID] = name
+	}
+	require.Error(t, tree.Insert(1, nil), "the rowid 1 already exists")
+	require.NoError(t, database.Commit())
+
+	reopened, err := NewDB(vfs.NewMemFile(file.Bytes()))
+	require.NoError(t, err)
+	tree, err = reopened.FindTablePage("oranges")
+	require.NoError(t, err)
+	records, err := tree.GetRecords()
+	require.NoError(t, err)
+
+	// the sample rows come first with the rowids 1 to 6
+	require.Len(t, records, len(want)+6)
+	for i, record := range records[6:] {
+		if i > 0 {
+			require.Less(t, records[6+i-1].RowID, record.RowID)
+		}
+		name, err := record.Value(1)
+		require.NoError(t, err)
+		require.Equal(t, want[record.RowID], name, "rowid %d", record.RowID)
+	}
+}
+
+func TestTableBTreeDelete(t *testing.T) {
+	data, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	file := vfs.NewMemFile(data)
+	database, err := NewDB(file)
+	require.NoError(t, err)
+	tree, err := database.FindTablePage("oranges")
+	require.NoError(t, err)
+
+	require.NoError(t, database.Begin())
+	for rowID := int64(100); rowID < 3100; rowID++ {
+		name := strings.Repeat("o", int(rowID%150))
+		if rowID%100 == 0 {
+			name = strings.Repeat("O", 10000)
+		}
+		record, err := EncodeRecord([]any{nil, name, "juicy"}, EncodingUTF8)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(rowID, record))
+	}
+	require.NoError(t, database.Commit())
+	pages := database.Header().DatabaseSize
+
+	// deleting most rows merges the pages, the emptied pages and the overflow pages are freed
+	require.NoError(t, database.Begin())
+	for rowID := int64(100); rowID < 3100; rowID++ {
+		if rowID%10 != 0 {
+			require.NoError(t, tree.Delete(rowID))
+		}
+	}
+	require.Error(t, tree.Delete(101), "the rowid 101 doesn't exist anymore")
+	record, err := EncodeRecord([]any{nil, "updated", "sour"}, EncodingUTF8)
+	require.NoError(t, err)
+	require.NoError(t, tree.Update(200, record))
+	require.NoError(t, database.Commit())
+
+	reopened, err := NewDB(vfs.NewMemFile(file.Bytes()))
+	require.NoError(t, err)
+	require.Equal(t, pages, reopened.Header().DatabaseSize)
+	require.Greater(t, reopened.Header().FreelistCount, pages/2)
+	tree, err = reopened.FindTablePage("oranges")
+	require.NoError(t, err)
+	records, err := tree.GetRecords()
+	require.NoError(t, err)
+
+	require.Len(t, records, 6+300)
+	for i, record := range records[6:] {
+		require.Equal(t, int64(100+i*10), record.RowID)
+		name, err := record.Value(1)
+		require.NoError(t, err)
+		switch {
+		case record.RowID == 200:
+			require.Equal(t, "updated", name)
+		case record.RowID%100 == 0:
+			require.Equal(t, strings.Repeat("O", 10000), name)
+		default:
+			require.Equal(t, strings.Repeat("o", int(record.RowID%150)), name)
+		}
+	}
+
+	// the freed pages are reused before the database grows
+	freePages := reopened.Header().FreelistCount
+	require.NoError(t, reopened.Begin())
+	for rowID := int64(5000); rowID < 5500; rowID++ {
+		record, err := EncodeRecord([]any{nil, strings.Repeat("n", 100), "fresh"}, EncodingUTF8)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(rowID, record))
+	}
+	require.NoError(t, reopened.Commit())
+	require.Equal(t, pages, reopened.Header().DatabaseSize)
+	require.Less(t, reopened.Header().FreelistCount, freePages)
+	records, err = tree.GetRecords()
+	require.NoError(t, err)
+	require.Len(t, records, 6+300+500)
+}
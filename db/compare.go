@@ -0,0 +1,143 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+)
+
+// storageClassOrder is the order sqlite sorts values of different storage classes:
+// NULL values first, then the numbers, then the text and the blobs last.
+func storageClassOrder(v any) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case int64, float64:
+		return 1
+	case string:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// CompareValues compares two values using the sqlite sort order and the BINARY collation.
+// It returns a negative number when a < b, zero when they're equal and a positive number when a > b.
+// The values are the ones a record decodes to, the Go integer, float and bool types and the strings,
+// any other type is an error.
+func CompareValues(a, b any) (int, error) {
+	return compareValues(a, b, EncodingUTF8)
+}
+
+// compareValues compares the text values as they're stored in the encoding.
+func compareValues(a, b any, encoding TextEncoding) (int, error) {
+	a, b = normalizeValue(a), normalizeValue(b)
+	for _, v := range []any{a, b} {
+		switch v.(type) {
+		case nil, int64, float64, string, []byte:
+		default:
+			return 0, fmt.Errorf("unsupported value type %T", v)
+		}
+	}
+	classA, classB := storageClassOrder(a), storageClassOrder(b)
+	if classA != classB {
+		return classA - classB, nil
+	}
+
+	switch va := a.(type) {
+	case int64:
+		if vb, ok := b.(int64); ok {
+			return compareOrdered(va, vb), nil
+		}
+		return compareOrdered(float64(va), b.(float64)), nil
+	case float64:
+		if vb, ok := b.(int64); ok {
+			return compareOrdered(va, float64(vb)), nil
+		}
+		return compareOrdered(va, b.(float64)), nil
+	case string:
+		return encoding.Compare(va, b.(string)), nil
+	case []byte:
+		return bytes.Compare(va, b.([]byte)), nil
+	}
+	// both are NULL
+	return 0, nil
+}
+
+// normalizeValue converts the Go integer and float types to int64 and float64, the booleans
+// to 0 and 1 like sqlite. The unsigned integers which overflow int64 are left as they are.
+func normalizeValue(v any) any {
+	switch n := v.(type) {
+	case bool:
+		if n {
+			return int64(1)
+		}
+		return int64(0)
+	case int:
+		return int64(n)
+	case int8:
+		return int64(n)
+	case int16:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case uint8:
+		return int64(n)
+	case uint16:
+		return int64(n)
+	case uint32:
+		return int64(n)
+	case uint:
+		if uint64(n) <= math.MaxInt64 {
+			return int64(n)
+		}
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n)
+		}
+	case float32:
+		return float64(n)
+	}
+	return v
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// CompareKey compares the first len(key) fields of the record with the key,
+// so a key shorter than the record acts as a prefix.
+func CompareKey(record Record, key []any) (int, error) {
+	return compareKey(record, key, nil)
+}
+
+// compareKey compares like CompareKey, the order of the fields i with desc[i] set is reversed.
+func compareKey(record Record, key []any, desc []bool) (int, error) {
+	for i, want := range key {
+		if i >= len(record.Header.Fields) {
+			return -1, nil
+		}
+		value, err := record.Value(i)
+		if err != nil {
+			return 0, err
+		}
+		c, err := compareValues(value, want, record.Encoding)
+		if err != nil {
+			return 0, err
+		}
+		if c != 0 {
+			if i < len(desc) && desc[i] {
+				return -c, nil
+			}
+			return c, nil
+		}
+	}
+	return 0, nil
+}
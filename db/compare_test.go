@@ -0,0 +1,39 @@
+package db
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		a, b any
+		want int
+	}{
+		{nil, nil, 0},
+		{nil, int64(-5), -1},
+		{int64(2), 1.5, 1},
+		{int64(2), 2.0, 0},
+		{int(3), int64(3), 0},
+		{uint8(1), true, 0},
+		{false, int64(0), 0},
+		{uint64(7), int32(8), -1},
+		{float32(0.5), int64(1), -1},
+		{int64(math.MaxInt64), "1", -1},
+		{"b", "a", 1},
+		{"z", []byte("a"), -1},
+		{[]byte{1}, []byte{1, 0}, -1},
+	}
+	for _, test := range tests {
+		got, err := CompareValues(test.a, test.b)
+		require.NoError(t, err, "%v %v", test.a, test.b)
+		require.Equal(t, test.want, max(min(got, 1), -1), "%v %v", test.a, test.b)
+	}
+
+	_, err := CompareValues(uint64(math.MaxUint64), int64(1))
+	require.EqualError(t, err, "unsupported value type uint64")
+	_, err = CompareValues(int64(1), struct{}{})
+	require.EqualError(t, err, "unsupported value type struct {}")
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/adzimzf/sqlite-go/constant"
+	"github.com/adzimzf/sqlite-go/vfs"
+)
+
+// DefaultPageSize is the page size of a new database when none is given, the default of sqlite.
+const DefaultPageSize = 4096
+
+// CreateOptions are the settings of a new database which can't change once it has been created,
+// and the application values of its header. The zero values are sqlite's defaults.
+type CreateOptions struct {
+	// PageSize is a power of two between 512 and 65536, DefaultPageSize when 0
+	PageSize uint32
+	// Encoding is the text encoding of the database, EncodingUTF8 when 0
+	Encoding TextEncoding
+	// ReservedBytes is the space left unused at the end of every page for the extensions
+	ReservedBytes byte
+	ApplicationID uint32
+	UserVersion   uint32
+	// AutoVacuum is the auto-vacuum mode, the pointer-map pages can't be added once the database exists
+	AutoVacuum AutoVacuumMode
+}
+
+// Create creates the database name in the storage fs and opens it for writing,
+// the file must not exist or be empty.
+func Create(fs vfs.VFS, name string, options CreateOptions) (*DB, error) {
+	// the invalid options don't leave an empty file behind
+	if _, err := newDatabaseHeader(options); err != nil {
+		return nil, err
+	}
+	file, err := fs.Open(name, vfs.OpenReadWrite|vfs.OpenCreate)
+	if err != nil {
+		return nil, err
+	}
+	database, err := CreateDB(file, options)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return database, nil
+}
+
+// CreateDB initializes the empty file with the header of a new database and an empty sqlite_master
+// table in page 1, and opens it.
+// See https://www.sqlite.org/fileformat2.html#the_database_header
+func CreateDB(file vfs.File, options CreateOptions) (*DB, error) {
+	size, err := file.Size()
+	if err != nil {
+		return nil, err
+	}
+	if size > 0 {
+		return nil, fmt.Errorf("cannot create a database in a file which isn't empty")
+	}
+
+	header, err := newDatabaseHeader(options)
+	if err != nil {
+		return nil, err
+	}
+	pager := NewPager(file, header, PagerOptions{})
+	if err := pager.Begin(); err != nil {
+		return nil, err
+	}
+	if err := pager.initSchema(); err != nil {
+		pager.Rollback()
+		return nil, err
+	}
+	if err := pager.Commit(); err != nil {
+		return nil, err
+	}
+	return NewDB(file)
+}
+
+// newDatabaseHeader returns the header of a new database of a single page.
+func newDatabaseHeader(options CreateOptions) (*DatabaseHeader, error) {
+	header := &DatabaseHeader{
+		PageSize:            options.PageSize,
+		FileFormatWrite:     1,
+		FileFormatRead:      1,
+		Reserved1:           options.ReservedBytes,
+		MaxEmbeddedPayload:  64,
+		MinEmbeddedPayload:  32,
+		LeafPayloadFraction: 32,
+		DatabaseSize:        1,
+		SchemaFormat:        4,
+		TextEncoding:        options.Encoding,
+		UserVersion:         options.UserVersion,
+		ApplicationID:       options.ApplicationID,
+		VersionUsed:         constant.SQLiteVersionNumber,
+	}
+	if header.PageSize == 0 {
+		header.PageSize = DefaultPageSize
+	}
+	if header.TextEncoding == 0 {
+		header.TextEncoding = EncodingUTF8
+	}
+	switch options.AutoVacuum {
+	case AutoVacuumNone:
+	case AutoVacuumFull, AutoVacuumIncremental:
+		// the largest root page of an auto-vacuum database without any table is sqlite_master's
+		header.LargestRootPage = 1
+		if options.AutoVacuum == AutoVacuumIncremental {
+			header.IncrementalVacuum = 1
+		}
+	default:
+		return nil, fmt.Errorf("invalid auto-vacuum mode: %d", options.AutoVacuum)
+	}
+	if err := header.validate(); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// initSchema writes page 1 of an empty database: the header and an empty sqlite_master.
+// It must be called within a write transaction.
+func (p *Pager) initSchema() error {
+	page1 := make([]byte, p.limits.pageSize)
+	p.header.encode(page1)
+	if err := p.Write(1, page1); err != nil {
+		return err
+	}
+	return p.writeNode(&btreeNode{number: 1, pageType: BTREE_LEAF_TABLE})
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreate(t *testing.T) {
+	fs := vfs.NewMemVFS()
+	options := CreateOptions{
+		PageSize:      1024,
+		Encoding:      EncodingUTF16le,
+		ReservedBytes: 16,
+		ApplicationID: 0x4a4b4c4d,
+		UserVersion:   7,
+	}
+	database, err := Create(fs, "new.db", options)
+	require.NoError(t, err)
+	require.NoError(t, database.Close())
+
+	_, err = Create(fs, "new.db", options)
+	require.Error(t, err, "the database already exists")
+	_, err = Create(fs, "invalid.db", CreateOptions{PageSize: 1000})
+	require.Error(t, err)
+	exists, err := fs.Exists("invalid.db")
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	database, err = Open(fs, "new.db", PagerOptions{})
+	require.NoError(t, err)
+	defer database.Close()
+	header := database.Header()
+	require.Equal(t, uint32(1024), header.PageSize)
+	require.Equal(t, EncodingUTF16le, header.TextEncoding)
+	require.Equal(t, byte(16), header.Reserved1)
+	require.Equal(t, uint32(0x4a4b4c4d), header.ApplicationID)
+	require.Equal(t, uint32(7), header.UserVersion)
+	require.Equal(t, uint32(1), header.DatabaseSize)
+	require.Equal(t, uint32(4), header.SchemaFormat)
+
+	sqliteMaster, err := database.FindSQLiteMaster()
+	require.NoError(t, err)
+	records, err := sqliteMaster.GetRecords()
+	require.NoError(t, err)
+	require.Empty(t, records)
+	messages, err := database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+
+	// the defaults of sqlite
+	database, err = CreateDB(vfs.NewMemFile(nil), CreateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, uint32(DefaultPageSize), database.Header().PageSize)
+	require.Equal(t, EncodingUTF8, database.Header().TextEncoding)
+}
@@ -0,0 +1,369 @@
+package db
+
+import (
+	"fmt"
+)
+
+// BTreeCursor walks a table or an index b-tree one entry at a time,
+// only the pages on the path from the root to the current entry are kept in memory.
+type BTreeCursor struct {
+	pager    *Pager
+	rootPage int
+	stack    []cursorFrame
+	// desc tells which index columns are sorted in descending order
+	desc []bool
+	// withoutRowID is set for the index b-trees of a WITHOUT ROWID table, their keys don't end with a rowid
+	withoutRowID bool
+}
+
+// cursorFrame is a page on the path from the root to the current entry.
+// For an interior page index is the child the cursor descended into, unless the frame
+// is at the top of the stack: an index b-tree cursor can point to a cell of an interior page.
+// The pages of the stack are pinned in the page cache.
+type cursorFrame struct {
+	page   *BTreePage
+	pinned *Page
+	index  int
+}
+
+func NewBTreeCursor(pager *Pager, rootPage int) *BTreeCursor {
+	return &BTreeCursor{pager: pager, rootPage: rootPage}
+}
+
+// Close releases the pages pinned by the cursor, the cursor doesn't point to any entry anymore.
+func (c *BTreeCursor) Close() {
+	for c.Valid() {
+		c.pop()
+	}
+}
+
+func (c *BTreeCursor) pop() {
+	c.pager.Unpin(c.stack[len(c.stack)-1].pinned)
+	c.stack = c.stack[:len(c.stack)-1]
+}
+
+// Valid reports whether the cursor points to an entry, it's false once the cursor
+// moved past the last or the first entry.
+func (c *BTreeCursor) Valid() bool {
+	return len(c.stack) > 0
+}
+
+// First moves the cursor to the smallest entry of the tree.
+func (c *BTreeCursor) First() error {
+	c.Close()
+	return c.descend(uint32(c.rootPage), true)
+}
+
+// Last moves the cursor to the biggest entry of the tree.
+func (c *BTreeCursor) Last() error {
+	c.Close()
+	return c.descend(uint32(c.rootPage), false)
+}
+
+// Next moves the cursor to the next entry.
+func (c *BTreeCursor) Next() error {
+	if !c.Valid() {
+		return nil
+	}
+	top := &c.stack[len(c.stack)-1]
+	if !top.page.IsLeaf() {
+		// the entries after an interior cell start from the left-most entry of the next child
+		top.index++
+		child, err := top.page.Child(top.index)
+		if err != nil {
+			return err
+		}
+		return c.descend(child, true)
+	}
+
+	top.index++
+	if top.index < len(top.page.CellPointers) {
+		return nil
+	}
+	return c.ascendNext()
+}
+
+// Prev moves the cursor to the previous entry.
+func (c *BTreeCursor) Prev() error {
+	if !c.Valid() {
+		return nil
+	}
+	top := &c.stack[len(c.stack)-1]
+	if !top.page.IsLeaf() {
+		// the entries before an interior cell end with the right-most entry of its left child
+		child, err := top.page.Child(top.index)
+		if err != nil {
+			return err
+		}
+		return c.descend(child, false)
+	}
+
+	top.index--
+	if top.index >= 0 {
+		return nil
+	}
+	return c.ascendPrev()
+}
+
+// SeekRowID moves the cursor of a table b-tree to the row with the given rowid,
+// or to the next row when it doesn't exist. It returns whether the row has been found.
+func (c *BTreeCursor) SeekRowID(rowID int64) (bool, error) {
+	c.Close()
+	err := c.seek(func(page *BTreePage, i int) (int, error) {
+		cell, err := page.Cell(i, c.pager.limits)
+		if err != nil {
+			return 0, err
+		}
+		return compareOrdered(cell.RowID, rowID), nil
+	})
+	if err != nil || !c.Valid() {
+		return false, err
+	}
+
+	current, err := c.RowID()
+	if err != nil {
+		return false, err
+	}
+	return current == rowID, nil
+}
+
+// SeekKey moves the cursor of an index b-tree to the first entry which key is greater than
+// or equal to the given key. It returns whether the entry starts with the key.
+func (c *BTreeCursor) SeekKey(key []any) (bool, error) {
+	c.Close()
+	err := c.seek(func(page *BTreePage, i int) (int, error) {
+		record, err := c.cellRecord(page, i)
+		if err != nil {
+			return 0, err
+		}
+		return compareKey(record, key, c.desc)
+	})
+	if err != nil || !c.Valid() {
+		return false, err
+	}
+
+	record, err := c.Record()
+	if err != nil {
+		return false, err
+	}
+	cmp, err := compareKey(record, key, c.desc)
+	if err != nil {
+		return false, err
+	}
+	return cmp == 0, nil
+}
+
+// seek descends from the root to the first entry for which compare doesn't return a negative number,
+// the cells of every page are binary searched.
+func (c *BTreeCursor) seek(compare func(page *BTreePage, i int) (int, error)) error {
+	pageNumber := uint32(c.rootPage)
+	for {
+		page, pinned, err := c.loadPage(pageNumber)
+		if err != nil {
+			return err
+		}
+
+		lo, hi := 0, len(page.CellPointers)
+		for lo < hi {
+			mid := (lo + hi) / 2
+			cmp, err := compare(page, mid)
+			if err != nil {
+				return err
+			}
+			if cmp < 0 {
+				lo = mid + 1
+			} else {
+				hi = mid
+			}
+		}
+
+		c.stack = append(c.stack, cursorFrame{page: page, pinned: pinned, index: lo})
+		if page.IsLeaf() {
+			if lo == len(page.CellPointers) {
+				return c.ascendNext()
+			}
+			return nil
+		}
+
+		pageNumber, err = page.Child(lo)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// RowID returns the rowid of the current entry.
+func (c *BTreeCursor) RowID() (int64, error) {
+	if !c.Valid() {
+		return 0, fmt.Errorf("cursor doesn't point to any entry")
+	}
+	top := c.stack[len(c.stack)-1]
+	if top.page.IsTable() {
+		cell, err := top.page.Cell(top.index, c.pager.limits)
+		if err != nil {
+			return 0, err
+		}
+		return cell.RowID, nil
+	}
+
+	record, err := c.Record()
+	if err != nil {
+		return 0, err
+	}
+	return record.RowID, nil
+}
+
+// Record returns the record of the current entry, for an index b-tree the RowID
+// is taken from the last field of the key unless the table is a WITHOUT ROWID table.
+func (c *BTreeCursor) Record() (Record, error) {
+	if !c.Valid() {
+		return Record{}, fmt.Errorf("cursor doesn't point to any entry")
+	}
+	top := c.stack[len(c.stack)-1]
+	return c.cellRecord(top.page, top.index)
+}
+
+// payload returns the whole payload of the current entry, without decoding it.
+func (c *BTreeCursor) payload() ([]byte, error) {
+	if !c.Valid() {
+		return nil, fmt.Errorf("cursor doesn't point to any entry")
+	}
+	top := c.stack[len(c.stack)-1]
+	_, payload, err := c.cellPayload(top.page, top.index)
+	return payload, err
+}
+
+func (c *BTreeCursor) cellPayload(page *BTreePage, i int) (BTreeCell, []byte, error) {
+	cell, err := page.Cell(i, c.pager.limits)
+	if err != nil {
+		return BTreeCell{}, nil, err
+	}
+	payload := cell.Payload
+	if cell.OverflowPage != 0 {
+		payload, err = readOverflowPayload(c.pager, cell.Payload, cell.OverflowPage, cell.Size)
+		if err != nil {
+			return BTreeCell{}, nil, fmt.Errorf("page %d cell %d: %w", page.Header.PageNumber, i, err)
+		}
+	}
+	return cell, payload, nil
+}
+
+func (c *BTreeCursor) cellRecord(page *BTreePage, i int) (Record, error) {
+	cell, payload, err := c.cellPayload(page, i)
+	if err != nil {
+		return Record{}, err
+	}
+
+	header, err := NewRecordHeader(payload)
+	if err != nil {
+		return Record{}, err
+	}
+	record := Record{RowID: cell.RowID, Header: header, Payload: payload, Encoding: c.pager.encoding}
+	if page.IsTable() || c.withoutRowID {
+		return record, nil
+	}
+
+	if len(header.Fields) == 0 {
+		return Record{}, fmt.Errorf("page %d cell %d has an empty index key", page.Header.PageNumber, i)
+	}
+	record.RowID, err = record.IntField(len(header.Fields) - 1)
+	if err != nil {
+		return Record{}, err
+	}
+	return record, nil
+}
+
+// descend goes down from the page to the left-most or the right-most entry of its subtree.
+func (c *BTreeCursor) descend(pageNumber uint32, leftMost bool) error {
+	for {
+		page, pinned, err := c.loadPage(pageNumber)
+		if err != nil {
+			return err
+		}
+
+		index := 0
+		if !leftMost {
+			index = len(page.CellPointers)
+			if page.IsLeaf() {
+				index--
+			}
+		}
+		c.stack = append(c.stack, cursorFrame{page: page, pinned: pinned, index: index})
+
+		if page.IsLeaf() {
+			// only an empty root page can be a leaf without any cell
+			if len(page.CellPointers) == 0 {
+				if leftMost {
+					return c.ascendNext()
+				}
+				return c.ascendPrev()
+			}
+			return nil
+		}
+
+		pageNumber, err = page.Child(index)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ascendNext leaves the current leaf page and moves to the next entry of the closest parent page.
+func (c *BTreeCursor) ascendNext() error {
+	c.pop()
+	for len(c.stack) > 0 {
+		top := &c.stack[len(c.stack)-1]
+		if top.index < len(top.page.CellPointers) {
+			// the interior cells of an index b-tree are entries as well
+			if !top.page.IsTable() {
+				return nil
+			}
+			top.index++
+			child, err := top.page.Child(top.index)
+			if err != nil {
+				return err
+			}
+			return c.descend(child, true)
+		}
+		c.pop()
+	}
+	return nil
+}
+
+// ascendPrev leaves the current leaf page and moves to the previous entry of the closest parent page.
+func (c *BTreeCursor) ascendPrev() error {
+	c.pop()
+	for len(c.stack) > 0 {
+		top := &c.stack[len(c.stack)-1]
+		if top.index > 0 {
+			top.index--
+			if !top.page.IsTable() {
+				return nil
+			}
+			child, err := top.page.Child(top.index)
+			if err != nil {
+				return err
+			}
+			return c.descend(child, false)
+		}
+		c.pop()
+	}
+	return nil
+}
+
+// loadPage returns the parsed page and its pinned cache page which is released once the page is popped.
+func (c *BTreeCursor) loadPage(pageNumber uint32) (*BTreePage, *Page, error) {
+	if len(c.stack) > maxBTreeDepth {
+		return nil, nil, fmt.Errorf("b-tree %d is too deep", c.rootPage)
+	}
+	pinned, err := c.pager.Get(pageNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	page, err := parseBTreePage(pinned.Data, int(pageNumber))
+	if err != nil {
+		c.pager.Unpin(pinned)
+		return nil, nil, err
+	}
+	return page, pinned, nil
+}
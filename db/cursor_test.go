@@ -0,0 +1,91 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBTreeCursor(t *testing.T) {
+	// written by sqlite3 with 512 bytes pages, evens only has the even rowids from 2 to 126
+	// spread over several leaves under an interior page
+	data, err := os.ReadFile("testdata/interior.db")
+	require.NoError(t, err)
+	database, err := NewDB(vfs.NewMemFile(data))
+	require.NoError(t, err)
+
+	tree, err := database.FindTablePage("empty")
+	require.NoError(t, err)
+	cursor := tree.Cursor()
+	require.NoError(t, cursor.First())
+	require.False(t, cursor.Valid(), "the table is empty")
+	require.NoError(t, cursor.Last())
+	require.False(t, cursor.Valid())
+	found, err := cursor.SeekRowID(1)
+	require.NoError(t, err)
+	require.False(t, found)
+	require.False(t, cursor.Valid())
+
+	tree, err = database.FindTablePage("evens")
+	require.NoError(t, err)
+	cursor = tree.Cursor()
+	rowID := func() int64 {
+		id, err := cursor.RowID()
+		require.NoError(t, err)
+		return id
+	}
+
+	require.NoError(t, cursor.First())
+	require.Len(t, cursor.stack, 2)
+	for want := int64(2); want <= 126; want += 2 {
+		require.True(t, cursor.Valid())
+		require.Equal(t, want, rowID())
+		record, err := cursor.Record()
+		require.NoError(t, err)
+		name, _, err := record.FieldData(0)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("e%056d", want), name)
+		require.NoError(t, cursor.Next())
+	}
+	require.False(t, cursor.Valid())
+	require.NoError(t, cursor.Next(), "moving past the end again does nothing")
+	require.False(t, cursor.Valid())
+
+	require.NoError(t, cursor.Last())
+	for want := int64(126); want >= 2; want -= 2 {
+		require.True(t, cursor.Valid())
+		require.Equal(t, want, rowID())
+		require.NoError(t, cursor.Prev())
+	}
+	require.False(t, cursor.Valid())
+
+	found, err = cursor.SeekRowID(64)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, int64(64), rowID())
+	require.NoError(t, cursor.Prev())
+	require.Equal(t, int64(62), rowID())
+
+	// a missing rowid leaves the cursor on the next row, in the next leaf when it's the last of its leaf
+	for missing := int64(1); missing < 126; missing += 2 {
+		found, err = cursor.SeekRowID(missing)
+		require.NoError(t, err)
+		require.False(t, found)
+		require.Equal(t, missing+1, rowID())
+	}
+	found, err = cursor.SeekRowID(127)
+	require.NoError(t, err)
+	require.False(t, found)
+	require.False(t, cursor.Valid())
+
+	// the pages of the path are released once the cursor is closed
+	require.NoError(t, cursor.First())
+	cursor.Close()
+	for elem := database.pager.cache.lru.Front(); elem != nil; elem = elem.Next() {
+		page := elem.Value.(*Page)
+		require.Zero(t, page.pins, "page %d", page.Number)
+	}
+}
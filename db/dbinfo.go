@@ -0,0 +1,77 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// dbInfoFields are the 4 bytes header fields printed by .dbinfo with their offset.
+var dbInfoFields = []struct {
+	name   string
+	offset int
+}{
+	{"file change counter:", 24},
+	{"database page count:", 28},
+	{"freelist page count:", 36},
+	{"schema cookie:", 40},
+	{"schema format:", 44},
+	{"default cache size:", 48},
+	{"autovacuum top root:", 52},
+	{"incremental vacuum:", 64},
+	{"text encoding:", 56},
+	{"user version:", 60},
+	{"application id:", 68},
+	{"software version:", 96},
+}
+
+// WriteDBInfo writes the same report as the sqlite3 .dbinfo command.
+func (d *DB) WriteDBInfo(w io.Writer) error {
+	raw := d.header.HeaderString[:]
+	fmt.Fprintf(w, "%-20s %d\n", "database page size:", d.header.PageSize)
+	fmt.Fprintf(w, "%-20s %d\n", "write format:", d.header.FileFormatWrite)
+	fmt.Fprintf(w, "%-20s %d\n", "read format:", d.header.FileFormatRead)
+	fmt.Fprintf(w, "%-20s %d\n", "reserved bytes:", d.header.Reserved1)
+	for _, field := range dbInfoFields {
+		value := binary.BigEndian.Uint32(raw[field.offset : field.offset+4])
+		fmt.Fprintf(w, "%-20s %d", field.name, value)
+		if field.offset == 56 && value >= 1 && value <= 3 {
+			fmt.Fprintf(w, " (%s)", TextEncoding(value))
+		}
+		fmt.Fprintln(w)
+	}
+
+	sqliteMaster, err := d.FindSQLiteMaster()
+	if err != nil {
+		return err
+	}
+	records, err := sqliteMaster.GetRecords()
+	if err != nil {
+		return err
+	}
+
+	objects := map[string]int{}
+	schemaSize := 0
+	for _, record := range records {
+		objectType, err := record.Value(0)
+		if err != nil {
+			return err
+		}
+		objects[fmt.Sprint(objectType)]++
+
+		rawSQL, err := record.Value(4)
+		if err != nil {
+			return err
+		}
+		if s, ok := rawSQL.(string); ok {
+			schemaSize += utf8.RuneCountInString(s)
+		}
+	}
+	fmt.Fprintf(w, "%-20s %d\n", "number of tables:", objects["table"])
+	fmt.Fprintf(w, "%-20s %d\n", "number of indexes:", objects["index"])
+	fmt.Fprintf(w, "%-20s %d\n", "number of triggers:", objects["trigger"])
+	fmt.Fprintf(w, "%-20s %d\n", "number of views:", objects["view"])
+	fmt.Fprintf(w, "%-20s %d\n", "schema size:", schemaSize)
+	return nil
+}
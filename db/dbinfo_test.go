@@ -0,0 +1,24 @@
+package db
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDBInfo(t *testing.T) {
+	data, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	database, err := NewDB(vfs.NewMemFile(data))
+	require.NoError(t, err)
+
+	// the golden file is the .dbinfo report expected for sample.db
+	want, err := os.ReadFile("testdata/sample.dbinfo")
+	require.NoError(t, err)
+	var got bytes.Buffer
+	require.NoError(t, database.WriteDBInfo(&got))
+	require.Equal(t, string(want), got.String())
+}
@@ -0,0 +1,757 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/adzimzf/sqlite-go/constant"
+	sqlparser "github.com/adzimzf/sqlite-go/sql"
+)
+
+// schemaObject is a record of sqlite_master.
+type schemaObject struct {
+	rowID      int64
+	objectType string
+	name       string
+	tableName  string
+	rootPage   int64
+	// sql is empty for the automatic indexes of the UNIQUE and PRIMARY KEY constraints
+	sql string
+}
+
+// record returns the payload of the object in sqlite_master.
+func (o schemaObject) record(encoding TextEncoding) ([]byte, error) {
+	var sql any
+	if o.sql != "" {
+		sql = o.sql
+	}
+	return EncodeRecord([]any{o.objectType, o.name, o.tableName, o.rootPage, sql}, encoding)
+}
+
+// CreateTable creates the table in a single transaction: its root page is allocated, its record
+// with the SQL text of the table is added to sqlite_master and the schema cookie is incremented.
+// An existing table is an error unless ifNotExists is set.
+// See https://www.sqlite.org/lang_createtable.html
+func (d *DB) CreateTable(table TableSchemaInfo, ifNotExists bool) error {
+	return d.changeSchema(func() error {
+		return d.createTable(table, ifNotExists)
+	})
+}
+
+func (d *DB) createTable(table TableSchemaInfo, ifNotExists bool) error {
+	if isInternalName(table.Name) {
+		return fmt.Errorf("object name reserved for internal use: %s", table.Name)
+	}
+	objects, err := d.schemaObjects()
+	if err != nil {
+		return err
+	}
+	if object, found := findSchemaObject(objects, table.Name); found {
+		if object.objectType == "index" {
+			return fmt.Errorf("there is already an index named %s", table.Name)
+		}
+		if ifNotExists {
+			return errSchemaUnchanged
+		}
+		return fmt.Errorf("%s %s already exists", object.objectType, table.Name)
+	}
+
+	for i, column := range table.Columns {
+		for _, previous := range table.Columns[:i] {
+			if strings.EqualFold(previous.Name, column.Name) {
+				return fmt.Errorf("duplicate column name: %s", column.Name)
+			}
+		}
+	}
+
+	d.setSchemaFormat()
+	// a WITHOUT ROWID table is an index b-tree keyed by its PRIMARY KEY
+	pageType := BTREE_LEAF_TABLE
+	if table.WithoutRowID {
+		pageType = BTREE_LEAF_INDEX
+	}
+	if err := d.insertTable(table.Name, table.RawSQL, pageType); err != nil {
+		return err
+	}
+	for _, index := range table.AutoIndexes() {
+		if index.PrimaryKey && table.WithoutRowID {
+			continue
+		}
+		object := schemaObject{objectType: "index", name: index.Name, tableName: table.Name}
+		if err := d.insertSchemaObject(object, BTREE_LEAF_INDEX); err != nil {
+			return err
+		}
+	}
+	// the first AUTOINCREMENT table creates sqlite_sequence
+	if _, found := findSchemaObject(objects, constant.SqliteInternalName); table.IsAutoIncrement && !found {
+		return d.insertTable(constant.SqliteInternalName, sqliteSequenceSQL, BTREE_LEAF_TABLE)
+	}
+	return nil
+}
+
+// insertTable allocates the root page of a new table, a leaf of pageType, and adds its record to sqlite_master.
+func (d *DB) insertTable(name, sql string, pageType BTreePageType) error {
+	return d.insertSchemaObject(schemaObject{objectType: "table", name: name, tableName: name, sql: sql}, pageType)
+}
+
+// insertSchemaObject allocates the root page of the object, a leaf of pageType, and adds its record to sqlite_master.
+func (d *DB) insertSchemaObject(object schemaObject, pageType BTreePageType) error {
+	rootPage, err := d.pager.CreateBTree(pageType)
+	if err != nil {
+		return err
+	}
+	object.rootPage = int64(rootPage)
+	record, err := object.record(d.pager.encoding)
+	if err != nil {
+		return err
+	}
+	sqliteMaster, err := d.FindSQLiteMaster()
+	if err != nil {
+		return err
+	}
+	rowID, err := sqliteMaster.NewRowID()
+	if err != nil {
+		return err
+	}
+	return sqliteMaster.Insert(rowID, record)
+}
+
+// DropTable removes the table, its indexes and its sqlite_sequence row in a single transaction,
+// all their pages go to the freelist. A missing table is an error unless ifExists is set.
+// See https://www.sqlite.org/lang_droptable.html
+func (d *DB) DropTable(name string, ifExists bool) error {
+	return d.changeSchema(func() error {
+		return d.dropTable(name, ifExists)
+	})
+}
+
+func (d *DB) dropTable(name string, ifExists bool) error {
+	if isSchemaTable(name) {
+		return fmt.Errorf("table %s may not be dropped", name)
+	}
+	objects, err := d.schemaObjects()
+	if err != nil {
+		return err
+	}
+	table, found := findSchemaObject(objects, name)
+	if !found || table.objectType != "table" && table.objectType != "view" {
+		if ifExists {
+			return errSchemaUnchanged
+		}
+		return fmt.Errorf("no such table: %s", name)
+	}
+	if table.objectType == "view" {
+		return fmt.Errorf("use DROP VIEW to delete view %s", name)
+	}
+	if isInternalName(table.name) && !strings.HasPrefix(strings.ToLower(table.name), "sqlite_stat") {
+		return fmt.Errorf("table %s may not be dropped", table.name)
+	}
+
+	// like sqlite the b-trees are dropped from the largest root page down, so the root pages moved
+	// by an auto-vacuum database are never the ones still to drop
+	var dropped []schemaObject
+	for _, object := range objects {
+		if strings.EqualFold(object.tableName, table.name) {
+			dropped = append(dropped, object)
+		}
+	}
+	sort.SliceStable(dropped, func(i, j int) bool { return dropped[i].rootPage > dropped[j].rootPage })
+	for _, object := range dropped {
+		if err := d.dropSchemaObject(object); err != nil {
+			return err
+		}
+	}
+	return d.renameSequence(objects, table.name, "")
+}
+
+// DropIndex removes the index in a single transaction, its pages go to the freelist.
+// A missing index is an error unless ifExists is set.
+// See https://www.sqlite.org/lang_dropindex.html
+func (d *DB) DropIndex(name string, ifExists bool) error {
+	return d.changeSchema(func() error {
+		objects, err := d.schemaObjects()
+		if err != nil {
+			return err
+		}
+		index, found := findSchemaObject(objects, name)
+		if !found || index.objectType != "index" {
+			if ifExists {
+				return errSchemaUnchanged
+			}
+			return fmt.Errorf("no such index: %s", name)
+		}
+		if index.sql == "" {
+			return fmt.Errorf("index associated with UNIQUE or PRIMARY KEY constraint cannot be dropped")
+		}
+		return d.dropSchemaObject(index)
+	})
+}
+
+// RenameTable renames the table in a single transaction. The new name is written in the SQL text
+// of the table and of its indexes, and in the names of its automatic indexes and its sqlite_sequence row.
+// See https://www.sqlite.org/lang_altertable.html#alter_table_rename
+func (d *DB) RenameTable(name, newName string) error {
+	return d.changeSchema(func() error {
+		objects, table, err := d.alteredTable(name)
+		if err != nil {
+			return err
+		}
+		if isInternalName(newName) {
+			return fmt.Errorf("object name reserved for internal use: %s", newName)
+		}
+		if _, found := findSchemaObject(objects, newName); found {
+			return fmt.Errorf("there is already another table or index with this name: %s", newName)
+		}
+
+		autoIndexPrefix := "sqlite_autoindex_" + table.name + "_"
+		for _, object := range objects {
+			if !strings.EqualFold(object.tableName, table.name) {
+				continue
+			}
+			if object.rowID == table.rowID {
+				object.name = newName
+			}
+			if strings.HasPrefix(object.name, autoIndexPrefix) {
+				object.name = "sqlite_autoindex_" + newName + "_" + strings.TrimPrefix(object.name, autoIndexPrefix)
+			}
+			object.tableName = newName
+			if object.sql != "" {
+				if object.sql, err = renameTableSQL(object.sql, table.name, newName); err != nil {
+					return err
+				}
+			}
+			if err := d.updateSchemaObject(object); err != nil {
+				return err
+			}
+		}
+		return d.renameSequence(objects, table.name, newName)
+	})
+}
+
+// RenameColumn renames the column of the table in a single transaction, the new name is written
+// in the SQL text of the table and of its indexes.
+// See https://www.sqlite.org/lang_altertable.html#alter_table_rename_column
+func (d *DB) RenameColumn(tableName, column, newName string) error {
+	return d.changeSchema(func() error {
+		objects, table, err := d.alteredTable(tableName)
+		if err != nil {
+			return err
+		}
+		schema, err := d.FindTableSchema(table.name)
+		if err != nil {
+			return err
+		}
+		if _, ok := schema.Column(column); !ok {
+			return fmt.Errorf("no such column: %q", column)
+		}
+		// the column itself is a duplicate too, whatever the case of the new name
+		if _, ok := schema.Column(newName); ok {
+			return fmt.Errorf("error in table %s after rename: duplicate column name: %s", table.name, newName)
+		}
+
+		for _, object := range objects {
+			if !strings.EqualFold(object.tableName, table.name) || object.sql == "" {
+				continue
+			}
+			if object.sql, err = renameColumnSQL(object.sql, column, newName); err != nil {
+				return err
+			}
+			if err := d.updateSchemaObject(object); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddColumn appends the column to the table in a single transaction, its definition is appended to
+// the SQL text of the table. The rows aren't rewritten: the new column of the existing rows is NULL.
+// See https://www.sqlite.org/lang_altertable.html#alter_table_add_column
+func (d *DB) AddColumn(tableName string, column TableColumnInfo, constraints ColumnConstraints, columnSQL string) error {
+	return d.changeSchema(func() error {
+		_, table, err := d.alteredTable(tableName)
+		if err != nil {
+			return err
+		}
+		schema, err := d.FindTableSchema(table.name)
+		if err != nil {
+			return err
+		}
+		if _, ok := schema.Column(column.Name); ok {
+			return fmt.Errorf("duplicate column name: %s", column.Name)
+		}
+		if constraints.PrimaryKey {
+			return fmt.Errorf("Cannot add a PRIMARY KEY column")
+		}
+		if constraints.Unique {
+			return fmt.Errorf("Cannot add a UNIQUE column")
+		}
+		// the existing rows would read NULL for the new column, there's no DEFAULT to give them
+		if constraints.NotNull {
+			empty, err := d.emptyTree(int(table.rootPage))
+			if err != nil {
+				return err
+			}
+			if !empty {
+				return fmt.Errorf("Cannot add a NOT NULL column with default value NULL")
+			}
+		}
+
+		if table.sql, err = addColumnToSQL(table.sql, columnSQL); err != nil {
+			return err
+		}
+		return d.updateSchemaObject(table)
+	})
+}
+
+// emptyTree reports whether the table or index b-tree doesn't have any entry.
+func (d *DB) emptyTree(rootPage int) (bool, error) {
+	cursor := NewBTreeCursor(d.pager, rootPage)
+	defer cursor.Close()
+	if err := cursor.First(); err != nil {
+		return false, err
+	}
+	return !cursor.Valid(), nil
+}
+
+// DropColumn removes the column from the table in a single transaction: its definition is removed
+// from the SQL text of the table and every row is rewritten without it.
+// See https://www.sqlite.org/lang_altertable.html#alter_table_drop_column
+func (d *DB) DropColumn(tableName, column string) error {
+	return d.changeSchema(func() error {
+		_, table, err := d.alteredTable(tableName)
+		if err != nil {
+			return err
+		}
+		schema, err := d.FindTableSchema(table.name)
+		if err != nil {
+			return err
+		}
+		dropped, ok := schema.Column(column)
+		if !ok {
+			return fmt.Errorf("no such column: %q", column)
+		}
+		for _, constraint := range schema.Constraints {
+			if constraint.PrimaryKey && constraint.hasColumn(dropped.Name) {
+				return fmt.Errorf("cannot drop PRIMARY KEY column: %q", dropped.Name)
+			}
+		}
+		if len(schema.Columns) == 1 {
+			return fmt.Errorf("cannot drop column %q: no other columns exist", dropped.Name)
+		}
+		if table.sql, err = dropColumnSQL(table.sql, table.name, int(dropped.Idx)); err != nil {
+			return err
+		}
+		indexSchemas, err := d.indexSchemas()
+		if err != nil {
+			return err
+		}
+		for _, indexSchema := range indexSchemas {
+			if !strings.EqualFold(indexSchema.TableName, table.name) {
+				continue
+			}
+			// the columns of the automatic indexes come from the constraints of the table
+			index, err := d.newIndexBTree(indexSchema, schema)
+			if err != nil {
+				return err
+			}
+			for _, indexColumn := range index.Columns {
+				if !strings.EqualFold(indexColumn.Name, dropped.Name) {
+					continue
+				}
+				if indexSchema.RawSQL == "" {
+					return fmt.Errorf("cannot drop UNIQUE column: %q", dropped.Name)
+				}
+				return fmt.Errorf("error in index %s after drop column: no such column: %s", index.Name, indexColumn.Name)
+			}
+		}
+
+		if err := d.updateSchemaObject(table); err != nil {
+			return err
+		}
+		if schema.WithoutRowID {
+			return d.dropWithoutRowIDColumnValues(schema, int(schema.storedIndex(dropped.Idx)))
+		}
+		return d.dropColumnValues(NewTableBTree(d.pager, int(table.rootPage)), len(schema.Columns), int(dropped.Idx))
+	})
+}
+
+// dropColumnValues rewrites every row of the table without the column, the columns missing from
+// the rows written before a column has been added are written as NULL. The rows are read one at a
+// time, the cursor is moved back to the row once it's written as the write can move it to other pages.
+func (d *DB) dropColumnValues(tree *TableBTree, columns, dropped int) error {
+	cursor := tree.Cursor()
+	defer cursor.Close()
+	err := cursor.First()
+	for ; err == nil && cursor.Valid(); err = cursor.Next() {
+		record, err := cursor.Record()
+		if err != nil {
+			return err
+		}
+		values, err := valuesWithoutField(record, columns, dropped)
+		if err != nil {
+			return err
+		}
+		payload, err := EncodeRecord(values, d.pager.encoding)
+		if err != nil {
+			return err
+		}
+		cursor.Close()
+		if err := tree.Update(record.RowID, payload); err != nil {
+			return err
+		}
+		if _, err := cursor.SeekRowID(record.RowID); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// dropWithoutRowIDColumnValues rewrites every row of the WITHOUT ROWID table without the field
+// stored at dropped, the rows are removed and inserted again under the same PRIMARY KEY.
+func (d *DB) dropWithoutRowIDColumnValues(schema TableSchemaInfo, dropped int) error {
+	table, err := d.FindWithoutRowIDTable(schema)
+	if err != nil {
+		return err
+	}
+	var rows [][]any
+	err = table.index.Scan(func(record Record) (bool, error) {
+		values, err := valuesWithoutField(record, len(schema.Columns), dropped)
+		rows = append(rows, values)
+		return err == nil, err
+	})
+	if err != nil {
+		return err
+	}
+	for _, values := range rows {
+		// the PRIMARY KEY is stored first and can't be dropped
+		if err := table.index.Delete(values[:1]); err != nil {
+			return err
+		}
+		if err := table.index.Insert(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// valuesWithoutField returns the values of the fields of the record but the dropped one,
+// the record is padded with NULL up to the given number of fields.
+func valuesWithoutField(record Record, fields, dropped int) ([]any, error) {
+	values := make([]any, 0, fields-1)
+	for i := 0; i < fields; i++ {
+		if i == dropped {
+			continue
+		}
+		var value any
+		if i < len(record.Header.Fields) {
+			var err error
+			if value, err = record.Value(i); err != nil {
+				return nil, err
+			}
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// errSchemaUnchanged ends the change of the schema without any error when there's nothing to do.
+var errSchemaUnchanged = errors.New("the schema is unchanged")
+
+// changeSchema runs the change of the schema in a single transaction and increments the schema
+// cookie so the other connections read the schema again.
+func (d *DB) changeSchema(change func() error) error {
+	if err := d.pager.Begin(); err != nil {
+		return err
+	}
+	cookie := d.pager.header.SchemaCookie
+	if err := change(); err != nil {
+		d.pager.Rollback()
+		if err == errSchemaUnchanged {
+			return nil
+		}
+		return err
+	}
+	d.pager.header.SchemaCookie = cookie + 1
+	return d.pager.Commit()
+}
+
+// alteredTable returns every object of the schema and the table of ALTER TABLE.
+func (d *DB) alteredTable(name string) ([]schemaObject, schemaObject, error) {
+	if isSchemaTable(name) {
+		return nil, schemaObject{}, fmt.Errorf("table %s may not be altered", name)
+	}
+	objects, err := d.schemaObjects()
+	if err != nil {
+		return nil, schemaObject{}, err
+	}
+	table, found := findSchemaObject(objects, name)
+	if !found || table.objectType != "table" {
+		return nil, schemaObject{}, fmt.Errorf("no such table: %s", name)
+	}
+	if isInternalName(table.name) {
+		return nil, schemaObject{}, fmt.Errorf("table %s may not be altered", table.name)
+	}
+	return objects, table, nil
+}
+
+// schemaObjects returns every record of sqlite_master in order.
+func (d *DB) schemaObjects() ([]schemaObject, error) {
+	sqliteMaster, err := d.FindSQLiteMaster()
+	if err != nil {
+		return nil, err
+	}
+	records, err := sqliteMaster.GetRecords()
+	if err != nil {
+		return nil, err
+	}
+	objects := make([]schemaObject, 0, len(records))
+	for _, record := range records {
+		if len(record.Header.Fields) != 5 {
+			return nil, fmt.Errorf("sqlite_master row %d has %d columns", record.RowID, len(record.Header.Fields))
+		}
+		values := make([]any, 5)
+		for i := range values {
+			if values[i], err = record.Value(i); err != nil {
+				return nil, err
+			}
+		}
+		object := schemaObject{rowID: record.RowID}
+		object.objectType, _ = values[0].(string)
+		object.name, _ = values[1].(string)
+		object.tableName, _ = values[2].(string)
+		object.rootPage, _ = values[3].(int64)
+		object.sql, _ = values[4].(string)
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+// findSchemaObject returns the object with the name, the names are case insensitive and the
+// tables, the indexes, the views and the triggers share a single namespace.
+func findSchemaObject(objects []schemaObject, name string) (schemaObject, bool) {
+	if isSchemaTable(name) {
+		return schemaObject{objectType: "table", name: constant.SqliteMasterName, rootPage: 1}, true
+	}
+	for _, object := range objects {
+		if strings.EqualFold(object.name, name) {
+			return object, true
+		}
+	}
+	return schemaObject{}, false
+}
+
+// updateSchemaObject writes the object to its record of sqlite_master.
+func (d *DB) updateSchemaObject(object schemaObject) error {
+	record, err := object.record(d.pager.encoding)
+	if err != nil {
+		return err
+	}
+	sqliteMaster, err := d.FindSQLiteMaster()
+	if err != nil {
+		return err
+	}
+	return sqliteMaster.Update(object.rowID, record)
+}
+
+// dropSchemaObject removes the object from sqlite_master and frees the pages of its b-tree.
+// The object whose root page moved to the freed root in an auto-vacuum database is updated.
+func (d *DB) dropSchemaObject(object schemaObject) error {
+	sqliteMaster, err := d.FindSQLiteMaster()
+	if err != nil {
+		return err
+	}
+	if err := sqliteMaster.Delete(object.rowID); err != nil {
+		return err
+	}
+	if object.rootPage <= 1 {
+		return nil
+	}
+	moved, err := d.pager.DropBTree(uint32(object.rootPage))
+	if err != nil || moved == 0 {
+		return err
+	}
+	objects, err := d.schemaObjects()
+	if err != nil {
+		return err
+	}
+	for _, other := range objects {
+		if other.rootPage == int64(moved) {
+			other.rootPage = object.rootPage
+			return d.updateSchemaObject(other)
+		}
+	}
+	return fmt.Errorf("moved root page %d doesn't belong to any object", moved)
+}
+
+// setSchemaFormat sets the schema format and the text encoding of a database which doesn't have
+// any schema yet, sqlite leaves them to 0 until the first object is created.
+func (d *DB) setSchemaFormat() {
+	if d.pager.header.SchemaFormat == 0 {
+		d.pager.header.SchemaFormat = 4
+	}
+	if d.pager.header.TextEncoding == 0 {
+		d.pager.header.TextEncoding = EncodingUTF8
+		d.pager.encoding = EncodingUTF8
+	}
+}
+
+// isSchemaTable reports whether the name is sqlite_master or its alias sqlite_schema.
+func isSchemaTable(name string) bool {
+	return strings.EqualFold(name, constant.SqliteMasterName) || strings.EqualFold(name, "sqlite_schema")
+}
+
+// isInternalName reports whether the name is reserved for the objects created by sqlite.
+func isInternalName(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "sqlite_")
+}
+
+// renameTableSQL replaces the name of the table in the SQL text of the table or of one of its indexes,
+// the new name is quoted the way sqlite does.
+func renameTableSQL(sql, name, newName string) (string, error) {
+	tokens, err := sqlparser.Tokenize(sql)
+	if err != nil {
+		return "", err
+	}
+	var edits []sqlEdit
+	for i := 1; i < len(tokens); i++ {
+		token := tokens[i]
+		previous := tokens[i-1].ID
+		if token.ID == sqlparser.IDENTIFIER && (previous == sqlparser.TABLE || previous == sqlparser.ON) &&
+			strings.EqualFold(token.Value, name) {
+			edits = append(edits, sqlEdit{token.Start, token.End, sqlparser.QuoteIdentifier(newName)})
+		}
+	}
+	return applySQLEdits(sql, edits), nil
+}
+
+// renameColumnSQL replaces the name of the column in the SQL text of its table or of one of the
+// indexes of the table. The name is quoted when it was or when it must be.
+func renameColumnSQL(sql, column, newName string) (string, error) {
+	tokens, err := sqlparser.Tokenize(sql)
+	if err != nil {
+		return "", err
+	}
+	replacement := newName
+	if !isPlainName(newName) {
+		replacement = sqlparser.QuoteIdentifier(newName)
+	}
+	var edits []sqlEdit
+	inColumns := false
+	for _, token := range tokens {
+		if token.ID == sqlparser.LPAREN {
+			inColumns = true
+		}
+		if !inColumns || token.ID != sqlparser.IDENTIFIER || !strings.EqualFold(token.Value, column) {
+			continue
+		}
+		if quoted := token.End-token.Start != len(token.Value); quoted {
+			edits = append(edits, sqlEdit{token.Start, token.End, sqlparser.QuoteIdentifier(newName)})
+		} else {
+			edits = append(edits, sqlEdit{token.Start, token.End, replacement})
+		}
+	}
+	return applySQLEdits(sql, edits), nil
+}
+
+// addColumnToSQL appends the definition of a column to the columns of the SQL text of a table.
+func addColumnToSQL(sql, columnSQL string) (string, error) {
+	end := strings.LastIndexByte(sql, ')')
+	if end < 0 {
+		return "", fmt.Errorf("the columns of the table are missing: %s", sql)
+	}
+	return sql[:end] + ", " + columnSQL + sql[end:], nil
+}
+
+// dropColumnSQL removes the definition of the column idx from the SQL text of the table name,
+// from its name up to the name of the next column or from the comma before the last column.
+// The column can't be dropped while a table constraint refers to it.
+func dropColumnSQL(sql, name string, idx int) (string, error) {
+	tokens, err := sqlparser.Tokenize(sql)
+	if err != nil {
+		return "", err
+	}
+	// the first token of every column definition and of every table constraint, and the closing parenthesis
+	var elements []int
+	depth := 0
+	for i, token := range tokens {
+		switch token.ID {
+		case sqlparser.LPAREN:
+			depth++
+			if depth == 1 && i+1 < len(tokens) {
+				elements = append(elements, i+1)
+			}
+		case sqlparser.RPAREN:
+			depth--
+			if depth == 0 {
+				elements = append(elements, i)
+			}
+		case sqlparser.COMMA:
+			if depth == 1 && i+1 < len(tokens) {
+				elements = append(elements, i+1)
+			}
+		}
+	}
+	// the table constraints come after the columns
+	columns := 0
+	for columns < len(elements)-1 && !isTableConstraint(tokens[elements[columns]].ID) {
+		columns++
+	}
+	if idx >= columns {
+		return "", fmt.Errorf("column %d of the table is missing: %s", idx, sql)
+	}
+	column := tokens[elements[idx]].Value
+	for _, token := range tokens[elements[columns]:elements[len(elements)-1]] {
+		if token.ID == sqlparser.IDENTIFIER && strings.EqualFold(token.Value, column) {
+			return "", fmt.Errorf("error in table %s after drop column: no such column: %s", name, token.Value)
+		}
+	}
+
+	start := func(element int) int { return tokens[elements[element]].Start }
+	if idx < columns-1 {
+		return applySQLEdits(sql, []sqlEdit{{start(idx), start(idx + 1), ""}}), nil
+	}
+	// the last column is removed up to the comma before the table constraints or the closing parenthesis
+	end := start(len(elements) - 1)
+	if columns < len(elements)-1 {
+		end = tokens[elements[columns]-1].Start
+	}
+	comma := strings.LastIndexByte(sql[:start(idx)], ',')
+	if comma < 0 {
+		return "", fmt.Errorf("the table has a single column: %s", sql)
+	}
+	return applySQLEdits(sql, []sqlEdit{{comma, end, ""}}), nil
+}
+
+// isTableConstraint reports whether the token starts a table constraint rather than a column definition.
+func isTableConstraint(id int) bool {
+	return id == sqlparser.PRIMARY || id == sqlparser.UNIQUE
+}
+
+// isPlainName reports whether the name can be written without quotes.
+func isPlainName(name string) bool {
+	tokens, err := sqlparser.Tokenize(name)
+	return err == nil && len(tokens) == 1 && tokens[0].ID == sqlparser.IDENTIFIER && tokens[0].Value == name &&
+		tokens[0].End-tokens[0].Start == len(name)
+}
+
+// sqlEdit replaces the text between start and end of a SQL text.
+type sqlEdit struct {
+	start, end int
+	text       string
+}
+
+// applySQLEdits applies the edits, sorted by position and not overlapping, to the SQL text.
+func applySQLEdits(sql string, edits []sqlEdit) string {
+	var buf strings.Builder
+	last := 0
+	for _, edit := range edits {
+		buf.WriteString(sql[last:edit.start])
+		buf.WriteString(edit.text)
+		last = edit.end
+	}
+	buf.WriteString(sql[last:])
+	return buf.String()
+}
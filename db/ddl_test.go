@@ -0,0 +1,304 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTable(t *testing.T) {
+	database, err := CreateDB(vfs.NewMemFile(nil), CreateOptions{})
+	require.NoError(t, err)
+	info, err := ExtractQueryInfo("create table if not exists fruits (id integer primary key, name text);")
+	require.NoError(t, err)
+	require.Equal(t, CREATE_TABLE, info.QueryType)
+	require.Equal(t, "CREATE TABLE fruits (id integer primary key, name text)", info.DDL.Table.RawSQL)
+	require.NoError(t, database.CreateTable(info.DDL.Table, info.DDL.IfNotExists))
+	require.Equal(t, uint32(1), database.Header().SchemaCookie)
+
+	require.NoError(t, database.CreateTable(info.DDL.Table, true))
+	require.EqualError(t, database.CreateTable(info.DDL.Table, false), "table fruits already exists")
+	require.Error(t, database.CreateTable(TableSchemaInfo{Name: "sqlite_fruits"}, false))
+	_, err = ExtractQueryInfo("create table t (a integer primary key, b text primary key)")
+	require.Error(t, err)
+	require.Equal(t, uint32(1), database.Header().SchemaCookie)
+
+	schema, err := database.FindTableSchema("fruits")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), schema.PageID)
+	tree, err := database.FindTablePage("fruits")
+	require.NoError(t, err)
+	record, err := EncodeRecord([]any{nil, "apple"}, EncodingUTF8)
+	require.NoError(t, err)
+	require.NoError(t, database.Begin())
+	require.NoError(t, tree.Insert(1, record))
+	require.NoError(t, database.Commit())
+	records, err := tree.GetRecords()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	messages, err := database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
+
+func TestCreateTableOverflowsPage1(t *testing.T) {
+	file := vfs.NewMemFile(nil)
+	database, err := CreateDB(file, CreateOptions{})
+	require.NoError(t, err)
+
+	// the schema moves from page 1 to a child page once page 1 is full, every row must be kept
+	for i := 1; i <= 80; i++ {
+		info, err := ExtractQueryInfo(fmt.Sprintf("create table fruits_%d (id integer primary key, name text, color text, size integer)", i))
+		require.NoError(t, err)
+		require.NoError(t, database.CreateTable(info.DDL.Table, false))
+
+		reopened, err := NewDB(vfs.NewMemFile(file.Bytes()))
+		require.NoError(t, err)
+		master, err := reopened.FindSQLiteMaster()
+		require.NoError(t, err)
+		records, err := master.GetRecords()
+		require.NoError(t, err)
+		require.Equal(t, i, len(records), "after %d tables", i)
+		for j := 1; j <= i; j++ {
+			_, err := reopened.FindTableSchema(fmt.Sprintf("fruits_%d", j))
+			require.NoError(t, err)
+		}
+	}
+	master, err := database.FindSQLiteMaster()
+	require.NoError(t, err)
+	root, err := ReadBTreePage(file, int(database.header.PageSize), uint32(master.RootPage))
+	require.NoError(t, err)
+	require.Equal(t, byte(BTREE_INTERNAL_TABLE), root[100])
+	messages, err := database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
+
+func TestCreateTableAutoIndexes(t *testing.T) {
+	database, err := CreateDB(vfs.NewMemFile(nil), CreateOptions{})
+	require.NoError(t, err)
+	for _, query := range []string{
+		"create table t1 (a text unique, b text primary key, c unique)",
+		"create table t2 (x unique, y, unique(x), unique(y, x desc))",
+		"create table t3 (k integer, u text, primary key(k desc), unique(u))",
+		"create table t4 (k integer primary key, u unique) without rowid",
+		"create table t5 (k text primary key, u unique) without rowid",
+		"create table t6 (a, b, primary key(a, b), unique(a))",
+	} {
+		info, err := ExtractQueryInfo(query)
+		require.NoError(t, err)
+		require.NoError(t, database.CreateTable(info.DDL.Table, false))
+	}
+
+	// the rows and the root pages of sqlite3 for the same tables
+	objects, err := database.schemaObjects()
+	require.NoError(t, err)
+	var rows []string
+	for _, object := range objects {
+		if object.objectType == "index" {
+			rows = append(rows, fmt.Sprintf("%s|%s|%d|%s", object.name, object.tableName, object.rootPage, object.sql))
+		}
+	}
+	require.Equal(t, []string{
+		"sqlite_autoindex_t1_1|t1|3|",
+		"sqlite_autoindex_t1_2|t1|4|",
+		"sqlite_autoindex_t1_3|t1|5|",
+		"sqlite_autoindex_t2_1|t2|7|",
+		"sqlite_autoindex_t2_2|t2|8|",
+		"sqlite_autoindex_t3_1|t3|10|",
+		"sqlite_autoindex_t4_1|t4|12|",
+		"sqlite_autoindex_t5_2|t5|14|",
+		"sqlite_autoindex_t6_1|t6|16|",
+		"sqlite_autoindex_t6_2|t6|17|",
+	}, rows)
+
+	indexes, err := database.FindTableIndexes("t2")
+	require.NoError(t, err)
+	require.Len(t, indexes, 2)
+	require.True(t, indexes[1].Unique)
+	require.Equal(t, []IndexColumnInfo{{Name: "y"}, {Name: "x", Desc: true}}, indexes[1].Columns)
+	indexes, err = database.FindTableIndexes("t6")
+	require.NoError(t, err)
+	require.Len(t, indexes, 2)
+	require.Equal(t, []IndexColumnInfo{{Name: "a"}, {Name: "b"}}, indexes[0].Columns)
+
+	for _, query := range []string{
+		"create table t7 (a, primary key(b))",
+		"create table t7 (a primary key, primary key(a))",
+		"create table t7 (a, b, primary key(a, b)) without rowid",
+	} {
+		_, err := ExtractQueryInfo(query)
+		require.Error(t, err, query)
+	}
+	messages, err := database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
+
+func TestAlterTable(t *testing.T) {
+	database, err := CreateDB(vfs.NewMemFile(nil), CreateOptions{})
+	require.NoError(t, err)
+	for _, query := range []string{
+		"create table fruits (id integer primary key, name text, color text)",
+		"create table seeds (name text)",
+	} {
+		info, err := ExtractQueryInfo(query)
+		require.NoError(t, err)
+		require.NoError(t, database.CreateTable(info.DDL.Table, false))
+	}
+	tree, err := database.FindTablePage("fruits")
+	require.NoError(t, err)
+	record, err := EncodeRecord([]any{nil, "apple", "red"}, EncodingUTF8)
+	require.NoError(t, err)
+	require.NoError(t, database.Begin())
+	require.NoError(t, tree.Insert(1, record))
+	require.NoError(t, database.Commit())
+
+	require.NoError(t, database.RenameTable("fruits", "my fruits"))
+	require.EqualError(t, database.RenameTable("seeds", "My Fruits"), "there is already another table or index with this name: My Fruits")
+	require.EqualError(t, database.RenameColumn("my fruits", "name", "color"), "error in table my fruits after rename: duplicate column name: color")
+	require.EqualError(t, database.RenameColumn("my fruits", "name", "name"), "error in table my fruits after rename: duplicate column name: name")
+	require.EqualError(t, database.RenameColumn("my fruits", "name", "Name"), "error in table my fruits after rename: duplicate column name: Name")
+	require.NoError(t, database.RenameColumn("my fruits", "name", "title"))
+	info, err := ExtractQueryInfo("alter table \"my fruits\" add column size integer;")
+	require.NoError(t, err)
+	require.Equal(t, "size integer", info.DDL.ColumnSQL)
+	require.NoError(t, database.AddColumn(info.TableName, info.DDL.NewColumn, info.DDL.Constraints, info.DDL.ColumnSQL))
+	for _, test := range []struct {
+		query string
+		err   string
+	}{
+		{`alter table "my fruits" add column Size text`, "duplicate column name: Size"},
+		{`alter table "my fruits" add column code integer primary key`, "Cannot add a PRIMARY KEY column"},
+		{`alter table "my fruits" add column code text unique`, "Cannot add a UNIQUE column"},
+		{`alter table "my fruits" add column code text not null`, "Cannot add a NOT NULL column with default value NULL"},
+	} {
+		info, err := ExtractQueryInfo(test.query)
+		require.NoError(t, err)
+		require.EqualError(t, database.AddColumn(info.TableName, info.DDL.NewColumn, info.DDL.Constraints, info.DDL.ColumnSQL), test.err, test.query)
+	}
+	require.EqualError(t, database.DropColumn("my fruits", "id"), `cannot drop PRIMARY KEY column: "id"`)
+	require.NoError(t, database.DropColumn("my fruits", "color"))
+	require.Equal(t, uint32(6), database.Header().SchemaCookie)
+
+	schema, err := database.FindTableSchema("my fruits")
+	require.NoError(t, err)
+	require.Equal(t, `CREATE TABLE "my fruits" (id integer primary key, title text, size integer)`, schema.RawSQL)
+	tree, err = database.FindTablePage("my fruits")
+	require.NoError(t, err)
+	records, err := tree.GetRecords()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	values := make([]any, len(records[0].Header.Fields))
+	for i := range values {
+		values[i], err = records[0].Value(i)
+		require.NoError(t, err)
+	}
+	require.Equal(t, []any{nil, "apple", nil}, values)
+
+	// seeds doesn't have any row yet, none of them would read NULL
+	info, err = ExtractQueryInfo("alter table seeds add column weight integer not null")
+	require.NoError(t, err)
+	require.Equal(t, ColumnConstraints{NotNull: true}, info.DDL.Constraints)
+	require.NoError(t, database.AddColumn(info.TableName, info.DDL.NewColumn, info.DDL.Constraints, info.DDL.ColumnSQL))
+
+	// the columns of the automatic indexes are refused like the ones of the other indexes
+	info, err = ExtractQueryInfo("create table pips (id integer, name text unique, size integer, color text, primary key(size, color))")
+	require.NoError(t, err)
+	require.NoError(t, database.CreateTable(info.DDL.Table, false))
+	require.EqualError(t, database.DropColumn("pips", "name"), `cannot drop UNIQUE column: "name"`)
+	require.EqualError(t, database.DropColumn("pips", "color"), `cannot drop PRIMARY KEY column: "color"`)
+	require.NoError(t, database.DropColumn("pips", "id"))
+	schema, err = database.FindTableSchema("pips")
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE pips (name text unique, size integer, color text, primary key(size, color))", schema.RawSQL)
+
+	// a table constraint isn't a column, the columns it refers to can't be dropped
+	info, err = ExtractQueryInfo("create table stones (kind text, weight integer, hue text, unique(kind, weight))")
+	require.NoError(t, err)
+	require.NoError(t, database.CreateTable(info.DDL.Table, false))
+	require.EqualError(t, database.DropColumn("stones", "weight"), "error in table stones after drop column: no such column: weight")
+	require.NoError(t, database.DropColumn("stones", "hue"))
+	schema, err = database.FindTableSchema("stones")
+	require.NoError(t, err)
+	require.Equal(t, "CREATE TABLE stones (kind text, weight integer, unique(kind, weight))", schema.RawSQL)
+
+	require.NoError(t, database.DropTable("my fruits", false))
+	require.NoError(t, database.DropTable("my fruits", true))
+	require.EqualError(t, database.DropTable("my fruits", false), "no such table: my fruits")
+	require.Equal(t, uint32(1), database.Header().FreelistCount)
+	messages, err := database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
+
+func TestDropColumnManyRows(t *testing.T) {
+	database, err := CreateDB(vfs.NewMemFile(nil), CreateOptions{})
+	require.NoError(t, err)
+	info, err := ExtractQueryInfo("create table fruits (name text, notes text, color text)")
+	require.NoError(t, err)
+	require.NoError(t, database.CreateTable(info.DDL.Table, false))
+	tree, err := database.FindTablePage("fruits")
+	require.NoError(t, err)
+
+	// the notes spread the rows over many pages, some of them into overflow pages
+	require.NoError(t, database.Begin())
+	for rowID := int64(1); rowID <= 1000; rowID++ {
+		notes := strings.Repeat("n", int(rowID%300))
+		if rowID%100 == 0 {
+			notes = strings.Repeat("N", 5000)
+		}
+		record, err := EncodeRecord([]any{fmt.Sprintf("fruit %d", rowID), notes, "red"}, EncodingUTF8)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(rowID, record))
+	}
+	require.NoError(t, database.Commit())
+
+	require.NoError(t, database.DropColumn("fruits", "notes"))
+	records, err := tree.GetRecords()
+	require.NoError(t, err)
+	require.Len(t, records, 1000)
+	for i, record := range records {
+		require.Equal(t, int64(i+1), record.RowID)
+		require.Len(t, record.Header.Fields, 2)
+		name, err := record.Value(0)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("fruit %d", i+1), name)
+		color, err := record.Value(1)
+		require.NoError(t, err)
+		require.Equal(t, "red", color)
+	}
+	messages, err := database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
+
+func TestDropTable(t *testing.T) {
+	original, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	database, err := NewDB(vfs.NewMemFile(original))
+	require.NoError(t, err)
+
+	require.EqualError(t, database.DropTable("sqlite_sequence", false), "table sqlite_sequence may not be dropped")
+	require.NoError(t, database.RenameTable("oranges", "tangerines"))
+	require.NoError(t, database.DropTable("apples", false))
+	sequence, err := database.FindTablePage("sqlite_sequence")
+	require.NoError(t, err)
+	records, err := sequence.GetRecords()
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	name, err := records[0].Value(0)
+	require.NoError(t, err)
+	require.Equal(t, "tangerines", name)
+
+	_, err = database.FindTablePage("apples")
+	require.Error(t, err)
+	require.Positive(t, database.Header().FreelistCount)
+	messages, err := database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
@@ -0,0 +1,506 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	sqlparser "github.com/adzimzf/sqlite-go/sql"
+)
+
+// ExprRow is the row the column names of an expression refer to.
+type ExprRow struct {
+	Table    TableSchemaInfo
+	RowID    int64
+	Values   []any
+	Encoding TextEncoding
+}
+
+// EvalExpr evaluates the expression like sqlite does and returns nil, int64, float64, string or []byte.
+// The comparisons and the logical operators return 1, 0 or NULL.
+// The row can be nil when the expression doesn't refer to any column.
+func EvalExpr(expr sqlparser.Expr, row *ExprRow) (any, error) {
+	switch node := expr.(type) {
+	case *sqlparser.NullVal, *sqlparser.SQLVal:
+		return LiteralValue(expr)
+	case *sqlparser.ColName:
+		value, _, err := row.column(node)
+		return value, err
+	case *sqlparser.ParenExpr:
+		return EvalExpr(node.Expr, row)
+	case *sqlparser.UnaryExpr:
+		return evalUnary(node, row)
+	case *sqlparser.BinaryExpr:
+		left, err := EvalExpr(node.Left, row)
+		if err != nil {
+			return nil, err
+		}
+		right, err := EvalExpr(node.Right, row)
+		if err != nil {
+			return nil, err
+		}
+		return arithmetic(node.Operator, left, right), nil
+	case *sqlparser.ComparisonExpr:
+		return evalComparison(node, row)
+	case *sqlparser.IsExpr:
+		value, err := EvalExpr(node.Expr, row)
+		if err != nil {
+			return nil, err
+		}
+		return boolValue((value == nil) == (node.Operator == sqlparser.IsNullStr)), nil
+	case *sqlparser.NotExpr:
+		value, err := evalTruth(node.Expr, row)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		return boolValue(!*value), nil
+	case *sqlparser.AndExpr:
+		return evalLogical(node.Left, node.Right, false, row)
+	case *sqlparser.OrExpr:
+		return evalLogical(node.Left, node.Right, true, row)
+	}
+	return nil, fmt.Errorf("unsupported expression: %T", expr)
+}
+
+// IsTrue reports whether the WHERE expression holds for the row, NULL doesn't.
+func IsTrue(expr sqlparser.Expr, row *ExprRow) (bool, error) {
+	value, err := evalTruth(expr, row)
+	if err != nil || value == nil {
+		return false, err
+	}
+	return *value, nil
+}
+
+// CheckColumns fails when the expression refers to a column the table doesn't have.
+func CheckColumns(expr sqlparser.Expr, table TableSchemaInfo) error {
+	row := &ExprRow{Table: table}
+	return sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		if name, ok := node.(*sqlparser.ColName); ok {
+			_, _, err := row.column(name)
+			return false, err
+		}
+		return true, nil
+	}, expr)
+}
+
+// RowIDLookup returns the rowid when the WHERE expression is rowid = constant,
+// the only row which can match is found with a seek instead of a table scan.
+func RowIDLookup(where sqlparser.Expr, table TableSchemaInfo) (int64, bool) {
+	rowIDColumn, hasRowIDColumn := table.RowIDColumn()
+	value, ok := equalityLookup(where, table, func(column *TableColumnInfo) bool {
+		return column.Idx == -1 || hasRowIDColumn && column.Idx == rowIDColumn.Idx
+	})
+	if !ok {
+		return 0, false
+	}
+	rowID, ok := integerAffinity(value).(int64)
+	return rowID, ok
+}
+
+// PrimaryKeyLookup returns the PRIMARY KEY of a WITHOUT ROWID table when the WHERE expression
+// is pk = constant, the only row which can match is found with a seek instead of a table scan.
+func PrimaryKeyLookup(where sqlparser.Expr, table TableSchemaInfo) (any, bool) {
+	if !table.WithoutRowID {
+		return nil, false
+	}
+	value, ok := equalityLookup(where, table, func(column *TableColumnInfo) bool {
+		return column.Idx == table.PrimaryKey.Idx
+	})
+	if !ok || value == nil {
+		return nil, false
+	}
+	return table.PrimaryKey.ApplyAffinity(value), true
+}
+
+// equalityLookup returns the constant of the WHERE expression column = constant
+// when isKey holds for the column.
+func equalityLookup(where sqlparser.Expr, table TableSchemaInfo, isKey func(column *TableColumnInfo) bool) (any, bool) {
+	comparison, ok := unparen(where).(*sqlparser.ComparisonExpr)
+	if !ok || comparison.Operator != sqlparser.EqualStr {
+		return nil, false
+	}
+	row := &ExprRow{Table: table}
+	isKeyColumn := func(expr sqlparser.Expr) bool {
+		name, ok := unparen(expr).(*sqlparser.ColName)
+		if !ok {
+			return false
+		}
+		_, column, err := row.column(name)
+		return err == nil && isKey(column)
+	}
+
+	constant := comparison.Right
+	if !isKeyColumn(comparison.Left) {
+		if !isKeyColumn(comparison.Right) {
+			return nil, false
+		}
+		constant = comparison.Left
+	}
+	value, err := EvalExpr(constant, nil)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+func unparen(expr sqlparser.Expr) sqlparser.Expr {
+	for {
+		paren, ok := expr.(*sqlparser.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.Expr
+	}
+}
+
+// column returns the value of the column and the column itself, the rowid aliases and
+// the INTEGER PRIMARY KEY column are the rowid of the row.
+func (row *ExprRow) column(name *sqlparser.ColName) (any, *TableColumnInfo, error) {
+	fullName := name.Name.String()
+	if qualifier := name.Qualifier.Name.String(); qualifier != "" {
+		fullName = qualifier + "." + fullName
+		if row != nil && !strings.EqualFold(qualifier, row.Table.Name) {
+			return nil, nil, fmt.Errorf("no such column: %s", fullName)
+		}
+	}
+	if row == nil {
+		return nil, nil, fmt.Errorf("no such column: %s", fullName)
+	}
+
+	for i, column := range row.Table.Columns {
+		if !strings.EqualFold(column.Name, name.Name.String()) {
+			continue
+		}
+		if rowIDColumn, ok := row.Table.RowIDColumn(); ok && rowIDColumn.Idx == column.Idx {
+			return row.RowID, &row.Table.Columns[i], nil
+		}
+		if int(column.Idx) >= len(row.Values) {
+			// the columns added after the row has been written are NULL
+			return nil, &row.Table.Columns[i], nil
+		}
+		return normalizeValue(row.Values[column.Idx]), &row.Table.Columns[i], nil
+	}
+	switch strings.ToLower(name.Name.String()) {
+	case "rowid", "oid", "_rowid_":
+		if row.Table.WithoutRowID {
+			break
+		}
+		return row.RowID, &TableColumnInfo{Idx: -1, Name: name.Name.String(), Type: Int64}, nil
+	}
+	return nil, nil, fmt.Errorf("no such column: %s", fullName)
+}
+
+func evalUnary(node *sqlparser.UnaryExpr, row *ExprRow) (any, error) {
+	// -9223372036854775808 is the only integer literal which doesn't fit in int64 without its sign
+	if literal, ok := node.Expr.(*sqlparser.SQLVal); ok && node.Operator == sqlparser.UMinusStr &&
+		literal.Type == sqlparser.IntVal && string(literal.Val) == "9223372036854775808" {
+		return int64(math.MinInt64), nil
+	}
+
+	value, err := EvalExpr(node.Expr, row)
+	if err != nil || node.Operator == sqlparser.UPlusStr {
+		return value, err
+	}
+	switch n := toNumeric(value).(type) {
+	case int64:
+		if n == math.MinInt64 {
+			return -float64(n), nil
+		}
+		return -n, nil
+	case float64:
+		return -n, nil
+	}
+	return nil, nil
+}
+
+// evalComparison compares the operands after applying the affinity of the column operand
+// to the other operand, so a text column compares with a number as text.
+// See https://www.sqlite.org/datatype3.html#type_conversions_prior_to_comparison
+func evalComparison(node *sqlparser.ComparisonExpr, row *ExprRow) (any, error) {
+	left, leftColumn, err := evalOperand(node.Left, row)
+	if err != nil {
+		return nil, err
+	}
+	right, rightColumn, err := evalOperand(node.Right, row)
+	if err != nil {
+		return nil, err
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+
+	switch {
+	case isNumericColumn(leftColumn) && !isNumericColumn(rightColumn):
+		right = numericAffinity(right)
+	case isNumericColumn(rightColumn) && !isNumericColumn(leftColumn):
+		left = numericAffinity(left)
+	case isTextColumn(leftColumn) && rightColumn == nil:
+		right = leftColumn.ApplyAffinity(right)
+	case isTextColumn(rightColumn) && leftColumn == nil:
+		left = rightColumn.ApplyAffinity(left)
+	}
+
+	encoding := EncodingUTF8
+	if row != nil {
+		encoding = row.Encoding
+	}
+	c, err := compareValues(left, right, encoding)
+	if err != nil {
+		return nil, err
+	}
+	switch node.Operator {
+	case sqlparser.EqualStr:
+		return boolValue(c == 0), nil
+	case sqlparser.NotEqualStr:
+		return boolValue(c != 0), nil
+	case sqlparser.LessThanStr:
+		return boolValue(c < 0), nil
+	case sqlparser.GreaterThanStr:
+		return boolValue(c > 0), nil
+	case sqlparser.LessEqualStr:
+		return boolValue(c <= 0), nil
+	case sqlparser.GreaterEqualStr:
+		return boolValue(c >= 0), nil
+	}
+	return nil, fmt.Errorf("unsupported comparison operator: %s", node.Operator)
+}
+
+// evalOperand evaluates the operand of a comparison and returns the column when it's a column name.
+func evalOperand(expr sqlparser.Expr, row *ExprRow) (any, *TableColumnInfo, error) {
+	if name, ok := unparen(expr).(*sqlparser.ColName); ok {
+		return row.column(name)
+	}
+	value, err := EvalExpr(expr, row)
+	return value, nil, err
+}
+
+func isNumericColumn(column *TableColumnInfo) bool {
+	if column == nil {
+		return false
+	}
+	switch fieldTypeMapping[column.Type] {
+	case "INTEGER", "FLOAT":
+		return true
+	}
+	return false
+}
+
+func isTextColumn(column *TableColumnInfo) bool {
+	return column != nil && fieldTypeMapping[column.Type] == "TEXT"
+}
+
+// numericAffinity converts the text looking like a number to a number, unlike the INTEGER
+// affinity of a stored value the reals are kept.
+func numericAffinity(value any) any {
+	s, ok := value.(string)
+	if !ok || !isNumericText(strings.TrimSpace(s)) {
+		return value
+	}
+	return integerAffinity(s)
+}
+
+// evalLogical evaluates AND and OR with the three-valued logic of SQL:
+// NULL AND 0 is 0 and NULL OR 1 is 1, otherwise NULL wins.
+func evalLogical(leftExpr, rightExpr sqlparser.Expr, or bool, row *ExprRow) (any, error) {
+	left, err := evalTruth(leftExpr, row)
+	if err != nil {
+		return nil, err
+	}
+	if left != nil && *left == or {
+		return boolValue(or), nil
+	}
+	right, err := evalTruth(rightExpr, row)
+	if err != nil {
+		return nil, err
+	}
+	if right != nil && *right == or {
+		return boolValue(or), nil
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	return boolValue(!or), nil
+}
+
+// evalTruth evaluates the expression as a boolean, nil is NULL.
+func evalTruth(expr sqlparser.Expr, row *ExprRow) (*bool, error) {
+	value, err := EvalExpr(expr, row)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	var truth bool
+	switch n := toNumeric(value).(type) {
+	case int64:
+		truth = n != 0
+	case float64:
+		truth = n != 0
+	}
+	return &truth, nil
+}
+
+func boolValue(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// arithmetic applies the operator to the operands converted to numbers. An integer overflow
+// gives a real, a division by zero gives NULL and % works on the integer part of the operands.
+func arithmetic(operator string, left, right any) any {
+	left, right = toNumeric(left), toNumeric(right)
+	if left == nil || right == nil {
+		return nil
+	}
+
+	a, aIsInt := left.(int64)
+	b, bIsInt := right.(int64)
+	if operator == sqlparser.ModStr {
+		if !aIsInt {
+			a = realToInt(left.(float64))
+		}
+		if !bIsInt {
+			b = realToInt(right.(float64))
+		}
+		if b == 0 {
+			return nil
+		}
+		remainder := int64(0)
+		if b != -1 {
+			remainder = a % b
+		}
+		if aIsInt && bIsInt {
+			return remainder
+		}
+		return float64(remainder)
+	}
+
+	if aIsInt && bIsInt {
+		switch operator {
+		case sqlparser.PlusStr:
+			if sum := a + b; (sum > a) == (b > 0) {
+				return sum
+			}
+		case sqlparser.MinusStr:
+			if diff := a - b; (diff < a) == (b > 0) {
+				return diff
+			}
+		case sqlparser.MultStr:
+			if a == 0 || b == 0 {
+				return int64(0)
+			}
+			if product := a * b; product/b == a && !(a == -1 && b == math.MinInt64) && !(b == -1 && a == math.MinInt64) {
+				return product
+			}
+		case sqlparser.DivStr:
+			if b == 0 {
+				return nil
+			}
+			if !(a == math.MinInt64 && b == -1) {
+				return a / b
+			}
+		}
+	}
+
+	x, y := toFloat(left), toFloat(right)
+	switch operator {
+	case sqlparser.PlusStr:
+		return x + y
+	case sqlparser.MinusStr:
+		return x - y
+	case sqlparser.MultStr:
+		return x * y
+	case sqlparser.DivStr:
+		if y == 0 {
+			return nil
+		}
+		return x / y
+	}
+	return nil
+}
+
+func toFloat(n any) float64 {
+	if i, ok := n.(int64); ok {
+		return float64(i)
+	}
+	return n.(float64)
+}
+
+// realToInt converts the real to an integer like CAST(x AS INTEGER), saturating out of range values.
+func realToInt(f float64) int64 {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f <= math.MinInt64:
+		return math.MinInt64
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	}
+	return int64(f)
+}
+
+// toNumeric converts the value to int64 or float64 the way sqlite does for arithmetic:
+// the text and the blobs are read up to the longest prefix looking like a number.
+func toNumeric(value any) any {
+	switch v := normalizeValue(value).(type) {
+	case nil:
+		return nil
+	case int64, float64:
+		return v
+	case string:
+		return numericPrefix(v)
+	case []byte:
+		return numericPrefix(string(v))
+	}
+	return int64(0)
+}
+
+func numericPrefix(text string) any {
+	text = strings.TrimLeft(text, " \t\n\r\f\v")
+	end, digits, isReal := 0, false, false
+	if end < len(text) && (text[end] == '+' || text[end] == '-') {
+		end++
+	}
+	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+		end, digits = end+1, true
+	}
+	if end < len(text) && text[end] == '.' {
+		end, isReal = end+1, true
+		for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+			end, digits = end+1, true
+		}
+	}
+	if !digits {
+		return int64(0)
+	}
+	if end < len(text) && (text[end] == 'e' || text[end] == 'E') {
+		exponent := end + 1
+		if exponent < len(text) && (text[exponent] == '+' || text[exponent] == '-') {
+			exponent++
+		}
+		if exponent < len(text) && text[exponent] >= '0' && text[exponent] <= '9' {
+			for exponent < len(text) && text[exponent] >= '0' && text[exponent] <= '9' {
+				exponent++
+			}
+			end, isReal = exponent, true
+		}
+	}
+
+	prefix := text[:end]
+	if !isReal {
+		i, err := strconv.ParseInt(prefix, 10, 64)
+		if err == nil {
+			return i
+		}
+		if !errors.Is(err, strconv.ErrRange) {
+			return int64(0)
+		}
+	}
+	f, err := strconv.ParseFloat(prefix, 64)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return int64(0)
+	}
+	return f
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"testing"
+
+	sqlparser "github.com/adzimzf/sqlite-go/sql"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalExpr(t *testing.T) {
+	row := &ExprRow{
+		Table: TableSchemaInfo{
+			Name:       "apples",
+			PrimaryKey: TableColumnInfo{Idx: 0, Name: "id", Type: Int64},
+			Columns: []TableColumnInfo{
+				{Idx: 0, Name: "id", Type: Int64},
+				{Idx: 1, Name: "name", Type: String},
+				{Idx: 2, Name: "size", Type: Int64},
+			},
+		},
+		RowID:  7,
+		Values: []any{nil, "12", int8(3)},
+	}
+
+	tcs := []struct {
+		expr string
+		want any
+	}{
+		{expr: "id", want: int64(7)},
+		{expr: "rowid + size", want: int64(10)},
+		{expr: "name = 12", want: int64(1)},
+		{expr: "size = '3'", want: int64(1)},
+		{expr: "'12' = 12", want: int64(0)},
+		{expr: "size / 0", want: nil},
+		{expr: "7 / 2", want: int64(3)},
+		{expr: "7.0 / 2", want: 3.5},
+		{expr: "5.5 % 2", want: 1.0},
+		{expr: "'3abc' + 1", want: int64(4)},
+		{expr: "9223372036854775807 + 1", want: 9223372036854775808.0},
+		{expr: "-9223372036854775808", want: int64(-9223372036854775808)},
+		{expr: "NULL AND 0", want: int64(0)},
+		{expr: "NULL OR 0", want: nil},
+		{expr: "NOT size > 2", want: int64(0)},
+		{expr: "name IS NOT NULL", want: int64(1)},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.expr, func(t *testing.T) {
+			stmt, err := sqlparser.Parse("UPDATE apples SET size = " + tc.expr)
+			require.NoError(t, err)
+			got, err := EvalExpr(stmt.(*sqlparser.Update).Exprs[0].Expr, row)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	_, err := EvalExpr(&sqlparser.ColName{Name: sqlparser.NewColIdent("color")}, row)
+	require.EqualError(t, err, "no such column: color")
+}
@@ -0,0 +1,596 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// DefaultIntegrityErrors is the number of problems reported by PRAGMA integrity_check without argument.
+const DefaultIntegrityErrors = 100
+
+// IntegrityCheck verifies the freelist and the structure of every b-tree of the schema, that every
+// page is used once, and that the indexes have an entry for every row of their table. The problems
+// are reported with the messages of sqlite's PRAGMA integrity_check, up to maxErrors of them, the
+// problems of the file structure all come in the first message. The quick check doesn't verify the
+// content of the indexes like PRAGMA quick_check. It returns nil when the database is well-formed.
+// Like sqlite even the quick check reads every row, a record which can't be decoded is reported
+// with its row and the check goes on.
+// See https://www.sqlite.org/pragma.html#pragma_integrity_check
+func (d *DB) IntegrityCheck(maxErrors int, quick bool) ([]string, error) {
+	if err := d.BeginRead(); err != nil {
+		return nil, err
+	}
+	defer d.EndRead()
+	tables, err := d.tableRoots()
+	if err != nil {
+		return nil, err
+	}
+	indexSchemas, err := d.indexSchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	// the schema is checked in the order sqlite walks it, the latest table first with its latest index
+	// first, and every table is followed by its indexes
+	tables = append([]TableSchemaInfo{{Name: "sqlite_master", PageID: 1}}, tables...)
+	slices.Reverse(tables)
+	slices.Reverse(indexSchemas)
+	var roots []uint32
+	tableIndexes := make([][]IndexSchemaInfo, len(tables))
+	for i, table := range tables {
+		roots = append(roots, uint32(table.PageID))
+		for _, indexSchema := range indexSchemas {
+			if indexSchema.TableName == table.Name {
+				tableIndexes[i] = append(tableIndexes[i], indexSchema)
+				roots = append(roots, uint32(indexSchema.PageID))
+			}
+		}
+	}
+
+	check := newIntegrityChecker(d.pager, maxErrors)
+	rowCounts := check.checkFile(roots)
+	var messages []string
+	if len(check.messages) > 0 {
+		messages = append(messages, "*** in database main ***\n"+strings.Join(check.messages, "\n"))
+	}
+	remaining := check.remaining
+
+	// the number of entries of every index is the number of rows of its table
+	root := 0
+	for i := range tables {
+		tableRows := rowCounts[root]
+		root++
+		for _, indexSchema := range tableIndexes[i] {
+			if remaining > 0 && rowCounts[root] != tableRows {
+				messages = append(messages, "wrong # of entries in index "+indexSchema.Name)
+				remaining--
+			}
+			root++
+		}
+	}
+
+	for i, table := range tables {
+		if remaining <= 0 {
+			break
+		}
+		rowMessages, err := d.checkTableRows(table, tableIndexes[i], quick, remaining)
+		messages = append(messages, rowMessages...)
+		if err != nil {
+			return messages, err
+		}
+		remaining -= len(rowMessages)
+	}
+	return messages, nil
+}
+
+// tableRoots returns the name and the root page of every table found in sqlite_master,
+// their columns are only parsed when they are needed.
+func (d *DB) tableRoots() ([]TableSchemaInfo, error) {
+	sqliteMaster, err := d.FindSQLiteMaster()
+	if err != nil {
+		return nil, err
+	}
+	records, err := sqliteMaster.GetRecords()
+	if err != nil {
+		return nil, err
+	}
+	var tables []TableSchemaInfo
+	for _, record := range records {
+		objectType, err := record.Value(0)
+		if err != nil {
+			return nil, err
+		}
+		if objectType != "table" {
+			continue
+		}
+		name, err := record.Value(1)
+		if err != nil {
+			return nil, err
+		}
+		rootPage, err := record.IntField(3)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, TableSchemaInfo{Name: fmt.Sprint(name), PageID: rootPage})
+	}
+	return tables, nil
+}
+
+// checkTableRows verifies that every record of the table can be decoded and that every PRIMARY KEY
+// and UNIQUE constraint has its automatic index. Unless it's the quick check, it verifies that every
+// row has its entry in every index and that the entries of a UNIQUE index aren't duplicated.
+// The rows are numbered from 1 in the messages.
+func (d *DB) checkTableRows(table TableSchemaInfo, indexSchemas []IndexSchemaInfo, quick bool, maxErrors int) ([]string, error) {
+	var err error
+	if table.PageID == 1 {
+		table, err = d.FindSQLiteSchema()
+		table.Name = "sqlite_master"
+	} else {
+		table, err = d.FindTableSchema(table.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	for _, autoIndex := range table.AutoIndexes() {
+		if autoIndex.PrimaryKey && table.WithoutRowID || len(messages) >= maxErrors {
+			continue
+		}
+		found := slices.ContainsFunc(indexSchemas, func(indexSchema IndexSchemaInfo) bool {
+			return strings.EqualFold(indexSchema.Name, autoIndex.Name)
+		})
+		if !found {
+			messages = append(messages, fmt.Sprintf("%s constraint on %s has no automatic index %s",
+				autoIndex.kind(), table.constraintColumns(autoIndex.UniqueConstraint), autoIndex.Name))
+		}
+	}
+	if quick {
+		indexSchemas = nil
+	}
+
+	indexes := make([]*IndexBTree, len(indexSchemas))
+	cursors := make([]*BTreeCursor, len(indexSchemas))
+	for i, indexSchema := range indexSchemas {
+		index, err := d.newIndexBTree(indexSchema, table)
+		if err != nil {
+			return nil, err
+		}
+		indexes[i] = index
+		cursors[i] = index.Cursor()
+		defer cursors[i].Close()
+	}
+
+	cursor, err := d.TableCursor(table)
+	if err != nil {
+		return messages, err
+	}
+	defer cursor.Close()
+	row := int64(0)
+	err = cursor.First()
+	for ; err == nil && cursor.Valid() && len(messages) < maxErrors; err = cursor.Next() {
+		row++
+		rowID, values, err := rowValues(cursor, table)
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("row %d of %s: database disk image is malformed", row, table.Name))
+			continue
+		}
+
+		for i, index := range indexes {
+			if len(messages) >= maxErrors {
+				break
+			}
+			key, err := table.IndexKey(index, values, rowID)
+			if err != nil {
+				return messages, err
+			}
+			found, err := cursors[i].SeekKey(key)
+			if err != nil {
+				return messages, err
+			}
+			if !found {
+				messages = append(messages, fmt.Sprintf("row %d missing from index %s", row, index.Name))
+				continue
+			}
+			duplicated, err := nextHasPrefix(cursors[i], index, key[:len(index.Columns)])
+			if err != nil {
+				return messages, err
+			}
+			if duplicated {
+				messages = append(messages, "non-unique entry in index "+index.Name)
+			}
+		}
+	}
+	return messages, err
+}
+
+// rowValues returns the rowid and the values of the columns of the row at the cursor.
+func rowValues(cursor *BTreeCursor, table TableSchemaInfo) (int64, []any, error) {
+	rowID, err := cursor.RowID()
+	if err != nil {
+		return 0, nil, err
+	}
+	record, err := cursor.Record()
+	if err != nil {
+		return 0, nil, err
+	}
+	record = table.TableRecord(record)
+	values := make([]any, len(table.Columns))
+	for i := range values {
+		// the columns added after the row has been written are NULL
+		if i >= len(record.Header.Fields) {
+			break
+		}
+		if values[i], err = record.Value(i); err != nil {
+			return 0, nil, err
+		}
+	}
+	return rowID, values, nil
+}
+
+// nextHasPrefix reports whether the entry after the cursor of a UNIQUE index has the same values,
+// the entries with a NULL value are never duplicated.
+func nextHasPrefix(cursor *BTreeCursor, index *IndexBTree, prefix []any) (bool, error) {
+	if !index.Unique {
+		return false, nil
+	}
+	for _, value := range prefix {
+		if value == nil {
+			return false, nil
+		}
+	}
+	if err := cursor.Next(); err != nil || !cursor.Valid() {
+		return false, err
+	}
+	record, err := cursor.Record()
+	if err != nil {
+		return false, err
+	}
+	cmp, err := compareKey(record, prefix, index.desc())
+	return cmp == 0, err
+}
+
+// integrityChecker walks the pages of the file like sqlite's checkTreePage and checkList, and keeps
+// their messages. The prefix of the messages is a format of the root, the page and the cell checked.
+type integrityChecker struct {
+	pager      *Pager
+	pageCount  uint32
+	referenced []bool
+	messages   []string
+	remaining  int
+	// autoVacuum is set when the pages have pointer-map entries to check
+	autoVacuum bool
+
+	prefix           string
+	root, page, cell uint32
+	// rowCount is the number of entries of the b-tree being checked
+	rowCount int64
+}
+
+func newIntegrityChecker(pager *Pager, maxErrors int) *integrityChecker {
+	return &integrityChecker{
+		pager:      pager,
+		pageCount:  pager.header.DatabaseSize,
+		referenced: make([]bool, pager.header.DatabaseSize+1),
+		remaining:  maxErrors,
+		autoVacuum: pager.autoVacuum(),
+	}
+}
+
+func (c *integrityChecker) errorf(format string, args ...any) {
+	if c.remaining <= 0 {
+		return
+	}
+	c.remaining--
+	prefixArgs := []any{c.root, c.page, c.cell}[:strings.Count(c.prefix, "%d")]
+	c.messages = append(c.messages, fmt.Sprintf(c.prefix, prefixArgs...)+fmt.Sprintf(format, args...))
+}
+
+// checkFile checks the freelist, the b-trees and that every page is used, it returns
+// the number of entries of every b-tree.
+func (c *integrityChecker) checkFile(roots []uint32) []int64 {
+	header := c.pager.header
+	if pendingBytePage := c.pager.pendingBytePage(); pendingBytePage <= c.pageCount {
+		c.referenced[pendingBytePage] = true
+	}
+
+	c.prefix = "Freelist: "
+	c.checkList(true, header.FreelistTrunkPage, header.FreelistCount)
+	c.prefix = ""
+
+	if c.autoVacuum {
+		largestRoot := uint32(0)
+		for _, root := range roots {
+			largestRoot = max(largestRoot, root)
+		}
+		if largestRoot != header.LargestRootPage {
+			c.errorf("max rootpage (%d) disagrees with header (%d)", largestRoot, header.LargestRootPage)
+		}
+	} else if header.IncrementalVacuum != 0 {
+		c.errorf("incremental_vacuum enabled with a max rootpage of zero")
+	}
+
+	rowCounts := make([]int64, len(roots))
+	for i, root := range roots {
+		if c.remaining <= 0 {
+			break
+		}
+		if c.autoVacuum && root > 1 {
+			c.checkPtrmap(root, ptrmapRootPage, 0)
+		}
+		c.rowCount = 0
+		c.root = root
+		var minKey int64
+		c.checkTreePage(root, &minKey, math.MaxInt64)
+		rowCounts[i] = c.rowCount
+	}
+
+	// the pointer-map pages are the only pages which aren't referenced
+	for pageNumber := uint32(1); pageNumber <= c.pageCount && c.remaining > 0; pageNumber++ {
+		ptrmap := c.pager.isPtrmapPage(pageNumber)
+		if !c.referenced[pageNumber] && !ptrmap {
+			c.errorf("Page %d: never used", pageNumber)
+		}
+		if c.referenced[pageNumber] && ptrmap {
+			c.errorf("Page %d: pointer map referenced", pageNumber)
+		}
+	}
+	return rowCounts
+}
+
+// checkPtrmap checks the pointer-map entry of the page of an auto-vacuum database.
+func (c *integrityChecker) checkPtrmap(pageNumber uint32, entryType byte, parent uint32) {
+	actualType, actualParent, err := c.pager.readPtrmapEntry(pageNumber)
+	if err != nil {
+		c.errorf("Failed to read ptrmap key=%d", pageNumber)
+		return
+	}
+	if actualType != entryType || actualParent != parent {
+		c.errorf("Bad ptr map entry key=%d expected=(%d,%d) got=(%d,%d)",
+			pageNumber, entryType, parent, actualType, actualParent)
+	}
+}
+
+// checkRef marks the page as used, it reports whether the page is invalid or already used.
+func (c *integrityChecker) checkRef(pageNumber uint32) bool {
+	if pageNumber == 0 || pageNumber > c.pageCount {
+		c.errorf("invalid page number %d", pageNumber)
+		return true
+	}
+	if c.referenced[pageNumber] {
+		c.errorf("2nd reference to page %d", pageNumber)
+		return true
+	}
+	c.referenced[pageNumber] = true
+	return false
+}
+
+// checkList follows a chain of overflow pages or of freelist trunk pages and checks it has
+// the expected number of pages, the leaves of the trunk pages included.
+func (c *integrityChecker) checkList(freelist bool, pageNumber uint32, expected uint32) {
+	remaining := int64(expected)
+	errors := c.remaining
+	for pageNumber != 0 && c.remaining > 0 {
+		if c.checkRef(pageNumber) {
+			break
+		}
+		remaining--
+		page, err := c.pager.Get(pageNumber)
+		if err != nil {
+			c.errorf("failed to get page %d", pageNumber)
+			break
+		}
+		c.pager.Unpin(page)
+		next := binary.BigEndian.Uint32(page.Data[0:4])
+		if freelist {
+			if c.autoVacuum {
+				c.checkPtrmap(pageNumber, ptrmapFreePage, 0)
+			}
+			leafCount := binary.BigEndian.Uint32(page.Data[4:8])
+			if int64(leafCount) > int64(c.pager.limits.usableSize/4-2) {
+				c.errorf("freelist leaf count too big on page %d", pageNumber)
+				remaining--
+			} else {
+				for i := uint32(0); i < leafCount; i++ {
+					leaf := binary.BigEndian.Uint32(page.Data[8+4*i:])
+					if c.autoVacuum {
+						c.checkPtrmap(leaf, ptrmapFreePage, 0)
+					}
+					c.checkRef(leaf)
+				}
+				remaining -= int64(leafCount)
+			}
+		} else if c.autoVacuum && remaining > 0 {
+			// the overflow pages but the first one have the previous page as parent
+			c.checkPtrmap(next, ptrmapOverflow2, pageNumber)
+		}
+		pageNumber = next
+	}
+	if remaining != 0 && errors == c.remaining {
+		name := "overflow list length"
+		if freelist {
+			name = "size"
+		}
+		c.errorf("%s is %d but should be %d", name, uint32(int64(expected)-remaining), expected)
+	}
+}
+
+// checkTreePage checks the page and its children, and returns the depth of the subtree.
+// The rowids of a table must be below maxKey, minKey is set to the smallest rowid of the subtree.
+func (c *integrityChecker) checkTreePage(pageNumber uint32, minKey *int64, maxKey int64) int {
+	savedPrefix, savedPage, savedCell := c.prefix, c.page, c.cell
+	defer func() {
+		c.prefix, c.page, c.cell = savedPrefix, savedPage, savedCell
+	}()
+	if c.remaining <= 0 || pageNumber == 0 {
+		return 0
+	}
+	if c.checkRef(pageNumber) {
+		return 0
+	}
+	c.prefix = "Tree %d page %d: "
+	c.page = pageNumber
+	page, err := c.pager.Get(pageNumber)
+	if err != nil {
+		c.errorf("unable to get the page. error code=%d", 1)
+		return 0
+	}
+	c.pager.Unpin(page)
+	data := page.Data
+	usableSize := c.pager.limits.usableSize
+	headerOffset := btreeHeaderOffset(int(pageNumber))
+
+	// the checks of sqlite's btreeInitPage and btreeComputeFreeSpace
+	pageType := BTreePageType(data[headerOffset])
+	leaf := pageType == BTREE_LEAF_TABLE || pageType == BTREE_LEAF_INDEX
+	cellCount := int(binary.BigEndian.Uint16(data[headerOffset+3:]))
+	if !leaf && pageType != BTREE_INTERNAL_TABLE && pageType != BTREE_INTERNAL_PAGE ||
+		cellCount > (c.pager.limits.pageSize-8)/6 {
+		c.errorf("btreeInitPage() returns error code %d", 11)
+		return 0
+	}
+	cellStart := headerOffset + 8
+	if !leaf {
+		cellStart += 4
+	}
+	if !pageFreeSpaceValid(data, headerOffset, cellStart+2*cellCount, usableSize) {
+		c.errorf("free space corruption")
+		return 0
+	}
+
+	c.prefix = "Tree %d page %d cell %d: "
+	contentOffset := int(binary.BigEndian.Uint16(data[headerOffset+5:]))
+	if leaf || pageType == BTREE_LEAF_INDEX || pageType == BTREE_INTERNAL_PAGE {
+		c.rowCount += int64(cellCount)
+	}
+
+	depth := -1
+	keyCanBeEqual := true
+	if !leaf {
+		rightChild := binary.BigEndian.Uint32(data[headerOffset+8:])
+		// like sqlite, the prefix is kept for the messages of the cells
+		if c.autoVacuum {
+			c.prefix = "Tree %d page %d right child: "
+			c.checkPtrmap(rightChild, ptrmapBTree, pageNumber)
+		}
+		depth = c.checkTreePage(rightChild, &maxKey, maxKey)
+		keyCanBeEqual = false
+	}
+
+	coverageCheck := true
+	cells := make([][2]int, 0, cellCount)
+	for i := cellCount - 1; i >= 0 && c.remaining > 0; i-- {
+		c.cell = uint32(i)
+		pc := int(binary.BigEndian.Uint16(data[cellStart+2*i:]))
+		if pc < contentOffset || pc > usableSize-4 {
+			c.errorf("Offset %d out of range %d..%d", pc, contentOffset, usableSize-4)
+			coverageCheck = false
+			continue
+		}
+		cell, size, err := decodeCell(pageType, data[pc:], c.pager.limits)
+		overflow := err == nil && int64(len(cell.Payload)) < cell.Size
+		if overflow && cell.OverflowPage == 0 {
+			size += 4
+		}
+		// a cell takes at least 4 bytes so it can become a freeblock
+		size = max(size, 4)
+		if err != nil || pc+size > usableSize {
+			c.errorf("Extends off end of page")
+			coverageCheck = false
+			continue
+		}
+		cells = append(cells, [2]int{pc, pc + size - 1})
+
+		if pageType == BTREE_LEAF_TABLE || pageType == BTREE_INTERNAL_TABLE {
+			if keyCanBeEqual && cell.RowID > maxKey || !keyCanBeEqual && cell.RowID >= maxKey {
+				c.errorf("Rowid %d out of order", cell.RowID)
+			}
+			maxKey = cell.RowID
+			keyCanBeEqual = false
+		}
+
+		if overflow {
+			pages := (cell.Size - int64(len(cell.Payload)) + int64(usableSize) - 5) / int64(usableSize-4)
+			if c.autoVacuum {
+				c.checkPtrmap(cell.OverflowPage, ptrmapOverflow1, pageNumber)
+			}
+			c.checkList(false, cell.OverflowPage, uint32(pages))
+		}
+
+		if !leaf {
+			if c.autoVacuum {
+				c.checkPtrmap(cell.LeftChild, ptrmapBTree, pageNumber)
+			}
+			childDepth := c.checkTreePage(cell.LeftChild, &maxKey, maxKey)
+			keyCanBeEqual = false
+			if childDepth != depth {
+				c.errorf("Child page depth differs")
+				depth = childDepth
+			}
+		}
+	}
+	*minKey = maxKey
+
+	// every byte of the cell content area is used by a cell, a freeblock or a fragment
+	c.prefix = ""
+	if coverageCheck && c.remaining > 0 {
+		for offset := int(binary.BigEndian.Uint16(data[headerOffset+1:])); offset > 0; {
+			size := int(binary.BigEndian.Uint16(data[offset+2:]))
+			cells = append(cells, [2]int{offset, offset + size - 1})
+			offset = int(binary.BigEndian.Uint16(data[offset:]))
+		}
+		sort.Slice(cells, func(i, j int) bool { return cells[i][0] < cells[j][0] })
+		fragments, previous, overlap := 0, contentOffset-1, false
+		for _, area := range cells {
+			if previous >= area[0] {
+				c.errorf("Multiple uses for byte %d of page %d", area[0], pageNumber)
+				overlap = true
+				break
+			}
+			fragments += area[0] - previous - 1
+			previous = area[1]
+		}
+		fragments += usableSize - previous - 1
+		if !overlap && fragments != int(data[headerOffset+7]) {
+			c.errorf("Fragmentation of %d bytes reported as %d on page %d", fragments, data[headerOffset+7], pageNumber)
+		}
+	}
+	return depth + 1
+}
+
+// pageFreeSpaceValid checks the freeblock list of a page the way sqlite's btreeComputeFreeSpace does:
+// the freeblocks are in the cell content area in ascending order, and the free space fits in the page.
+func pageFreeSpaceValid(data []byte, headerOffset, cellFirst, usableSize int) bool {
+	top := int(binary.BigEndian.Uint16(data[headerOffset+5:]))
+	if top == 0 {
+		top = 65536
+	}
+	free := int(data[headerOffset+7]) + top
+	offset := int(binary.BigEndian.Uint16(data[headerOffset+1:]))
+	if offset > 0 {
+		if offset < cellFirst {
+			return false
+		}
+		var next, size int
+		for {
+			if offset > usableSize-4 {
+				return false
+			}
+			next = int(binary.BigEndian.Uint16(data[offset:]))
+			size = int(binary.BigEndian.Uint16(data[offset+2:]))
+			free += size
+			if next <= offset+size+3 {
+				break
+			}
+			offset = next
+		}
+		if next > 0 || offset+size > usableSize {
+			return false
+		}
+	}
+	return free <= usableSize && free >= cellFirst
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrityCheck(t *testing.T) {
+	original, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	database, err := NewDB(vfs.NewMemFile(append([]byte(nil), original...)))
+	require.NoError(t, err)
+	messages, err := database.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+
+	// a freelist page which doesn't exist and fragments missing from the root page of apples
+	corrupted := append([]byte(nil), original...)
+	binary.BigEndian.PutUint32(corrupted[36:40], 1)
+	pageSize := int(binary.BigEndian.Uint16(corrupted[16:18]))
+	corrupted[pageSize+7] = 3
+	database, err = NewDB(vfs.NewMemFile(corrupted))
+	require.NoError(t, err)
+	messages, err = database.IntegrityCheck(DefaultIntegrityErrors, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"*** in database main ***\n" +
+		"Freelist: size is 0 but should be 1\n" +
+		"Fragmentation of 0 bytes reported as 3 on page 2"}, messages)
+
+	messages, err = database.IntegrityCheck(1, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{"*** in database main ***\nFreelist: size is 0 but should be 1"}, messages)
+}
+
+func TestIntegrityCheckRows(t *testing.T) {
+	file := vfs.NewMemFile(nil)
+	database, err := CreateDB(file, CreateOptions{})
+	require.NoError(t, err)
+	info, err := ExtractQueryInfo("create table fruits (name text, color text)")
+	require.NoError(t, err)
+	require.NoError(t, database.CreateTable(info.DDL.Table, false))
+	tree, err := database.FindTablePage("fruits")
+	require.NoError(t, err)
+	require.NoError(t, database.Begin())
+	for i, name := range []string{"apple", "banana", "cherry"} {
+		record, err := EncodeRecord([]any{name, "red"}, EncodingUTF8)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(int64(i+1), record))
+	}
+	// the constraints of a table written without their automatic indexes
+	require.NoError(t, database.insertTable("seeds", "CREATE TABLE seeds (name text unique, pip integer, primary key(pip, name))", BTREE_LEAF_TABLE))
+	require.NoError(t, database.Commit())
+
+	// the text of the name of banana and of cherry goes past the end of their records
+	data := file.Bytes()
+	for _, name := range []string{"banana", "cherry"} {
+		i := bytes.Index(data, []byte(name))
+		data[i-2] = 0x7f
+	}
+	database, err = NewDB(vfs.NewMemFile(data))
+	require.NoError(t, err)
+	for _, quick := range []bool{false, true} {
+		messages, err := database.IntegrityCheck(DefaultIntegrityErrors, quick)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"UNIQUE constraint on seeds(name) has no automatic index sqlite_autoindex_seeds_1",
+			"PRIMARY KEY constraint on seeds(pip, name) has no automatic index sqlite_autoindex_seeds_2",
+			"row 2 of fruits: database disk image is malformed",
+			"row 3 of fruits: database disk image is malformed",
+		}, messages)
+	}
+	messages, err := database.IntegrityCheck(3, false)
+	require.NoError(t, err)
+	require.Len(t, messages, 3)
+}
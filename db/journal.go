@@ -0,0 +1,251 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/adzimzf/sqlite-go/constant"
+	"github.com/adzimzf/sqlite-go/vfs"
+)
+
+// journalMagic starts every header of a rollback journal.
+var journalMagic = []byte{0xd9, 0xd5, 0x05, 0xf9, 0x20, 0xa1, 0x63, 0xd7}
+
+const (
+	// journalSectorSize is the size of the journal header, the page records start after it
+	journalSectorSize = 512
+	// journalHeaderSize is the used part of the journal header
+	journalHeaderSize = 28
+)
+
+// journal is the rollback journal of a database: before a commit overwrites the pages of the
+// database file, their original content is written and synced to the journal. Deleting the journal
+// commits the transaction, a journal left behind by a crash is hot and is played back to restore
+// the database as it was before the transaction.
+// See https://www.sqlite.org/fileformat2.html#the_rollback_journal
+type journal struct {
+	fs   vfs.VFS
+	name string
+}
+
+// journalPage is the original content of a page of the database.
+type journalPage struct {
+	number uint32
+	data   []byte
+}
+
+// newJournal returns the journal of the database file, nil when the file doesn't know its name.
+func newJournal(file vfs.File) *journal {
+	fs, name, ok := companionFile(file, "-journal")
+	if !ok {
+		return nil
+	}
+	return &journal{fs: fs, name: name}
+}
+
+// companionFile returns the storage and the name of the file living next to the database file
+// with the suffix, ok is false when the database file doesn't know its name.
+func companionFile(file vfs.File, suffix string) (fs vfs.VFS, name string, ok bool) {
+	named, ok := file.(vfs.NamedFile)
+	if !ok || named.Name() == "" || named.VFS() == nil {
+		return nil, "", false
+	}
+	return named.VFS(), named.Name() + suffix, true
+}
+
+// write writes the original pages to the journal and syncs it. The number of page records is only
+// written once the records are synced so a torn journal is never played back.
+func (j *journal) write(pages []journalPage, databaseSize uint32, pageSize int) error {
+	file, err := j.fs.Open(j.name, vfs.OpenReadWrite|vfs.OpenCreate)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+
+	nonce := rand.Uint32()
+	header := make([]byte, journalSectorSize)
+	copy(header, journalMagic)
+	binary.BigEndian.PutUint32(header[12:16], nonce)
+	binary.BigEndian.PutUint32(header[16:20], databaseSize)
+	binary.BigEndian.PutUint32(header[20:24], journalSectorSize)
+	binary.BigEndian.PutUint32(header[24:28], uint32(pageSize))
+
+	buf := bytes.NewBuffer(header)
+	for _, page := range pages {
+		buf.Write(binary.BigEndian.AppendUint32(nil, page.number))
+		buf.Write(page.data)
+		buf.Write(binary.BigEndian.AppendUint32(nil, journalChecksum(nonce, page.data)))
+	}
+	if _, err := file.WriteAt(buf.Bytes(), 0); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+
+	if _, err := file.WriteAt(binary.BigEndian.AppendUint32(nil, uint32(len(pages))), 8); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// delete removes the journal, it's the moment the transaction is committed.
+func (j *journal) delete() error {
+	return j.fs.Delete(j.name)
+}
+
+// hot reports whether a journal has been left behind by a transaction which hasn't completed.
+func (j *journal) hot() (bool, error) {
+	exists, err := j.fs.Exists(j.name)
+	if err != nil || !exists {
+		return false, err
+	}
+	file, err := j.fs.Open(j.name, vfs.OpenReadOnly)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	size, err := file.Size()
+	return size > 0, err
+}
+
+// recover plays back a hot journal. A journal is only hot when no other connection is writing,
+// so it's played back holding the EXCLUSIVE lock, and left alone when the lock can't be taken.
+func (j *journal) recover(file vfs.File) error {
+	hot, err := j.hot()
+	if err != nil || !hot {
+		return err
+	}
+	if err := file.Lock(vfs.LockShared); err != nil {
+		return err
+	}
+	defer file.Unlock(vfs.LockNone)
+	if err := file.Lock(vfs.LockReserved); err != nil {
+		if errors.Is(err, vfs.ErrBusy) {
+			return nil
+		}
+		return err
+	}
+	if err := file.Lock(vfs.LockExclusive); err != nil {
+		return err
+	}
+
+	// the journal may have been played back by another connection while waiting for the lock
+	if hot, err := j.hot(); err != nil || !hot {
+		return err
+	}
+	if err := j.rollback(file); err != nil {
+		return fmt.Errorf("cannot rollback the hot journal %s: %w", j.name, err)
+	}
+	return nil
+}
+
+// rollback writes the original pages of the journal back to the database file, truncates it to
+// its size before the transaction and deletes the journal. The play back stops at the first
+// record which checksum doesn't match, it has never been synced.
+func (j *journal) rollback(file vfs.File) error {
+	journalFile, err := j.fs.Open(j.name, vfs.OpenReadOnly)
+	if err != nil {
+		return err
+	}
+	size, err := journalFile.Size()
+	if err != nil {
+		journalFile.Close()
+		return err
+	}
+
+	var databaseSize uint32
+	var pageSize int64
+	truncate := false
+	for offset := int64(0); offset+journalHeaderSize <= size; {
+		header := make([]byte, journalHeaderSize)
+		if err := readFullAt(journalFile, header, offset); err != nil {
+			journalFile.Close()
+			return err
+		}
+		if !bytes.Equal(header[:8], journalMagic) {
+			break
+		}
+		recordCount := binary.BigEndian.Uint32(header[8:12])
+		nonce := binary.BigEndian.Uint32(header[12:16])
+		sectorSize := int64(binary.BigEndian.Uint32(header[20:24]))
+		if sectorSize < 32 || sectorSize > 65536 || sectorSize&(sectorSize-1) != 0 {
+			break
+		}
+		// the first header has the database size and the page size of the whole journal
+		if offset == 0 {
+			databaseSize = binary.BigEndian.Uint32(header[16:20])
+			pageSize = int64(binary.BigEndian.Uint32(header[24:28]))
+			if pageSize < 512 || pageSize > 65536 || pageSize&(pageSize-1) != 0 {
+				break
+			}
+			truncate = true
+		}
+		offset += sectorSize
+
+		recordSize := pageSize + 8
+		// the number of records isn't known when it's 0xffffffff, they go up to the end of the journal
+		if recordCount == 0xffffffff {
+			recordCount = uint32((size - offset) / recordSize)
+		}
+		ok := true
+		for i := uint32(0); i < recordCount; i++ {
+			if offset+recordSize > size {
+				ok = false
+				break
+			}
+			record := make([]byte, recordSize)
+			if err := readFullAt(journalFile, record, offset); err != nil {
+				journalFile.Close()
+				return err
+			}
+			offset += recordSize
+
+			pageNumber := binary.BigEndian.Uint32(record[:4])
+			data := record[4 : 4+pageSize]
+			if pageNumber == 0 || binary.BigEndian.Uint32(record[4+pageSize:]) != journalChecksum(nonce, data) {
+				ok = false
+				break
+			}
+			if int64(pageNumber) == constant.PendingByte/pageSize+1 {
+				continue
+			}
+			if _, err := file.WriteAt(data, int64(pageNumber-1)*pageSize); err != nil {
+				journalFile.Close()
+				return err
+			}
+		}
+		if !ok {
+			break
+		}
+		// the next header starts on the next sector
+		offset = (offset + sectorSize - 1) / sectorSize * sectorSize
+	}
+	journalFile.Close()
+
+	if truncate {
+		if err := file.Truncate(int64(databaseSize) * pageSize); err != nil {
+			return err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	return j.delete()
+}
+
+// journalChecksum is the checksum of a page record: the nonce plus every 200th byte of the page
+// going backward from the end of the page.
+func journalChecksum(nonce uint32, data []byte) uint32 {
+	checksum := nonce
+	for i := len(data) - 200; i > 0; i -= 200 {
+		checksum += uint32(data[i])
+	}
+	return checksum
+}
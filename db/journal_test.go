@@ -0,0 +1,103 @@
+package db
+
+import (
+	"os"
+	"sort"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHotJournalRollback(t *testing.T) {
+	original, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	fs := vfs.NewMemVFS()
+	fs.AddFile("sample.db", append([]byte(nil), original...))
+	file, err := fs.Open("sample.db", vfs.OpenReadWrite)
+	require.NoError(t, err)
+	database, err := NewDB(file)
+	require.NoError(t, err)
+	tree, err := database.FindTablePage("oranges")
+	require.NoError(t, err)
+
+	// a committed transaction doesn't leave any journal behind
+	record, err := EncodeRecord([]any{nil, "Clementine", "orange"}, EncodingUTF8)
+	require.NoError(t, err)
+	require.NoError(t, database.Begin())
+	require.NoError(t, tree.Insert(100, record))
+	require.NoError(t, database.Commit())
+	exists, err := fs.Exists("sample.db-journal")
+	require.NoError(t, err)
+	require.False(t, exists)
+	committed := append([]byte(nil), file.(*vfs.MemFile).Bytes()...)
+
+	// the process crashes after the journal is synced, while the database pages are written
+	require.NoError(t, database.Begin())
+	for rowID := int64(1000); rowID < 1500; rowID++ {
+		require.NoError(t, tree.Insert(rowID, record))
+	}
+	require.NoError(t, tree.Delete(100))
+	pager := database.pager
+	pageNumbers := make([]uint32, 0, len(pager.dirty))
+	for pageNumber := range pager.dirty {
+		pageNumbers = append(pageNumbers, pageNumber)
+	}
+	sort.Slice(pageNumbers, func(i, j int) bool { return pageNumbers[i] < pageNumbers[j] })
+	require.NoError(t, pager.writeJournal(pageNumbers))
+	require.NoError(t, pager.writePages(pageNumbers[len(pageNumbers)/2:]))
+	require.NotEqual(t, committed, file.(*vfs.MemFile).Bytes())
+	require.NoError(t, file.Close())
+
+	// opening the database plays the hot journal back
+	file, err = fs.Open("sample.db", vfs.OpenReadWrite)
+	require.NoError(t, err)
+	_, err = NewDB(file)
+	require.NoError(t, err)
+	require.Equal(t, committed, file.(*vfs.MemFile).Bytes())
+	exists, err = fs.Exists("sample.db-journal")
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func TestBeginAfterOtherConnectionCommit(t *testing.T) {
+	original, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	fs := vfs.NewMemVFS()
+	fs.AddFile("sample.db", original)
+	open := func() (*DB, *TableBTree) {
+		file, err := fs.Open("sample.db", vfs.OpenReadWrite)
+		require.NoError(t, err)
+		database, err := NewDB(file)
+		require.NoError(t, err)
+		tree, err := database.FindTablePage("oranges")
+		require.NoError(t, err)
+		return database, tree
+	}
+	insert := func(database *DB, tree *TableBTree, from, to int64) {
+		record, err := EncodeRecord([]any{nil, "Clementine", "orange"}, EncodingUTF8)
+		require.NoError(t, err)
+		require.NoError(t, database.Begin())
+		for rowID := from; rowID < to; rowID++ {
+			require.NoError(t, tree.Insert(rowID, record))
+		}
+		require.NoError(t, database.Commit())
+	}
+
+	// the first connection caches the pages of oranges before the second one changes them
+	first, firstTree := open()
+	records, err := firstTree.GetRecords()
+	require.NoError(t, err)
+	require.Len(t, records, 6)
+	second, secondTree := open()
+	insert(second, secondTree, 1000, 1300)
+
+	insert(first, firstTree, 2000, 2100)
+	_, tree := open()
+	records, err = tree.GetRecords()
+	require.NoError(t, err)
+	require.Equal(t, 6+300+100, len(records))
+	messages, err := first.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// payloadLimits tells how much of a cell payload can be stored in a b-tree page,
+// anything bigger spills into a chain of overflow pages.
+// See https://www.sqlite.org/fileformat2.html#b_tree_pages
+type payloadLimits struct {
+	pageSize   int
+	usableSize int
+	maxLocal   int
+	minLocal   int
+	maxLeaf    int
+	minLeaf    int
+}
+
+func newPayloadLimits(header *DatabaseHeader) payloadLimits {
+	usableSize := header.UsableSize()
+	return payloadLimits{
+		pageSize:   int(header.PageSize),
+		usableSize: usableSize,
+		maxLocal:   (usableSize-12)*int(header.MaxEmbeddedPayload)/255 - 23,
+		minLocal:   (usableSize-12)*int(header.MinEmbeddedPayload)/255 - 23,
+		maxLeaf:    usableSize - 35,
+		minLeaf:    (usableSize-12)*int(header.LeafPayloadFraction)/255 - 23,
+	}
+}
+
+// localSize returns how many bytes of a payload are stored in a page of the given type.
+func (l payloadLimits) localSize(pageType BTreePageType, payloadSize int64) int {
+	maxLocal, minLocal := l.maxLocal, l.minLocal
+	if pageType == BTREE_LEAF_TABLE {
+		maxLocal, minLocal = l.maxLeaf, l.minLeaf
+	}
+	if payloadSize <= int64(maxLocal) {
+		return int(payloadSize)
+	}
+
+	local := minLocal + int((payloadSize-int64(minLocal))%int64(l.usableSize-4))
+	if local > maxLocal {
+		return minLocal
+	}
+	return local
+}
+
+// splitCellPayload takes the cell content starting at the payload and returns the local part of the payload
+// and the first overflow page, it's 0 when the whole payload fits in the page.
+func (l payloadLimits) splitCellPayload(pageType BTreePageType, cellData []byte, payloadSize int64) ([]byte, uint32, error) {
+	local := l.localSize(pageType, payloadSize)
+	if local > len(cellData) {
+		return nil, 0, fmt.Errorf("cell payload of %d bytes exceeds the page", local)
+	}
+	if int64(local) == payloadSize {
+		return cellData[:local], 0, nil
+	}
+
+	if local+4 > len(cellData) {
+		return nil, 0, fmt.Errorf("cell overflow page pointer exceeds the page")
+	}
+	return cellData[:local], binary.BigEndian.Uint32(cellData[local : local+4]), nil
+}
+
+// readOverflowPayload reassembles a payload from its local part followed by the content of the overflow pages.
+// Every overflow page starts with the next page number, 0 for the last page of the chain.
+func readOverflowPayload(pager *Pager, local []byte, firstPage uint32, payloadSize int64) ([]byte, error) {
+	payload := make([]byte, len(local), payloadSize)
+	copy(payload, local)
+
+	pageNumber := firstPage
+	for int64(len(payload)) < payloadSize {
+		if pageNumber == 0 {
+			return nil, fmt.Errorf("overflow chain ends %d bytes before the end of the payload", payloadSize-int64(len(payload)))
+		}
+		page, err := pager.Get(pageNumber)
+		if err != nil {
+			return nil, err
+		}
+
+		content := page.Data[4:pager.limits.usableSize]
+		if remaining := payloadSize - int64(len(payload)); int64(len(content)) > remaining {
+			content = content[:remaining]
+		}
+		payload = append(payload, content...)
+		pageNumber = binary.BigEndian.Uint32(page.Data[:4])
+		pager.Unpin(page)
+	}
+	return payload, nil
+}
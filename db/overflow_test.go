@@ -0,0 +1,68 @@
+package db
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadLimits(t *testing.T) {
+	limits := newPayloadLimits(&DatabaseHeader{PageSize: 512, MaxEmbeddedPayload: 64, MinEmbeddedPayload: 32, LeafPayloadFraction: 32})
+	require.Equal(t, 477, limits.maxLeaf)
+	require.Equal(t, 39, limits.minLeaf)
+	require.Equal(t, 102, limits.maxLocal)
+	require.Equal(t, 39, limits.minLocal)
+
+	tests := []struct {
+		pageType    BTreePageType
+		payloadSize int64
+		want        int
+	}{
+		{BTREE_LEAF_TABLE, 477, 477},
+		// what doesn't fit in the overflow pages is kept in the page when it's small enough
+		{BTREE_LEAF_TABLE, 600, 39 + (600-39)%508},
+		// otherwise only the minimum is kept
+		{BTREE_LEAF_TABLE, 1000, 39},
+		{BTREE_LEAF_INDEX, 102, 102},
+		{BTREE_LEAF_INDEX, 103, 39},
+		{BTREE_LEAF_INDEX, 600, 39 + (600-39)%508},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.want, limits.localSize(test.pageType, test.payloadSize), "%d bytes in a page of type %d", test.payloadSize, test.pageType)
+	}
+
+	local, overflow, err := limits.splitCellPayload(BTREE_LEAF_TABLE, []byte{1, 2, 3}, 3)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, local)
+	require.Zero(t, overflow)
+	_, _, err = limits.splitCellPayload(BTREE_LEAF_TABLE, make([]byte, 40), 1000)
+	require.Error(t, err, "the overflow page number is missing")
+}
+
+func TestReadOverflowPayload(t *testing.T) {
+	header := &DatabaseHeader{PageSize: 512, MaxEmbeddedPayload: 64, MinEmbeddedPayload: 32, LeafPayloadFraction: 32}
+
+	// the payload keeps 39 bytes in the cell and is spread over the pages 2, 4 then 3
+	payload := make([]byte, 1200)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+	local, rest := payload[:39], payload[39:]
+	data := make([]byte, 4*512)
+	for _, page := range []struct{ number, next uint32 }{{2, 4}, {4, 3}, {3, 0}} {
+		pageData := data[(page.number-1)*512 : page.number*512]
+		binary.BigEndian.PutUint32(pageData, page.next)
+		rest = rest[copy(pageData[4:], rest):]
+	}
+	pager := NewPager(vfs.NewMemFile(data), header, PagerOptions{})
+
+	got, err := readOverflowPayload(pager, local, 2, int64(len(payload)))
+	require.NoError(t, err)
+	require.Equal(t, payload, got)
+
+	// the chain ends before the whole payload has been read
+	_, err = readOverflowPayload(pager, local, 4, int64(len(payload)))
+	require.EqualError(t, err, "overflow chain ends 145 bytes before the end of the payload")
+}
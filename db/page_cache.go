@@ -0,0 +1,110 @@
+package db
+
+import "container/list"
+
+// DefaultCacheBytes is the default size of the page cache, the same 2000 KiB sqlite uses.
+const DefaultCacheBytes = 2000 * 1024
+
+// Page is a database page held by the page cache.
+// Data is shared with every user of the page, it must not be modified.
+type Page struct {
+	Number uint32
+	Data   []byte
+	pins   int
+}
+
+// CacheStats counts how the page cache has been used.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// PageCache is a bounded LRU cache of pages. A pinned page is never evicted,
+// the cache may grow beyond its capacity when every page is pinned.
+type PageCache struct {
+	capacity int
+	pages    map[uint32]*list.Element
+	lru      *list.List
+	stats    CacheStats
+}
+
+// NewPageCache creates a cache holding up to capacity pages, at least one page is cached.
+func NewPageCache(capacity int) *PageCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &PageCache{
+		capacity: capacity,
+		pages:    map[uint32]*list.Element{},
+		lru:      list.New(),
+	}
+}
+
+// Get returns the cached page and pins it, it returns nil when the page isn't cached.
+func (c *PageCache) Get(pageNumber uint32) *Page {
+	elem, ok := c.pages[pageNumber]
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+	c.stats.Hits++
+	c.lru.MoveToFront(elem)
+
+	page := elem.Value.(*Page)
+	page.pins++
+	return page
+}
+
+// Put adds a pinned page to the cache and evicts the least recently used unpinned pages above the capacity.
+func (c *PageCache) Put(page *Page) {
+	if elem, ok := c.pages[page.Number]; ok {
+		c.lru.Remove(elem)
+	}
+	page.pins++
+	c.pages[page.Number] = c.lru.PushFront(page)
+	c.evict()
+}
+
+// Unpin releases the page so it can be evicted again.
+func (c *PageCache) Unpin(page *Page) {
+	if page.pins > 0 {
+		page.pins--
+	}
+}
+
+// Remove drops the page from the cache whether it's pinned or not.
+func (c *PageCache) Remove(pageNumber uint32) {
+	if elem, ok := c.pages[pageNumber]; ok {
+		c.lru.Remove(elem)
+		delete(c.pages, pageNumber)
+	}
+}
+
+// Clear drops every page from the cache.
+func (c *PageCache) Clear() {
+	c.pages = map[uint32]*list.Element{}
+	c.lru.Init()
+}
+
+// Len returns the number of cached pages.
+func (c *PageCache) Len() int {
+	return c.lru.Len()
+}
+
+func (c *PageCache) Stats() CacheStats {
+	return c.stats
+}
+
+func (c *PageCache) evict() {
+	for elem := c.lru.Back(); elem != nil && c.lru.Len() > c.capacity; {
+		prev := elem.Prev()
+		page := elem.Value.(*Page)
+		if page.pins == 0 {
+			c.lru.Remove(elem)
+			delete(c.pages, page.Number)
+			c.stats.Evictions++
+		}
+		elem = prev
+	}
+}
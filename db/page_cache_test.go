@@ -0,0 +1,63 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageCache(t *testing.T) {
+	cache := NewPageCache(3)
+	cached := func() []uint32 {
+		var numbers []uint32
+		for elem := cache.lru.Front(); elem != nil; elem = elem.Next() {
+			numbers = append(numbers, elem.Value.(*Page).Number)
+		}
+		return numbers
+	}
+
+	pages := map[uint32]*Page{}
+	for number := uint32(1); number <= 3; number++ {
+		pages[number] = &Page{Number: number}
+		cache.Put(pages[number])
+		cache.Unpin(pages[number])
+	}
+	require.Equal(t, []uint32{3, 2, 1}, cached())
+
+	// reading the page 1 makes the page 2 the least recently used one
+	require.Same(t, pages[1], cache.Get(1))
+	cache.Unpin(pages[1])
+	require.Nil(t, cache.Get(4))
+	cache.Put(&Page{Number: 4})
+	require.Equal(t, []uint32{4, 1, 3}, cached())
+	require.Equal(t, CacheStats{Hits: 1, Misses: 1, Evictions: 1}, cache.Stats())
+
+	// the page 4 is still pinned, the unpinned pages are evicted before it
+	cache.Put(&Page{Number: 5})
+	cache.Put(&Page{Number: 6})
+	require.Equal(t, []uint32{6, 5, 4}, cached())
+	require.Equal(t, uint64(3), cache.Stats().Evictions)
+
+	// every page is pinned, the cache grows beyond its capacity
+	cache.Put(&Page{Number: 7})
+	require.Equal(t, []uint32{7, 6, 5, 4}, cached())
+	require.Equal(t, 4, cache.Len())
+
+	// a page pinned twice stays pinned until it's unpinned twice
+	page := cache.Get(4)
+	require.Equal(t, 2, page.pins)
+	cache.Unpin(page)
+	cache.Put(&Page{Number: 8})
+	require.Contains(t, cached(), uint32(4))
+	cache.Unpin(page)
+	cache.Unpin(page)
+	require.Zero(t, page.pins, "unpinning an unpinned page doesn't go below zero")
+	cache.Put(&Page{Number: 9})
+	require.NotContains(t, cached(), uint32(4))
+
+	require.Equal(t, CacheStats{Hits: 2, Misses: 1, Evictions: 4}, cache.Stats())
+	cache.Remove(9)
+	require.Nil(t, cache.Get(9))
+	require.Equal(t, 4, cache.Len())
+	require.Equal(t, uint64(2), cache.Stats().Misses)
+}
@@ -1,43 +1,145 @@
 package db
 
 import (
-	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"github.com/adzimzf/sqlite-go/constant"
-	"os"
+	"github.com/adzimzf/sqlite-go/vfs"
+	"io"
 )
 
 // ReadDatabaseHeader reads and parses the SQLite database header
-func ReadDatabaseHeader(file *os.File) (*DatabaseHeader, error) {
-	var header DatabaseHeader
-	_, err := file.ReadAt(header.HeaderString[:], 0)
+func ReadDatabaseHeader(file vfs.File) (*DatabaseHeader, error) {
+	raw := make([]byte, constant.HeaderSize)
+	if err := readFullAt(file, raw, 0); err != nil {
+		return nil, err
+	}
+	header, err := decodeDatabaseHeader(raw)
 	if err != nil {
 		return nil, err
 	}
 
+	// the database size is only trusted when the file has been last written by a sqlite version
+	// which maintains it, otherwise it's computed from the file size
+	if header.DatabaseSize == 0 || header.FileChangeCounter != header.VersionValidFor {
+		fileSize, err := file.Size()
+		if err != nil {
+			return nil, err
+		}
+		header.DatabaseSize = uint32(fileSize / int64(header.PageSize))
+	}
+	return header, nil
+}
+
+// decodeDatabaseHeader parses the first 100 bytes of page 1.
+func decodeDatabaseHeader(raw []byte) (*DatabaseHeader, error) {
+	var header DatabaseHeader
+	copy(header.HeaderString[:], raw)
+
 	// Verify the SQLite magic string
 	if string(header.HeaderString[:])[:16] != constant.SQLiteMagic {
 		return nil, fmt.Errorf("invalid SQLite database file")
 	}
 
-	// the header page is 2 bytes after the SQLiteMagicString
-	if err := binary.Read(bytes.NewReader(header.HeaderString[16:18]), binary.BigEndian, &header.PageSize); err != nil {
-		return nil, err
+	raw = header.HeaderString[:]
+	header.PageSize = uint32(binary.BigEndian.Uint16(raw[16:18]))
+	if header.PageSize == 1 {
+		header.PageSize = 65536
 	}
+	header.FileFormatWrite = raw[18]
+	header.FileFormatRead = raw[19]
 
-	// Read other header fields
-	//err = binary.Read(file, binary.BigEndian, &header.PageSize)
-	//if err != nil {
-	//	return nil, err
-	//}
+	// the reserved space at the end of every page and the payload fractions are used
+	// to know how much of a cell can be stored in a page
+	header.Reserved1 = raw[20]
+	header.MaxEmbeddedPayload = raw[21]
+	header.MinEmbeddedPayload = raw[22]
+	header.LeafPayloadFraction = raw[23]
 
-	// Continue reading the remaining header fields as needed
-	// For simplicity, we skip parsing all fields in this example
+	header.FileChangeCounter = binary.BigEndian.Uint32(raw[24:28])
+	header.DatabaseSize = binary.BigEndian.Uint32(raw[28:32])
+	header.FreelistTrunkPage = binary.BigEndian.Uint32(raw[32:36])
+	header.FreelistCount = binary.BigEndian.Uint32(raw[36:40])
+	header.SchemaCookie = binary.BigEndian.Uint32(raw[40:44])
+	header.SchemaFormat = binary.BigEndian.Uint32(raw[44:48])
+	header.DefaultPageCache = binary.BigEndian.Uint32(raw[48:52])
+	header.LargestRootPage = binary.BigEndian.Uint32(raw[52:56])
+	header.TextEncoding = TextEncoding(binary.BigEndian.Uint32(raw[56:60]))
+	header.UserVersion = binary.BigEndian.Uint32(raw[60:64])
+	header.IncrementalVacuum = binary.BigEndian.Uint32(raw[64:68])
+	header.ApplicationID = binary.BigEndian.Uint32(raw[68:72])
+	header.VersionValidFor = binary.BigEndian.Uint32(raw[92:96])
+	header.VersionUsed = binary.BigEndian.Uint32(raw[96:100])
 
+	if err := header.validate(); err != nil {
+		return nil, err
+	}
 	return &header, nil
 }
 
+// encode writes the header fields into the first 100 bytes of page 1.
+func (h *DatabaseHeader) encode(raw []byte) {
+	copy(raw[:constant.HeaderSize], h.HeaderString[:])
+	copy(raw, constant.SQLiteMagic)
+
+	pageSize := uint16(h.PageSize)
+	if h.PageSize == 65536 {
+		pageSize = 1
+	}
+	binary.BigEndian.PutUint16(raw[16:18], pageSize)
+	raw[18] = h.FileFormatWrite
+	raw[19] = h.FileFormatRead
+	raw[20] = h.Reserved1
+	raw[21] = h.MaxEmbeddedPayload
+	raw[22] = h.MinEmbeddedPayload
+	raw[23] = h.LeafPayloadFraction
+
+	binary.BigEndian.PutUint32(raw[24:28], h.FileChangeCounter)
+	binary.BigEndian.PutUint32(raw[28:32], h.DatabaseSize)
+	binary.BigEndian.PutUint32(raw[32:36], h.FreelistTrunkPage)
+	binary.BigEndian.PutUint32(raw[36:40], h.FreelistCount)
+	binary.BigEndian.PutUint32(raw[40:44], h.SchemaCookie)
+	binary.BigEndian.PutUint32(raw[44:48], h.SchemaFormat)
+	binary.BigEndian.PutUint32(raw[48:52], h.DefaultPageCache)
+	binary.BigEndian.PutUint32(raw[52:56], h.LargestRootPage)
+	binary.BigEndian.PutUint32(raw[56:60], uint32(h.TextEncoding))
+	binary.BigEndian.PutUint32(raw[60:64], h.UserVersion)
+	binary.BigEndian.PutUint32(raw[64:68], h.IncrementalVacuum)
+	binary.BigEndian.PutUint32(raw[68:72], h.ApplicationID)
+	binary.BigEndian.PutUint32(raw[92:96], h.VersionValidFor)
+	binary.BigEndian.PutUint32(raw[96:100], h.VersionUsed)
+	copy(h.HeaderString[:], raw[:constant.HeaderSize])
+}
+
+// validate checks the header fields have the values sqlite requires.
+func (h *DatabaseHeader) validate() error {
+	if h.PageSize < 512 || h.PageSize > 65536 || h.PageSize&(h.PageSize-1) != 0 {
+		return fmt.Errorf("invalid page size: %d", h.PageSize)
+	}
+	if h.FileFormatRead < 1 || h.FileFormatRead > 2 {
+		return fmt.Errorf("unsupported file format read version: %d", h.FileFormatRead)
+	}
+	if h.FileFormatWrite < 1 || h.FileFormatWrite > 2 {
+		return fmt.Errorf("unsupported file format write version: %d", h.FileFormatWrite)
+	}
+	if h.UsableSize() < 480 {
+		return fmt.Errorf("too many reserved bytes per page: %d", h.Reserved1)
+	}
+	if h.MaxEmbeddedPayload != 64 || h.MinEmbeddedPayload != 32 || h.LeafPayloadFraction != 32 {
+		return fmt.Errorf("invalid payload fractions: %d/%d/%d",
+			h.MaxEmbeddedPayload, h.MinEmbeddedPayload, h.LeafPayloadFraction)
+	}
+	// an empty database may not have a schema format nor a text encoding yet
+	if h.SchemaFormat > 4 {
+		return fmt.Errorf("unsupported schema format: %d", h.SchemaFormat)
+	}
+	if h.TextEncoding > EncodingUTF16be {
+		return fmt.Errorf("invalid text encoding: %d", h.TextEncoding)
+	}
+	return nil
+}
+
 // BTreePageType represents the type of a B-tree page
 type BTreePageType byte
 
@@ -50,86 +152,216 @@ const (
 
 // BTreePage represents a generic B-tree page
 type BTreePage struct {
-	PageType byte
-	// Other fields can be added as needed
-
-}
-
-type TableLeafPage struct {
 	Header       TableHeader
 	CellPointers []uint16
-	Cells        []TableLeafCell
+	Data         []byte
 }
 
-func NewTableLeafPage(file *os.File, pageSize, pageNumber int) (*TableLeafPage, error) {
-	pageData, err := ReadBTreePage(file, pageSize, uint32(pageNumber))
+func parseBTreePage(pageData []byte, pageNumber int) (*BTreePage, error) {
+	header, err := parseTableHeader(pageData, pageNumber)
 	if err != nil {
 		return nil, err
 	}
+	return &BTreePage{
+		Header:       header,
+		CellPointers: readCellPointers(pageData, header),
+		Data:         pageData,
+	}, nil
+}
 
-	pageLeaf := &TableLeafPage{
-		Header: TableHeader{
-			PageType: BTREE_LEAF_TABLE,
-		},
+// IsLeaf reports whether the page is a table or an index leaf page.
+func (p *BTreePage) IsLeaf() bool {
+	return p.Header.PageType == BTREE_LEAF_TABLE || p.Header.PageType == BTREE_LEAF_INDEX
+}
+
+// IsTable reports whether the page belongs to a table b-tree.
+func (p *BTreePage) IsTable() bool {
+	return p.Header.PageType == BTREE_LEAF_TABLE || p.Header.PageType == BTREE_INTERNAL_TABLE
+}
+
+// Child returns the page on the left of the cell i, i equal to the number of cells is the right-most page.
+func (p *BTreePage) Child(i int) (uint32, error) {
+	if i == len(p.CellPointers) {
+		return p.Header.RightMostPointer, nil
 	}
-	nCellBytes := pageData[3:5]
-	filePageSize := 100
-	if pageNumber == 1 {
-		nCellBytes = pageData[filePageSize+3 : filePageSize+5]
+	cell, err := p.Cell(i, payloadLimits{})
+	if err != nil {
+		return 0, err
 	}
+	return cell.LeftChild, nil
+}
 
-	pageLeaf.Header.CellCount = binary.BigEndian.Uint16(nCellBytes)
-	pageLeaf.Header.PageNumber = uint16(pageNumber)
-	pageLeaf.CellPointers = make([]uint16, pageLeaf.Header.CellCount)
+// BTreeCell is a decoded cell of any b-tree page type,
+// the fields which don't exist for the page type are left zero.
+type BTreeCell struct {
+	LeftChild    uint32
+	RowID        int64
+	Size         int64
+	Payload      []byte
+	OverflowPage uint32
+}
 
-	// Byte 4-7: Start of cell content area
-	//cellContentStart := binary.BigEndian.Uint32(pageData[4:8])
-	ptrOffsite := uint16(0)
-	if pageNumber == 1 {
-		//ptrOffsite = 100
+// Cell decodes the cell i of the page, the payload only holds the local part of it.
+func (p *BTreePage) Cell(i int, limits payloadLimits) (BTreeCell, error) {
+	ptr := int(p.CellPointers[i])
+	if ptr >= len(p.Data) {
+		return BTreeCell{}, fmt.Errorf("page %d has an invalid cell pointer: %d", p.Header.PageNumber, ptr)
 	}
-	// Cell pointers start at byte 8
-	for i := 0; i < int(pageLeaf.Header.CellCount); i++ {
-		offset := 8 + i*2
-		if pageNumber == 1 {
-			offset += 100
-		}
+	cell, _, err := decodeCell(p.Header.PageType, p.Data[ptr:], limits)
+	if err != nil {
+		return BTreeCell{}, fmt.Errorf("page %d: %w", p.Header.PageNumber, err)
+	}
+	return cell, nil
+}
 
-		bytePtr := pageData[offset : offset+2]
-		ptr := binary.BigEndian.Uint16(bytePtr)
-		pageLeaf.CellPointers[i] = ptr - ptrOffsite
+// decodeCell decodes the cell at the start of cellData and returns how many bytes it takes in the page.
+func decodeCell(pageType BTreePageType, cellData []byte, limits payloadLimits) (BTreeCell, int, error) {
+	var cell BTreeCell
+	offset := 0
+	if pageType == BTREE_INTERNAL_TABLE || pageType == BTREE_INTERNAL_PAGE {
+		if len(cellData) < 4 {
+			return BTreeCell{}, 0, fmt.Errorf("cell left child pointer exceeds the page")
+		}
+		cell.LeftChild = binary.BigEndian.Uint32(cellData[:4])
+		offset = 4
 	}
 
-	for _, ptr := range pageLeaf.CellPointers {
-		if int(ptr) >= len(pageData) {
-			continue // Invalid pointer
+	// the table interior cells only hold the rowid
+	if pageType == BTREE_INTERNAL_TABLE {
+		rowID, n, err := GetVarint(cellData[offset:])
+		if err != nil {
+			return BTreeCell{}, 0, err
 		}
-		// read fist bytes to identify the payload size
+		cell.RowID = rowID
+		return cell, offset + n, nil
+	}
 
-		cellData := pageData[ptr:]
+	size, n, err := GetVarint(cellData[offset:])
+	if err != nil {
+		return BTreeCell{}, 0, err
+	}
+	offset += n
+	cell.Size = size
 
-		n, size, err := ReadVarintAt(cellData, 0)
+	if pageType == BTREE_LEAF_TABLE {
+		rowID, n, err := GetVarint(cellData[offset:])
 		if err != nil {
-			return nil, err
+			return BTreeCell{}, 0, err
 		}
-		cellData = cellData[n:]
-		n, rowID, err := ReadVarintAt(cellData, 0)
+		offset += n
+		cell.RowID = rowID
+	}
+
+	cell.Payload, cell.OverflowPage, err = limits.splitCellPayload(pageType, cellData[offset:], size)
+	if err != nil {
+		return BTreeCell{}, 0, err
+	}
+	offset += len(cell.Payload)
+	if cell.OverflowPage != 0 {
+		offset += 4
+	}
+	return cell, offset, nil
+}
+
+type TableLeafPage struct {
+	Header       TableHeader
+	CellPointers []uint16
+	Cells        []TableLeafCell
+	encoding     TextEncoding
+}
+
+func NewTableLeafPage(pager *Pager, pageNumber int) (*TableLeafPage, error) {
+	page, err := pager.Get(uint32(pageNumber))
+	if err != nil {
+		return nil, err
+	}
+	defer pager.Unpin(page)
+	leaf, err := parseTableLeafPage(page.Data, pageNumber, pager.limits)
+	if err != nil {
+		return nil, err
+	}
+	leaf.encoding = pager.encoding
+	return leaf, nil
+}
+
+func parseTableLeafPage(pageData []byte, pageNumber int, limits payloadLimits) (*TableLeafPage, error) {
+	page, err := parseBTreePage(pageData, pageNumber)
+	if err != nil {
+		return nil, err
+	}
+	if page.Header.PageType != BTREE_LEAF_TABLE {
+		return nil, fmt.Errorf("page %d isn't a table leaf page: type %d", pageNumber, page.Header.PageType)
+	}
+
+	pageLeaf := &TableLeafPage{
+		Header:       page.Header,
+		CellPointers: page.CellPointers,
+	}
+	for i := range page.CellPointers {
+		cell, err := page.Cell(i, limits)
 		if err != nil {
 			return nil, err
 		}
-		cellData = cellData[n : size+1]
-		//log.Println(strconv.FormatInt(rowID, 10))
-
 		pageLeaf.Cells = append(pageLeaf.Cells, TableLeafCell{
-			Size:    size,
-			RowID:   rowID,
-			Payload: cellData,
+			Size:         cell.Size,
+			RowID:        cell.RowID,
+			Payload:      cell.Payload,
+			OverflowPage: cell.OverflowPage,
 		})
 	}
 
 	return pageLeaf, nil
 }
 
+// parseTableHeader reads the b-tree page header, it's located after the database header on page 1.
+func parseTableHeader(pageData []byte, pageNumber int) (TableHeader, error) {
+	offset := btreeHeaderOffset(pageNumber)
+	if len(pageData) < offset+8 {
+		return TableHeader{}, fmt.Errorf("page %d is too short for a b-tree header", pageNumber)
+	}
+
+	header := TableHeader{
+		PageType:   BTreePageType(pageData[offset]),
+		CellCount:  binary.BigEndian.Uint16(pageData[offset+3 : offset+5]),
+		PageNumber: uint32(pageNumber),
+		Offset:     offset,
+	}
+	switch header.PageType {
+	case BTREE_LEAF_TABLE, BTREE_LEAF_INDEX:
+		header.Size = 8
+	case BTREE_INTERNAL_TABLE, BTREE_INTERNAL_PAGE:
+		header.Size = 12
+		header.RightMostPointer = binary.BigEndian.Uint32(pageData[offset+8 : offset+12])
+	default:
+		return TableHeader{}, fmt.Errorf("page %d has an invalid b-tree page type: %d", pageNumber, header.PageType)
+	}
+
+	if offset+header.Size+int(header.CellCount)*2 > len(pageData) {
+		return TableHeader{}, fmt.Errorf("page %d has too many cells: %d", pageNumber, header.CellCount)
+	}
+	return header, nil
+}
+
+// readCellPointers returns the cell pointer array which starts right after the page header.
+// The pointers are offsets from the start of the page, including for page 1.
+func readCellPointers(pageData []byte, header TableHeader) []uint16 {
+	pointers := make([]uint16, header.CellCount)
+	for i := 0; i < int(header.CellCount); i++ {
+		offset := header.Offset + header.Size + i*2
+		pointers[i] = binary.BigEndian.Uint16(pageData[offset : offset+2])
+	}
+	return pointers
+}
+
+// btreeHeaderOffset returns where the b-tree page header starts,
+// page 1 begins with the 100 bytes database header.
+func btreeHeaderOffset(pageNumber int) int {
+	if pageNumber == 1 {
+		return constant.HeaderSize
+	}
+	return 0
+}
+
 func (t *TableLeafPage) GetRecords() ([]Record, error) {
 	records := make([]Record, len(t.Cells))
 	for i, cell := range t.Cells {
@@ -138,9 +370,10 @@ func (t *TableLeafPage) GetRecords() ([]Record, error) {
 			return nil, err
 		}
 		records[i] = Record{
-			RowID:   cell.RowID,
-			Header:  recordHeader,
-			Payload: cell.Payload,
+			RowID:    cell.RowID,
+			Header:   recordHeader,
+			Payload:  cell.Payload,
+			Encoding: t.encoding,
 		}
 	}
 	return records, nil
@@ -154,45 +387,158 @@ func (t *TableLeafPage) GetRecordsFields(fields []int64) ([]Record, error) {
 		return nil, err
 	}
 
-	// an empty filter will return all
-	if len(fields) == 0 {
-		return records, nil
-	}
-
-	newRecords := make([]Record, len(records))
 	for ri, record := range records {
-		var newFields []RecordField
-		for i := 0; i < len(fields); i++ {
-			newFields = append(newFields, record.Header.Fields[fields[i]])
-		}
-		record.Header.Fields = newFields
-		newRecords[ri] = record
+		records[ri] = record.SelectFields(fields)
 	}
-	return newRecords, nil
+	return records, nil
 }
 
 type TableHeader struct {
 	PageType   BTreePageType
 	CellCount  uint16
-	PageNumber uint16
+	PageNumber uint32
+	// RightMostPointer is only set for the interior pages
+	RightMostPointer uint32
+	// Offset is where the header starts in the page, and Size is its length (8 or 12 bytes)
+	Offset int
+	Size   int
 }
 
+// TableLeafCell holds a record, when the record doesn't fit in the page
+// Payload only holds the local part of it and the rest is stored from the OverflowPage.
 type TableLeafCell struct {
-	Size    int64
-	RowID   int64
-	Payload []byte
+	Size         int64
+	RowID        int64
+	Payload      []byte
+	OverflowPage uint32
+}
+
+// Pager reads the database pages through a bounded page cache,
+// every b-tree read goes through it.
+type Pager struct {
+	file     vfs.File
+	header   *DatabaseHeader
+	limits   payloadLimits
+	encoding TextEncoding
+	cache    *PageCache
+
+	// the pages written by the current write transaction, they reach the file on commit
+	dirty         map[uint32]*Page
+	inTransaction bool
+	savedHeader   DatabaseHeader
+	// readers is the number of nested read transactions
+	readers int
+	// journal is nil when the file doesn't know its name, the commits aren't atomic then
+	journal *journal
+	// wal is the log of a database in WAL mode, nil when there's none
+	wal *wal
+}
+
+// PagerOptions configures the size of the page cache.
+type PagerOptions struct {
+	// CachePages is the maximum number of cached pages,
+	// CacheBytes is used instead when it's 0 and DefaultCacheBytes when both are 0.
+	CachePages int
+	CacheBytes int
+}
+
+func NewPager(file vfs.File, header *DatabaseHeader, options PagerOptions) *Pager {
+	capacity := options.CachePages
+	if capacity == 0 {
+		cacheBytes := options.CacheBytes
+		if cacheBytes == 0 {
+			cacheBytes = DefaultCacheBytes
+		}
+		capacity = cacheBytes / int(header.PageSize)
+	}
+	return &Pager{
+		file:     file,
+		header:   header,
+		limits:   newPayloadLimits(header),
+		encoding: header.TextEncoding,
+		cache:    NewPageCache(capacity),
+	}
+}
+
+// Get returns the page from the cache or reads it on a miss, from the log when it has
+// a committed version of the page and from the database file otherwise.
+// The page is pinned until Unpin is called.
+func (p *Pager) Get(pageNumber uint32) (*Page, error) {
+	if pageNumber < 1 {
+		return nil, fmt.Errorf("invalid page number: %d", pageNumber)
+	}
+	if page, ok := p.dirty[pageNumber]; ok {
+		page.pins++
+		return page, nil
+	}
+	if page := p.cache.Get(pageNumber); page != nil {
+		return page, nil
+	}
+
+	pageData, err := p.readPage(pageNumber)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Number: pageNumber, Data: pageData}
+	p.cache.Put(page)
+	return page, nil
+}
+
+// readPage reads the last committed version of the page.
+func (p *Pager) readPage(pageNumber uint32) ([]byte, error) {
+	if p.wal != nil {
+		data, ok, err := p.wal.readPage(pageNumber)
+		if err != nil || ok {
+			return data, err
+		}
+	}
+	return ReadBTreePage(p.file, p.limits.pageSize, pageNumber)
+}
+
+// Unpin releases a page returned by Get.
+func (p *Pager) Unpin(page *Page) {
+	p.cache.Unpin(page)
+}
+
+func (p *Pager) PageSize() int {
+	return p.limits.pageSize
+}
+
+func (p *Pager) CacheStats() CacheStats {
+	return p.cache.Stats()
+}
+
+// readBTreePage reads and parses a b-tree page, the page isn't pinned.
+func (p *Pager) readBTreePage(pageNumber uint32) (*BTreePage, error) {
+	page, err := p.Get(pageNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Unpin(page)
+	return parseBTreePage(page.Data, int(pageNumber))
 }
 
 // ReadBTreePage reads a specific page from the database file
-func ReadBTreePage(file *os.File, pageSize int, pageNumber uint32) ([]byte, error) {
+func ReadBTreePage(file vfs.File, pageSize int, pageNumber uint32) ([]byte, error) {
 	offset := int64((int(pageNumber) - 1) * pageSize)
 	if pageNumber == 1 {
 		offset = 0
 	}
 	buf := make([]byte, pageSize)
-	_, err := file.ReadAt(buf, offset)
-	if err != nil {
+	if err := readFullAt(file, buf, offset); err != nil {
 		return nil, err
 	}
 	return buf, nil
 }
+
+// readFullAt reads exactly len(buf) bytes, io.EOF is only an error when the buffer isn't filled.
+func readFullAt(file vfs.File, buf []byte, offset int64) error {
+	n, err := file.ReadAt(buf, offset)
+	if n == len(buf) {
+		return nil
+	}
+	if err == nil || errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
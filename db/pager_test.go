@@ -0,0 +1,51 @@
+package db
+
+import (
+	"os"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadDatabaseHeader(t *testing.T) {
+	data, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	header, err := ReadDatabaseHeader(vfs.NewMemFile(data[:100]))
+	require.NoError(t, err)
+	require.Equal(t, uint32(4096), header.PageSize)
+	require.Equal(t, EncodingUTF8, header.TextEncoding)
+
+	tests := []struct {
+		name   string
+		modify func(raw []byte)
+		err    string
+	}{
+		{"bad magic", func(raw []byte) { copy(raw, "SQLite format 2\x00") }, "invalid SQLite database file"},
+		{"page size not a power of two", func(raw []byte) { raw[16], raw[17] = 0x03, 0x00 }, "invalid page size: 768"},
+		{"page size too small", func(raw []byte) { raw[16], raw[17] = 0x01, 0x00 }, "invalid page size: 256"},
+		{"read format", func(raw []byte) { raw[19] = 3 }, "unsupported file format read version: 3"},
+		{"write format", func(raw []byte) { raw[18] = 0 }, "unsupported file format write version: 0"},
+		{"reserved bytes", func(raw []byte) { raw[16], raw[17], raw[20] = 0x02, 0x00, 33 }, "too many reserved bytes per page: 33"},
+		{"max payload fraction", func(raw []byte) { raw[21] = 63 }, "invalid payload fractions: 63/32/32"},
+		{"min payload fraction", func(raw []byte) { raw[22] = 16 }, "invalid payload fractions: 64/16/32"},
+		{"leaf payload fraction", func(raw []byte) { raw[23] = 0 }, "invalid payload fractions: 64/32/0"},
+		{"schema format", func(raw []byte) { raw[47] = 5 }, "unsupported schema format: 5"},
+		{"text encoding", func(raw []byte) { raw[59] = 4 }, "invalid text encoding: 4"},
+	}
+	for _, test := range tests {
+		raw := make([]byte, 100)
+		copy(raw, data)
+		test.modify(raw)
+		_, err := ReadDatabaseHeader(vfs.NewMemFile(raw))
+		require.EqualError(t, err, test.err, test.name)
+	}
+
+	// the page size 65536 is stored as 1
+	raw := make([]byte, 100)
+	copy(raw, data)
+	raw[16], raw[17] = 0x00, 0x01
+	header, err = ReadDatabaseHeader(vfs.NewMemFile(raw))
+	require.NoError(t, err)
+	require.Equal(t, uint32(65536), header.PageSize)
+}
@@ -0,0 +1,447 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/adzimzf/sqlite-go/constant"
+	"github.com/adzimzf/sqlite-go/vfs"
+)
+
+// Begin starts a write transaction, the pages written until Commit are only kept in memory.
+// Only one connection can hold the RESERVED lock so only one can write at a time.
+// A write transaction started within a read transaction keeps its snapshot, in WAL mode it fails
+// when another connection committed since the snapshot has been taken.
+func (p *Pager) Begin() error {
+	if p.inTransaction {
+		return fmt.Errorf("cannot start a transaction within a transaction")
+	}
+	if err := p.file.Lock(vfs.LockShared); err != nil {
+		return err
+	}
+	if err := p.file.Lock(vfs.LockReserved); err != nil {
+		p.unlock()
+		return err
+	}
+	if p.readers == 0 {
+		if err := p.beginSnapshot(); err != nil {
+			p.unlock()
+			return err
+		}
+	} else if p.wal != nil {
+		stale, err := p.wal.stale()
+		if err == nil && stale {
+			err = fmt.Errorf("%w: another connection committed since the read transaction started", vfs.ErrBusy)
+		}
+		if err != nil {
+			p.unlock()
+			return err
+		}
+	}
+	p.inTransaction = true
+	p.dirty = map[uint32]*Page{}
+	p.savedHeader = *p.header
+	return nil
+}
+
+// BeginRead starts a read transaction, the pages read until EndRead are the ones of the last commit
+// when it started. In rollback journal mode the SHARED lock keeps the other connections from
+// committing meanwhile, in WAL mode they can but the read mark keeps the frames of the snapshot
+// in the log. Read transactions nest, one started within a write transaction reads its pages.
+func (p *Pager) BeginRead() error {
+	if p.readers > 0 || p.inTransaction {
+		p.readers++
+		return nil
+	}
+	if err := p.file.Lock(vfs.LockShared); err != nil {
+		return err
+	}
+	if err := p.beginSnapshot(); err != nil {
+		p.file.Unlock(vfs.LockNone)
+		return err
+	}
+	p.readers++
+	return nil
+}
+
+// EndRead ends the read transaction started by BeginRead.
+func (p *Pager) EndRead() {
+	if p.readers == 0 {
+		return
+	}
+	if p.readers--; p.readers > 0 || p.inTransaction {
+		return
+	}
+	p.endSnapshot()
+	p.file.Unlock(vfs.LockNone)
+}
+
+// beginSnapshot drops the cached pages and takes the header read again when another connection
+// changed the database since the last transaction: in WAL mode the index of the log is read again
+// and the read mark of the snapshot is set, otherwise the file change counter of the header tells it.
+func (p *Pager) beginSnapshot() error {
+	if p.wal == nil {
+		return p.refreshHeader()
+	}
+	changed, err := p.wal.beginRead()
+	if err != nil || !changed {
+		return err
+	}
+	header, err := p.wal.databaseHeader(p.file)
+	if err != nil {
+		p.wal.endRead()
+		return err
+	}
+	*p.header = *header
+	p.cache.Clear()
+	return nil
+}
+
+func (p *Pager) endSnapshot() {
+	if p.wal != nil {
+		p.wal.endRead()
+	}
+}
+
+// unlock releases the locks of the write transaction, the SHARED lock of a read transaction is kept.
+func (p *Pager) unlock() {
+	if p.readers > 0 {
+		p.file.Unlock(vfs.LockShared)
+		return
+	}
+	p.file.Unlock(vfs.LockNone)
+}
+
+// refreshHeader drops the cached pages and takes the header read again when the file change
+// counter shows another connection committed since the header has been read.
+func (p *Pager) refreshHeader() error {
+	// a new database isn't written until its first commit
+	size, err := p.file.Size()
+	if err != nil || size == 0 {
+		return err
+	}
+	header, err := ReadDatabaseHeader(p.file)
+	if err != nil {
+		return err
+	}
+	if header.FileChangeCounter == p.header.FileChangeCounter {
+		return nil
+	}
+	*p.header = *header
+	p.cache.Clear()
+	return nil
+}
+
+// Commit writes the dirty pages and the updated header to the database file.
+// The original pages are saved to the rollback journal first so the commit is atomic.
+// In WAL mode the pages are appended to the log instead, and the log is checkpointed
+// once it holds walAutoCheckpoint frames.
+func (p *Pager) Commit() error {
+	if !p.inTransaction {
+		return fmt.Errorf("cannot commit - no transaction is active")
+	}
+	// a full auto-vacuum database moves its last pages to the free pages and drops the freelist
+	if p.autoVacuum() && p.header.IncrementalVacuum == 0 && p.header.FreelistCount > 0 {
+		if err := p.autoVacuumCommit(); err != nil {
+			return err
+		}
+	}
+	if p.wal == nil {
+		if err := p.file.Lock(vfs.LockExclusive); err != nil {
+			return err
+		}
+	}
+
+	// every commit changes the file change counter so the other connections know their cache is stale,
+	// the connections in WAL mode use the log instead and page 1 is only written when the header changed
+	if p.wal == nil {
+		p.header.FileChangeCounter++
+		p.header.VersionValidFor = p.header.FileChangeCounter
+		p.header.VersionUsed = constant.SQLiteVersionNumber
+	}
+	if _, dirty := p.dirty[1]; p.wal == nil || dirty || *p.header != p.savedHeader {
+		page1, err := p.Get(1)
+		if err != nil {
+			return err
+		}
+		p.Unpin(page1)
+		data := make([]byte, len(page1.Data))
+		copy(data, page1.Data)
+		p.header.encode(data)
+		if err := p.Write(1, data); err != nil {
+			return err
+		}
+	}
+
+	pageNumbers := make([]uint32, 0, len(p.dirty))
+	for pageNumber := range p.dirty {
+		pageNumbers = append(pageNumbers, pageNumber)
+	}
+	sort.Slice(pageNumbers, func(i, j int) bool { return pageNumbers[i] < pageNumbers[j] })
+	// the pages written beyond the end of a database which shrank are dropped
+	written := pageNumbers
+	for len(written) > 0 && written[len(written)-1] > p.header.DatabaseSize {
+		p.cache.Remove(written[len(written)-1])
+		written = written[:len(written)-1]
+	}
+	if p.wal != nil {
+		if len(written) > 0 {
+			pages := make([]*Page, len(written))
+			for i, pageNumber := range written {
+				pages[i] = p.dirty[pageNumber]
+			}
+			if err := p.wal.commit(pages, p.header.DatabaseSize); err != nil {
+				return err
+			}
+		}
+	} else {
+		if err := p.writeJournal(pageNumbers); err != nil {
+			return err
+		}
+		if err := p.writePages(written); err != nil {
+			// put the original pages back so the file isn't left half written
+			if p.journal != nil {
+				p.journal.rollback(p.file)
+			}
+			return err
+		}
+		if p.journal != nil {
+			if err := p.journal.delete(); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, pageNumber := range written {
+		page := p.dirty[pageNumber]
+		page.pins = 0
+		p.cache.Put(page)
+		p.cache.Unpin(page)
+	}
+	p.endTransaction()
+
+	// like sqlite, a failed automatic checkpoint doesn't fail the commit
+	if p.wal != nil && p.wal.frameCount >= walAutoCheckpoint {
+		p.Checkpoint(CheckpointPassive)
+	}
+	return nil
+}
+
+// writeJournal writes the original content of the pages the commit overwrites or truncates to the
+// journal, the pages added by the transaction are dropped by truncating the file on rollback.
+// The page numbers must be sorted.
+func (p *Pager) writeJournal(pageNumbers []uint32) error {
+	if p.journal == nil {
+		return nil
+	}
+	var originals []journalPage
+	save := func(pageNumber uint32) error {
+		data, err := ReadBTreePage(p.file, p.limits.pageSize, pageNumber)
+		if err != nil {
+			return err
+		}
+		originals = append(originals, journalPage{number: pageNumber, data: data})
+		return nil
+	}
+	for _, pageNumber := range pageNumbers {
+		if pageNumber > p.savedHeader.DatabaseSize {
+			break
+		}
+		if err := save(pageNumber); err != nil {
+			return err
+		}
+	}
+	for pageNumber := p.header.DatabaseSize + 1; pageNumber <= p.savedHeader.DatabaseSize; pageNumber++ {
+		if _, dirty := p.dirty[pageNumber]; dirty || pageNumber == p.pendingBytePage() {
+			continue
+		}
+		if err := save(pageNumber); err != nil {
+			return err
+		}
+	}
+	return p.journal.write(originals, p.savedHeader.DatabaseSize, p.limits.pageSize)
+}
+
+// writePages writes the dirty pages to the database file, truncates the file when the database
+// shrank and syncs it.
+func (p *Pager) writePages(pageNumbers []uint32) error {
+	for _, pageNumber := range pageNumbers {
+		offset := int64(pageNumber-1) * int64(p.limits.pageSize)
+		if _, err := p.file.WriteAt(p.dirty[pageNumber].Data, offset); err != nil {
+			return err
+		}
+	}
+	if p.header.DatabaseSize < p.savedHeader.DatabaseSize {
+		if err := p.file.Truncate(int64(p.header.DatabaseSize) * int64(p.limits.pageSize)); err != nil {
+			return err
+		}
+	}
+	return p.file.Sync()
+}
+
+// Rollback drops the pages written by the transaction and restores the header.
+func (p *Pager) Rollback() error {
+	if !p.inTransaction {
+		return fmt.Errorf("cannot rollback - no transaction is active")
+	}
+	*p.header = p.savedHeader
+	p.endTransaction()
+	return nil
+}
+
+func (p *Pager) endTransaction() {
+	p.inTransaction = false
+	p.dirty = nil
+	// the read transaction the write transaction has been started within sees its commit
+	if p.readers == 0 {
+		p.endSnapshot()
+	} else if p.wal != nil {
+		p.wal.beginRead()
+	}
+	p.unlock()
+}
+
+// Write replaces the content of the page, the page data must not be modified afterwards.
+func (p *Pager) Write(pageNumber uint32, data []byte) error {
+	if !p.inTransaction {
+		return fmt.Errorf("cannot write page %d outside of a transaction", pageNumber)
+	}
+	if len(data) != p.limits.pageSize {
+		return fmt.Errorf("page %d has %d bytes instead of %d", pageNumber, len(data), p.limits.pageSize)
+	}
+	p.cache.Remove(pageNumber)
+	p.dirty[pageNumber] = &Page{Number: pageNumber, Data: data}
+	return nil
+}
+
+// Allocate returns a zeroed page, a page of the freelist is reused before the database grows.
+func (p *Pager) Allocate() (uint32, error) {
+	if !p.inTransaction {
+		return 0, fmt.Errorf("cannot allocate a page outside of a transaction")
+	}
+	pageNumber, err := p.allocateFree()
+	if err != nil {
+		return 0, err
+	}
+	if pageNumber == 0 {
+		return p.extend()
+	}
+
+	if err := p.Write(pageNumber, make([]byte, p.limits.pageSize)); err != nil {
+		return 0, err
+	}
+	return pageNumber, nil
+}
+
+// extend adds a zeroed page at the end of the database and returns it. The page holding the lock
+// bytes is never used and the pointer-map pages of an auto-vacuum database are added on the way.
+func (p *Pager) extend() (uint32, error) {
+	for {
+		p.header.DatabaseSize++
+		pageNumber := p.header.DatabaseSize
+		if pageNumber == p.pendingBytePage() {
+			continue
+		}
+		if err := p.Write(pageNumber, make([]byte, p.limits.pageSize)); err != nil {
+			return 0, err
+		}
+		if !p.isPtrmapPage(pageNumber) {
+			return pageNumber, nil
+		}
+	}
+}
+
+// allocateFree takes a page from the freelist, it returns 0 when the freelist is empty.
+// The last leaf of the first trunk page is taken, or the trunk page itself once it has no leaves left.
+func (p *Pager) allocateFree() (uint32, error) {
+	trunkNumber := p.header.FreelistTrunkPage
+	if trunkNumber == 0 {
+		return 0, nil
+	}
+	if trunkNumber == 1 || trunkNumber > p.header.DatabaseSize {
+		return 0, fmt.Errorf("invalid freelist trunk page: %d", trunkNumber)
+	}
+	trunk, err := p.Get(trunkNumber)
+	if err != nil {
+		return 0, err
+	}
+	p.Unpin(trunk)
+
+	leafCount := binary.BigEndian.Uint32(trunk.Data[4:8])
+	if int(leafCount) > p.limits.usableSize/4-2 {
+		return 0, fmt.Errorf("freelist trunk page %d has too many leaves: %d", trunkNumber, leafCount)
+	}
+	if p.header.FreelistCount > 0 {
+		p.header.FreelistCount--
+	}
+	if leafCount == 0 {
+		p.header.FreelistTrunkPage = binary.BigEndian.Uint32(trunk.Data[0:4])
+		return trunkNumber, nil
+	}
+
+	leaf := binary.BigEndian.Uint32(trunk.Data[4+4*leafCount:])
+	if leaf <= 1 || leaf > p.header.DatabaseSize {
+		return 0, fmt.Errorf("invalid freelist leaf page %d in trunk page %d", leaf, trunkNumber)
+	}
+	data := make([]byte, len(trunk.Data))
+	copy(data, trunk.Data)
+	binary.BigEndian.PutUint32(data[4:8], leafCount-1)
+	binary.BigEndian.PutUint32(data[4+4*leafCount:], 0)
+	if err := p.Write(trunkNumber, data); err != nil {
+		return 0, err
+	}
+	return leaf, nil
+}
+
+// pendingBytePage returns the page holding the byte at 1GiB used by the file locks.
+func (p *Pager) pendingBytePage() uint32 {
+	return uint32(constant.PendingByte/p.limits.pageSize) + 1
+}
+
+// InTransaction reports whether a write transaction is active.
+func (p *Pager) InTransaction() bool {
+	return p.inTransaction
+}
+
+// FreePage adds the page to the freelist. The freed page becomes a leaf of the first trunk page
+// when the trunk has room left, otherwise it becomes the new first trunk page.
+// See https://www.sqlite.org/fileformat2.html#the_freelist
+func (p *Pager) FreePage(pageNumber uint32) error {
+	if !p.inTransaction {
+		return fmt.Errorf("cannot free page %d outside of a transaction", pageNumber)
+	}
+	if pageNumber <= 1 || pageNumber > p.header.DatabaseSize {
+		return fmt.Errorf("cannot free page %d: invalid page number", pageNumber)
+	}
+
+	if trunkNumber := p.header.FreelistTrunkPage; trunkNumber != 0 {
+		trunk, err := p.Get(trunkNumber)
+		if err != nil {
+			return err
+		}
+		p.Unpin(trunk)
+		leafCount := binary.BigEndian.Uint32(trunk.Data[4:8])
+		// sqlite before 3.6.0 considers the trunk pages with more leaves than this corrupted
+		if int(leafCount) < p.limits.usableSize/4-8 {
+			data := make([]byte, len(trunk.Data))
+			copy(data, trunk.Data)
+			binary.BigEndian.PutUint32(data[4:8], leafCount+1)
+			binary.BigEndian.PutUint32(data[8+4*leafCount:], pageNumber)
+			if err := p.Write(trunkNumber, data); err != nil {
+				return err
+			}
+			p.header.FreelistCount++
+			return p.writePtrmap(pageNumber, ptrmapFreePage, 0)
+		}
+	}
+
+	data := make([]byte, p.limits.pageSize)
+	binary.BigEndian.PutUint32(data[0:4], p.header.FreelistTrunkPage)
+	if err := p.Write(pageNumber, data); err != nil {
+		return err
+	}
+	p.header.FreelistTrunkPage = pageNumber
+	p.header.FreelistCount++
+	return p.writePtrmap(pageNumber, ptrmapFreePage, 0)
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The types of the pointer-map entries, the parent page is only set for the last three.
+// See https://www.sqlite.org/fileformat2.html#pointer_map_or_ptrmap_pages
+const (
+	ptrmapRootPage  byte = 1
+	ptrmapFreePage  byte = 2
+	ptrmapOverflow1 byte = 3
+	ptrmapOverflow2 byte = 4
+	ptrmapBTree     byte = 5
+)
+
+// ptrmapEntrySize is the size of an entry: its type followed by the parent page number.
+const ptrmapEntrySize = 5
+
+// autoVacuum reports whether the database has pointer-map pages, which is the case of the
+// auto-vacuum databases, full or incremental.
+func (p *Pager) autoVacuum() bool {
+	return p.header.LargestRootPage != 0
+}
+
+// ptrmapPage returns the pointer-map page holding the entry of the page, the page 2 is the first
+// one and every pointer-map page is followed by the pages it has an entry for.
+func (p *Pager) ptrmapPage(pageNumber uint32) uint32 {
+	if pageNumber < 2 {
+		return 0
+	}
+	pagesPerMap := uint32(p.limits.usableSize/ptrmapEntrySize) + 1
+	ptrmap := (pageNumber-2)/pagesPerMap*pagesPerMap + 2
+	// the page holding the lock bytes is never used, the next one takes its place
+	if ptrmap == p.pendingBytePage() {
+		ptrmap++
+	}
+	return ptrmap
+}
+
+// isPtrmapPage reports whether the page of an auto-vacuum database is a pointer-map page.
+func (p *Pager) isPtrmapPage(pageNumber uint32) bool {
+	return p.autoVacuum() && p.ptrmapPage(pageNumber) == pageNumber
+}
+
+// ptrmapOffset returns where the entry of the page is in its pointer-map page.
+func (p *Pager) ptrmapOffset(pageNumber uint32) (uint32, int, error) {
+	ptrmap := p.ptrmapPage(pageNumber)
+	if ptrmap == 0 || pageNumber <= ptrmap || ptrmap > p.header.DatabaseSize {
+		return 0, 0, fmt.Errorf("page %d doesn't have a pointer-map entry", pageNumber)
+	}
+	return ptrmap, int(pageNumber-ptrmap-1) * ptrmapEntrySize, nil
+}
+
+// readPtrmap returns the type and the parent page of the pointer-map entry of the page.
+func (p *Pager) readPtrmap(pageNumber uint32) (byte, uint32, error) {
+	entryType, parent, err := p.readPtrmapEntry(pageNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+	if entryType < ptrmapRootPage || entryType > ptrmapBTree {
+		return 0, 0, fmt.Errorf("page %d has an invalid pointer-map entry type: %d", pageNumber, entryType)
+	}
+	return entryType, parent, nil
+}
+
+// readPtrmapEntry returns the pointer-map entry of the page as it is, its type isn't checked.
+func (p *Pager) readPtrmapEntry(pageNumber uint32) (byte, uint32, error) {
+	ptrmap, offset, err := p.ptrmapOffset(pageNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+	page, err := p.Get(ptrmap)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer p.Unpin(page)
+	return page.Data[offset], binary.BigEndian.Uint32(page.Data[offset+1:]), nil
+}
+
+// writePtrmap sets the pointer-map entry of the page, it does nothing when the database isn't
+// an auto-vacuum database. The pointer-map page is only written when the entry changes.
+func (p *Pager) writePtrmap(pageNumber uint32, entryType byte, parent uint32) error {
+	if !p.autoVacuum() {
+		return nil
+	}
+	ptrmap, offset, err := p.ptrmapOffset(pageNumber)
+	if err != nil {
+		return err
+	}
+	page, err := p.Get(ptrmap)
+	if err != nil {
+		return err
+	}
+	p.Unpin(page)
+	if page.Data[offset] == entryType && binary.BigEndian.Uint32(page.Data[offset+1:]) == parent {
+		return nil
+	}
+	data := make([]byte, len(page.Data))
+	copy(data, page.Data)
+	data[offset] = entryType
+	binary.BigEndian.PutUint32(data[offset+1:], parent)
+	return p.Write(ptrmap, data)
+}
+
+// writeChildPtrmaps points the pointer-map entries of the children of the node and of the first
+// overflow page of its cells to the node. Writing them every time a node is written keeps them
+// right whatever cells moved to the node.
+func (p *Pager) writeChildPtrmaps(n *btreeNode) error {
+	if !p.autoVacuum() {
+		return nil
+	}
+	for i, cell := range n.cells {
+		if !n.isLeaf() {
+			if err := p.writePtrmap(n.child(i), ptrmapBTree, n.number); err != nil {
+				return err
+			}
+		}
+		overflow, err := p.cellOverflowPage(n.pageType, cell)
+		if err != nil {
+			return err
+		}
+		if overflow != 0 {
+			if err := p.writePtrmap(overflow, ptrmapOverflow1, n.number); err != nil {
+				return err
+			}
+		}
+	}
+	if n.isLeaf() {
+		return nil
+	}
+	return p.writePtrmap(n.rightMost, ptrmapBTree, n.number)
+}
+
+// cellOverflowPage returns the first overflow page of the raw cell, 0 when its payload fits in the page.
+func (p *Pager) cellOverflowPage(pageType BTreePageType, cell []byte) (uint32, error) {
+	// the cells of the interior pages of a table don't have any payload
+	if pageType == BTREE_INTERNAL_TABLE {
+		return 0, nil
+	}
+	decoded, _, err := decodeCell(pageType, cell, p.limits)
+	if err != nil {
+		return 0, err
+	}
+	return decoded.OverflowPage, nil
+}
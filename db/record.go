@@ -2,10 +2,8 @@ package db
 
 import (
 	"encoding/binary"
-	"errors"
 	"fmt"
 	"math"
-	"os"
 )
 
 // Record represents a single record in sqlite_master
@@ -13,43 +11,103 @@ type Record struct {
 	RowID   int64
 	Header  RecordHeader
 	Payload []byte
+	// Encoding is the database text encoding the text fields are stored in
+	Encoding TextEncoding
 }
 
+// FieldData decodes the field idx, the integers are returned as the smallest signed Go type
+// holding their serial type: int8, int16, int32 or int64.
 func (r *Record) FieldData(idx int) (any, FieldType, error) {
+	if idx >= len(r.Header.Fields) {
+		return nil, Null, fmt.Errorf("field %d doesn't exist", idx)
+	}
 	field := r.Header.Fields[idx]
+	if field.Offset+field.Size > uint64(len(r.Payload)) {
+		return nil, field.FieldType, fmt.Errorf("field %d exceeds the record payload", idx)
+	}
+	data := r.Payload[field.Offset : field.Offset+field.Size]
+
 	switch field.FieldType {
 	case Null:
 		return nil, field.FieldType, nil
 	case Int8:
-		return int8(r.Payload[field.Offset : field.Offset+1][0]), field.FieldType, nil
+		return int8(data[0]), field.FieldType, nil
 	case Int16:
-		return binary.BigEndian.Uint16(r.Payload[field.Offset : field.Offset+2]), field.FieldType, nil
+		return int16(binary.BigEndian.Uint16(data)), field.FieldType, nil
 	case Int24:
-		return int32(binary.BigEndian.Uint16(r.Payload[field.Offset : field.Offset+3])), field.FieldType, nil
+		return int32(bigEndianInt(data)), field.FieldType, nil
 	case Int32:
-		return int32(binary.BigEndian.Uint16(r.Payload[field.Offset : field.Offset+4])), field.FieldType, nil
+		return int32(binary.BigEndian.Uint32(data)), field.FieldType, nil
 	case Int48:
-		return int64(binary.BigEndian.Uint16(r.Payload[field.Offset : field.Offset+6])), field.FieldType, nil
+		return bigEndianInt(data), field.FieldType, nil
 	case Int64:
-		return int64(binary.BigEndian.Uint16(r.Payload[field.Offset : field.Offset+8])), field.FieldType, nil
+		return int64(binary.BigEndian.Uint64(data)), field.FieldType, nil
 	case Float64:
-		return math.Float64frombits(uint64(binary.BigEndian.Uint16(r.Payload[field.Offset : field.Offset+8]))),
-			field.FieldType, nil
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), field.FieldType, nil
+	case Zero:
+		return int8(0), field.FieldType, nil
+	case One:
+		return int8(1), field.FieldType, nil
 	case String:
-		data := r.Payload[field.Offset:]
-		if idx+1 != len(r.Header.Fields) {
-			data = r.Payload[field.Offset : uint64(field.Offset)+field.Size]
-		}
-		return string(data), field.FieldType, nil
+		return r.Encoding.Decode(data), field.FieldType, nil
 	case Blob:
-		data := r.Payload[field.Offset:]
-		if idx+1 != len(r.Header.Fields) {
-			data = r.Payload[field.Offset : uint64(field.Offset)+field.Size]
-		}
 		return data, field.FieldType, nil
 	default:
-		return nil, field.FieldType, fmt.Errorf("unimplemented")
+		return nil, field.FieldType, fmt.Errorf("serial type %d isn't supported", field.FieldType)
+	}
+}
+
+// bigEndianInt decodes a big-endian two's complement integer of up to 8 bytes.
+func bigEndianInt(data []byte) int64 {
+	var value uint64
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	// shift the sign bit to the top and back to extend it
+	shift := 64 - 8*uint(len(data))
+	return int64(value<<shift) >> shift
+}
+
+// IntField returns the integer value of the field idx whatever its integer serial type is.
+func (r *Record) IntField(idx int) (int64, error) {
+	value, err := r.Value(idx)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := value.(int64)
+	if !ok {
+		return 0, fmt.Errorf("field %d isn't an integer: %v", idx, r.Header.Fields[idx].FieldType)
+	}
+	return v, nil
+}
+
+// Value returns the field idx as one of the Go types used for the sqlite storage classes:
+// nil, int64, float64, string or []byte.
+func (r *Record) Value(idx int) (any, error) {
+	data, _, err := r.FieldData(idx)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeValue(data), nil
+}
+
+// SelectFields returns the record only with certain fields, an empty filter will return all.
+func (r Record) SelectFields(fields []int64) Record {
+	if len(fields) == 0 {
+		return r
 	}
+
+	var newFields []RecordField
+	for i := 0; i < len(fields); i++ {
+		// the columns added after the record has been written are NULL
+		if fields[i] >= int64(len(r.Header.Fields)) {
+			newFields = append(newFields, RecordField{FieldType: Null, FieldIdx: fields[i]})
+			continue
+		}
+		newFields = append(newFields, r.Header.Fields[fields[i]])
+	}
+	r.Header.Fields = newFields
+	return r
 }
 
 type RecordHeader struct {
@@ -57,9 +115,15 @@ type RecordHeader struct {
 }
 
 func NewRecordHeader(payload []byte) (RecordHeader, error) {
-	headerLengthUint64, varintSize := binary.Uvarint(payload)
+	headerLengthInt, varintSize, err := GetVarint(payload)
+	if err != nil {
+		return RecordHeader{}, err
+	}
 
-	headerLength := headerLengthUint64
+	headerLength := uint64(headerLengthInt)
+	if headerLength > uint64(len(payload)) || uint64(varintSize) > headerLength {
+		return RecordHeader{}, fmt.Errorf("invalid record header length: %d", headerLength)
+	}
 	data := payload[varintSize:headerLength]
 	curOffset := headerLength
 
@@ -67,9 +131,12 @@ func NewRecordHeader(payload []byte) (RecordHeader, error) {
 
 	idx := int64(0)
 	for len(data) != 0 {
-		serialType, at := binary.Uvarint(data)
-
+		serialTypeInt, at, err := GetVarint(data)
+		if err != nil {
+			return RecordHeader{}, err
+		}
 		data = data[at:]
+		serialType := uint64(serialTypeInt)
 
 		var fieldSize uint64
 
@@ -81,10 +148,12 @@ func NewRecordHeader(payload []byte) (RecordHeader, error) {
 			fieldSize = 1
 		case Int16:
 			fieldSize = 2
-		case Int32:
+		case Int24:
 			fieldSize = 3
-		case Int48:
+		case Int32:
 			fieldSize = 4
+		case Int48:
+			fieldSize = 6
 		case Int64:
 			fieldSize = 8
 		case Float64:
@@ -105,8 +174,11 @@ func NewRecordHeader(payload []byte) (RecordHeader, error) {
 			}
 		}
 
+		if curOffset+fieldSize > uint64(len(payload)) {
+			return RecordHeader{}, fmt.Errorf("field %d exceeds the record payload", idx)
+		}
 		fields = append(fields, RecordField{
-			Offset:    uint16(curOffset),
+			Offset:    curOffset,
 			FieldType: fieldType,
 			Size:      fieldSize,
 			FieldIdx:  idx,
@@ -123,7 +195,7 @@ func NewRecordHeader(payload []byte) (RecordHeader, error) {
 }
 
 type RecordField struct {
-	Offset    uint16
+	Offset    uint64
 	Size      uint64
 	FieldType FieldType
 	FieldIdx  int64
@@ -169,105 +241,3 @@ func StringToFieldType(s string) (FieldType, bool) {
 	}
 	return 0, false
 }
-
-// ParseRecords parses records from a table leaf page
-func ParseRecords(file *os.File, pageData []byte, pageNumber int) ([]Record, error) {
-	records := []Record{}
-
-	if len(pageData) < 1 {
-		return records, fmt.Errorf("page data too short")
-	}
-	fmt.Printf("page data: % X\n", pageData)
-	// Byte 0: Page Type
-	pageType := BTreePageType(pageData[0])
-	if pageType != BTREE_LEAF_TABLE {
-		return records, fmt.Errorf("not a table leaf page")
-	}
-
-	// Byte 1: First freeblock
-	// Byte 2-3: Number of cells
-	if len(pageData) < 4 {
-		return records, fmt.Errorf("page data too short for header")
-	}
-	nCellBytes := pageData[3:5]
-	numCells := binary.BigEndian.Uint16(nCellBytes)
-
-	// Byte 4-7: Start of cell content area
-	//cellContentStart := binary.BigEndian.Uint32(pageData[4:8])
-
-	ptrOffsite := 0
-	if pageNumber == 1 {
-		ptrOffsite = 100
-	}
-
-	// Cell pointers start at byte 8
-	cellPointers := make([]uint16, numCells)
-	for i := 0; i < int(numCells); i++ {
-		offset := 8 + i*2
-		bytePtr := pageData[offset : offset+2]
-		ptr := binary.BigEndian.Uint16(bytePtr)
-		cellPointers[i] = ptr - uint16(ptrOffsite)
-	}
-
-	// Iterate over each cell
-	for _, ptr := range cellPointers {
-		if int(ptr) >= len(pageData) {
-			continue // Invalid pointer
-		}
-		// read fist bytes to identify the payload size
-
-		cellData := pageData[ptr:]
-
-		n, _, err := ReadVarintAt(cellData, 0)
-		if err != nil {
-			return nil, err
-		}
-		cellData = cellData[n:]
-		n, rowID, err := ReadVarintAt(cellData, 0)
-		if err != nil {
-			return nil, err
-		}
-		cellData = cellData[n:]
-		//log.Println(strconv.FormatInt(rowID, 10))
-
-		recordHeader, err := NewRecordHeader(cellData)
-
-		rec := Record{
-			RowID:   rowID,
-			Header:  recordHeader,
-			Payload: cellData,
-		}
-
-		records = append(records, rec)
-	}
-
-	return records, nil
-}
-
-// ReadVarintAt reads a variable-length integer from the buffer starting at the given offset.
-// It returns the number of bytes read, the decoded integer value, and an error if any.
-func ReadVarintAt(buffer []byte, offset int) (bytesRead uint8, value int64, err error) {
-	var size uint8 = 0
-	var result int64 = 0
-
-	// Maximum 8 bytes to prevent overflow (7 bits per byte * 8 = 56 bits)
-	for size < 8 {
-		if offset >= len(buffer) {
-			return size, result, errors.New("buffer too small to contain varint")
-		}
-
-		byteVal := buffer[offset]
-		dataBits := byteVal & 0x7F              // Extract lower 7 bits
-		result |= int64(dataBits) << (7 * size) // Accumulate into result
-
-		size++
-		offset++
-
-		if byteVal&0x80 == 0 { // MSB is 0, last byte
-			return size, result, nil
-		}
-	}
-
-	// If we reach here, varint is too long
-	return size, result, errors.New("varint is too long (exceeds 8 bytes)")
-}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// EncodeRecord serializes the values into the sqlite record format, a header with the serial type of
+// every value followed by the values. The integers take the smallest serial type able to hold them,
+// 0 and 1 don't take any byte, and the text is stored in the given encoding.
+// The values can be nil, any Go integer, bool, float32, float64, string or []byte.
+// See https://www.sqlite.org/fileformat2.html#record_format
+func EncodeRecord(values []any, encoding TextEncoding) ([]byte, error) {
+	serialTypes := make([]int64, len(values))
+	bodies := make([][]byte, len(values))
+	headerSize, bodySize := 0, 0
+	for i, value := range values {
+		serialType, body, err := encodeValue(value, encoding)
+		if err != nil {
+			return nil, fmt.Errorf("field %d: %w", i, err)
+		}
+		serialTypes[i] = serialType
+		bodies[i] = body
+		headerSize += VarintLen(serialType)
+		bodySize += len(body)
+	}
+
+	// the header size counts its own varint too
+	headerLength := headerSize + VarintLen(int64(headerSize))
+	if VarintLen(int64(headerLength)) != VarintLen(int64(headerSize)) {
+		headerLength++
+	}
+
+	record := make([]byte, 0, headerLength+bodySize)
+	record = AppendVarint(record, int64(headerLength))
+	for _, serialType := range serialTypes {
+		record = AppendVarint(record, serialType)
+	}
+	for _, body := range bodies {
+		record = append(record, body...)
+	}
+	return record, nil
+}
+
+// Values returns the values of the row, ready to be encoded with EncodeRecord.
+func (r RecordTuple) Values() []any {
+	values := make([]any, len(r))
+	for i, tuple := range r {
+		values[i] = tuple.value
+	}
+	return values
+}
+
+// encodeValue returns the serial type of the value and its content.
+func encodeValue(value any, encoding TextEncoding) (int64, []byte, error) {
+	switch v := normalizeValue(value).(type) {
+	case nil:
+		return int64(Null), nil, nil
+	case int64:
+		serialType, data := encodeInt(v)
+		return serialType, data, nil
+	case uint:
+		return encodeValue(uint64(v), encoding)
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, nil, fmt.Errorf("integer %d overflows int64", v)
+		}
+		serialType, data := encodeInt(int64(v))
+		return serialType, data, nil
+	case float64:
+		return int64(Float64), binary.BigEndian.AppendUint64(nil, math.Float64bits(v)), nil
+	case string:
+		data := encoding.Encode(v)
+		return int64(len(data))*2 + int64(String), data, nil
+	case []byte:
+		return int64(len(v))*2 + int64(Blob), v, nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// encodeInt picks the smallest integer serial type holding v and returns v as a big-endian two's complement.
+func encodeInt(v int64) (int64, []byte) {
+	var fieldType FieldType
+	var size int
+	switch {
+	case v == 0:
+		return int64(Zero), nil
+	case v == 1:
+		return int64(One), nil
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		fieldType, size = Int8, 1
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		fieldType, size = Int16, 2
+	case v >= -1<<23 && v < 1<<23:
+		fieldType, size = Int24, 3
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		fieldType, size = Int32, 4
+	case v >= -1<<47 && v < 1<<47:
+		fieldType, size = Int48, 6
+	default:
+		fieldType, size = Int64, 8
+	}
+
+	data := make([]byte, size)
+	for i := size - 1; i >= 0; i-- {
+		data[i] = byte(v)
+		v >>= 8
+	}
+	return int64(fieldType), data
+}
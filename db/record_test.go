@@ -0,0 +1,96 @@
+package db
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeRecord(t *testing.T) {
+	values := []any{
+		nil, int64(0), int64(1), int64(-1), int64(127), int64(-129), int64(40000), int64(-8388608),
+		int64(math.MaxInt32), int64(1 << 40), int64(math.MinInt64), 1.5, "héllo", []byte{0, 1, 2},
+		strings.Repeat("x", 100), true,
+	}
+	wantTypes := []FieldType{Null, Zero, One, Int8, Int8, Int16, Int24, Int24, Int32, Int48, Int64, Float64, String, Blob, String, One}
+
+	for _, encoding := range []TextEncoding{EncodingUTF8, EncodingUTF16le, EncodingUTF16be} {
+		payload, err := EncodeRecord(values, encoding)
+		require.NoError(t, err)
+
+		header, err := NewRecordHeader(payload)
+		require.NoError(t, err)
+		require.Len(t, header.Fields, len(values))
+		record := Record{Header: header, Payload: payload, Encoding: encoding}
+		for i, want := range values {
+			require.Equal(t, wantTypes[i], header.Fields[i].FieldType, "field %d", i)
+			got, err := record.Value(i)
+			require.NoError(t, err)
+			if b, ok := want.(bool); ok && b {
+				want = int64(1)
+			}
+			require.Equal(t, want, got, "field %d", i)
+		}
+	}
+
+	// the 100 bytes text has a 2 bytes serial type, the header length varint still takes 1 byte
+	payload, err := EncodeRecord([]any{strings.Repeat("x", 100)}, EncodingUTF8)
+	require.NoError(t, err)
+	require.Equal(t, []byte{3, 0x81, 0x55}, payload[:3])
+
+	_, err = EncodeRecord([]any{struct{}{}}, EncodingUTF8)
+	require.Error(t, err)
+}
+
+func TestRecordSerialTypes(t *testing.T) {
+	tests := []struct {
+		serialType byte
+		body       []byte
+		fieldType  FieldType
+		want       any
+	}{
+		{0, nil, Null, nil},
+		{1, []byte{0x7f}, Int8, int64(127)},
+		{1, []byte{0x80}, Int8, int64(-128)},
+		{2, []byte{0xff, 0xfe}, Int16, int64(-2)},
+		{3, []byte{0x7f, 0xff, 0xff}, Int24, int64(8388607)},
+		{3, []byte{0x80, 0x00, 0x00}, Int24, int64(-8388608)},
+		{3, []byte{0xff, 0xff, 0xff}, Int24, int64(-1)},
+		{4, []byte{0x80, 0, 0, 0}, Int32, int64(math.MinInt32)},
+		{5, []byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff}, Int48, int64(1<<47 - 1)},
+		{5, []byte{0x80, 0, 0, 0, 0, 0}, Int48, int64(-1 << 47)},
+		{5, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}, Int48, int64(-2)},
+		{6, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, Int64, int64(-1)},
+		{7, []byte{0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18}, Float64, math.Pi},
+		{7, []byte{0xc0, 0x04, 0, 0, 0, 0, 0, 0}, Float64, -2.5},
+		{8, nil, Zero, int64(0)},
+		{9, nil, One, int64(1)},
+		{12, nil, Blob, []byte{}},
+		{14, []byte{0xca}, Blob, []byte{0xca}},
+		{18, []byte{1, 2, 3}, Blob, []byte{1, 2, 3}},
+		{13, nil, String, ""},
+		{15, []byte("a"), String, "a"},
+		{19, []byte("abc"), String, "abc"},
+	}
+	for _, test := range tests {
+		payload := append([]byte{2, test.serialType}, test.body...)
+		header, err := NewRecordHeader(payload)
+		require.NoError(t, err, "serial type %d", test.serialType)
+		require.Len(t, header.Fields, 1)
+		require.Equal(t, test.fieldType, header.Fields[0].FieldType, "serial type %d", test.serialType)
+		require.Equal(t, uint64(len(test.body)), header.Fields[0].Size, "serial type %d", test.serialType)
+
+		record := Record{Header: header, Payload: payload}
+		got, err := record.Value(0)
+		require.NoError(t, err)
+		require.Equal(t, test.want, got, "serial type %d % x", test.serialType, test.body)
+	}
+
+	// the reserved serial types and a field longer than the payload are rejected
+	for _, payload := range [][]byte{{2, 10}, {2, 11}, {2, 6, 0}, {2, 19, 'a'}} {
+		_, err := NewRecordHeader(payload)
+		require.Error(t, err, "% x", payload)
+	}
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/adzimzf/sqlite-go/constant"
+)
+
+// sqliteSequenceSQL is the SQL text of sqlite_sequence, the table of the largest rowid every
+// AUTOINCREMENT table ever had. It's created with the first AUTOINCREMENT table.
+// See https://www.sqlite.org/autoinc.html
+const sqliteSequenceSQL = "CREATE TABLE sqlite_sequence(name,seq)"
+
+// Sequence returns the largest rowid the AUTOINCREMENT table ever had, 0 when it doesn't have any
+// sqlite_sequence row yet.
+func (d *DB) Sequence(name string) (int64, error) {
+	_, record, err := d.findSequence(name)
+	if err != nil || record == nil {
+		return 0, err
+	}
+	seq, err := record.Value(1)
+	if err != nil {
+		return 0, err
+	}
+	// sqlite reads the value as an integer whatever it's been set to
+	value, _ := integerAffinity(seq).(int64)
+	return value, nil
+}
+
+// SetSequence writes the largest rowid the AUTOINCREMENT table ever had to its sqlite_sequence row,
+// the row is added when it doesn't exist. It must be called within a write transaction.
+func (d *DB) SetSequence(name string, seq int64) error {
+	tree, record, err := d.findSequence(name)
+	if err != nil {
+		return err
+	}
+	payload, err := EncodeRecord([]any{name, seq}, d.pager.encoding)
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		return tree.Update(record.RowID, payload)
+	}
+	rowID, err := tree.NewRowID()
+	if err != nil {
+		return err
+	}
+	return tree.Insert(rowID, payload)
+}
+
+// findSequence returns sqlite_sequence and the row of the table, nil when the table doesn't have any.
+func (d *DB) findSequence(name string) (*TableBTree, *Record, error) {
+	objects, err := d.schemaObjects()
+	if err != nil {
+		return nil, nil, err
+	}
+	sequence, found := findSchemaObject(objects, constant.SqliteInternalName)
+	if !found {
+		return nil, nil, fmt.Errorf("no such table: %s", constant.SqliteInternalName)
+	}
+	tree := NewTableBTree(d.pager, int(sequence.rootPage))
+	records, err := tree.GetRecords()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, record := range records {
+		sequenceName, err := record.Value(0)
+		if err != nil {
+			return nil, nil, err
+		}
+		if sequenceName == name {
+			return tree, &records[i], nil
+		}
+	}
+	return tree, nil, nil
+}
+
+// renameSequence renames the sqlite_sequence row of the table, or deletes it when newName is empty.
+func (d *DB) renameSequence(objects []schemaObject, name, newName string) error {
+	// only the databases with an AUTOINCREMENT table have a sqlite_sequence table
+	if _, found := findSchemaObject(objects, constant.SqliteInternalName); !found {
+		return nil
+	}
+	tree, record, err := d.findSequence(name)
+	if err != nil || record == nil {
+		return err
+	}
+	if newName == "" {
+		return tree.Delete(record.RowID)
+	}
+	seq, err := record.Value(1)
+	if err != nil {
+		return err
+	}
+	payload, err := EncodeRecord([]any{newName, seq}, d.pager.encoding)
+	if err != nil {
+		return err
+	}
+	return tree.Update(record.RowID, payload)
+}
+
+// NewAutoIncrementRowID returns the rowid of a new row of an AUTOINCREMENT table: one more than the
+// largest rowid the table has or ever had, the sequence. The rowids are never reused so there's no
+// new rowid once the largest possible rowid has been used.
+func (t *TableBTree) NewAutoIncrementRowID(sequence int64) (int64, error) {
+	cursor := t.Cursor()
+	defer cursor.Close()
+	if err := cursor.Last(); err != nil {
+		return 0, err
+	}
+	next := int64(1)
+	if cursor.Valid() {
+		rowID, err := cursor.RowID()
+		if err != nil {
+			return 0, err
+		}
+		if rowID == math.MaxInt64 {
+			return 0, fmt.Errorf("database or disk is full")
+		}
+		next = rowID + 1
+	}
+	if sequence == math.MaxInt64 {
+		return 0, fmt.Errorf("database or disk is full")
+	}
+	return max(next, sequence+1), nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"math"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoIncrement(t *testing.T) {
+	database, err := CreateDB(vfs.NewMemFile(nil), CreateOptions{})
+	require.NoError(t, err)
+	info, err := ExtractQueryInfo("create table fruits (id integer primary key autoincrement, name text)")
+	require.NoError(t, err)
+	require.True(t, info.DDL.Table.IsAutoIncrement)
+	require.NoError(t, database.CreateTable(info.DDL.Table, false))
+	_, err = ExtractQueryInfo("create table seeds (name text primary key autoincrement)")
+	require.EqualError(t, err, "AUTOINCREMENT is only allowed on an INTEGER PRIMARY KEY")
+
+	// sqlite_sequence is a table like the others
+	schema, err := database.FindTableSchema("sqlite_sequence")
+	require.NoError(t, err)
+	require.Equal(t, int64(3), schema.PageID)
+	require.Len(t, schema.Columns, 2)
+	seq, err := database.Sequence("fruits")
+	require.NoError(t, err)
+	require.Zero(t, seq)
+
+	tree, err := database.FindTablePage("fruits")
+	require.NoError(t, err)
+	record, err := EncodeRecord([]any{nil, "apple"}, EncodingUTF8)
+	require.NoError(t, err)
+	require.NoError(t, database.Begin())
+	require.NoError(t, tree.Insert(10, record))
+	require.NoError(t, database.SetSequence("fruits", 10))
+	require.NoError(t, tree.Delete(10))
+	require.NoError(t, database.Commit())
+
+	// the rowid of the deleted row isn't reused
+	seq, err = database.Sequence("fruits")
+	require.NoError(t, err)
+	require.Equal(t, int64(10), seq)
+	rowID, err := tree.NewAutoIncrementRowID(seq)
+	require.NoError(t, err)
+	require.Equal(t, int64(11), rowID)
+	rowID, err = tree.NewRowID()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rowID)
+	_, err = tree.NewAutoIncrementRowID(math.MaxInt64)
+	require.Error(t, err)
+}
@@ -1,8 +1,12 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	sqlparser "github.com/adzimzf/sqlite-go/sql"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 type QueryType int32
@@ -13,20 +17,30 @@ const (
 	INSERT
 	DELETE
 	UPDATE
+	PRAGMA
+	VACUUM
+	DROP_TABLE
+	DROP_INDEX
+	ALTER_TABLE
 )
 
 type QueryInfo struct {
-	QueryType    QueryType
-	SelectFields []*SelectFieldExpression // SELECT
-	//SetExpressions_      []*SetExpression         // UPDATE
+	QueryType      QueryType
+	SelectFields   []*SelectFieldExpression // SELECT
+	SetExpressions []*SetExpression         // UPDATE
 	//NewTable_            *string                  // CREATE TABLE
 	//ColDefExpressions_   []*ColDefExpression      // CREATE TABLE
 	//IndexDefExpressions_ []*IndexDefExpression    // CREATE TABLE
-	//TargetCols_          []*string                // INSERT
-	//Values_              []*types.Value           // INSERT
+	TableName  string   // INSERT, UPDATE, DELETE
+	TargetCols []string // INSERT
+	Values     [][]any  // INSERT
 	//OnExpressions_       *BinaryOpExpression      // SELECT (with JOIN)
-	JoinTables []string // SELECT
-	//WhereExpression_     *BinaryOpExpression      // SELECT, UPDATE, DELETE
+	JoinTables  []string       // SELECT
+	Where       sqlparser.Expr // UPDATE, DELETE
+	PragmaName  string         // PRAGMA
+	PragmaValue any            // PRAGMA, nil when the pragma is only queried
+	VacuumInto  string         // VACUUM, empty when the database is rebuilt in place
+	DDL         DDLInfo        // CREATE TABLE, DROP TABLE, DROP INDEX, ALTER TABLE
 	//LimitNum_            int32                    // SELECT
 	//OffsetNum_           int32                    // SELECT
 	//OrderByExpressions_  []*OrderByExpression     // SELECT
@@ -40,6 +54,12 @@ func (q *QueryInfo) FieldNameByTable(tbName string) []string {
 	return fieldNames
 }
 
+// SetExpression is a column assignment of an UPDATE.
+type SetExpression struct {
+	ColName string
+	Expr    sqlparser.Expr
+}
+
 type SelectFieldExpression struct {
 	IsAgg     bool
 	AggType   AggregationType
@@ -79,6 +99,14 @@ func ExtractQueryInfo(sql string) (QueryInfo, error) {
 	if err != nil {
 		return QueryInfo{}, err
 	}
+	if res.QueryType == CREATE_TABLE {
+		res.DDL.Table.RawSQL = createTableSQL(sql)
+	}
+	if res.QueryType == ALTER_TABLE && res.DDL.Action == DDLAddColumn {
+		if res.DDL.ColumnSQL, err = addColumnSQL(sql); err != nil {
+			return QueryInfo{}, err
+		}
+	}
 
 	return res, nil
 }
@@ -89,6 +117,26 @@ func RootSQLVisitor(tree sqlparser.Statement, queryInfo *QueryInfo) error {
 		case *sqlparser.Select:
 			queryInfo.QueryType = SELECT
 			return true, nil
+		case *sqlparser.Insert:
+			queryInfo.QueryType = INSERT
+			return false, InsertVisitor(nodeType, queryInfo)
+		case *sqlparser.Delete:
+			queryInfo.QueryType = DELETE
+			queryInfo.TableName = nodeType.Table.Name.String()
+			queryInfo.Where = whereExpr(nodeType.Where)
+			return false, nil
+		case *sqlparser.Update:
+			queryInfo.QueryType = UPDATE
+			return false, UpdateVisitor(nodeType, queryInfo)
+		case *sqlparser.Pragma:
+			queryInfo.QueryType = PRAGMA
+			return false, PragmaVisitor(nodeType, queryInfo)
+		case *sqlparser.DDL:
+			return false, DDLVisitor(nodeType, queryInfo)
+		case *sqlparser.Vacuum:
+			queryInfo.QueryType = VACUUM
+			queryInfo.VacuumInto = nodeType.Into
+			return false, nil
 		case *sqlparser.TableName:
 			queryInfo.SelectFields = append(queryInfo.SelectFields, &SelectFieldExpression{
 				TableName: node.(*sqlparser.TableName).Name.String(),
@@ -132,6 +180,94 @@ func RootSQLVisitor(tree sqlparser.Statement, queryInfo *QueryInfo) error {
 	}, tree)
 }
 
+func InsertVisitor(node *sqlparser.Insert, queryInfo *QueryInfo) error {
+	queryInfo.TableName = node.Table.Name.String()
+	for _, column := range node.Columns {
+		queryInfo.TargetCols = append(queryInfo.TargetCols, column.String())
+	}
+	for _, row := range node.Rows {
+		values := make([]any, len(row))
+		for i, expr := range row {
+			value, err := EvalExpr(expr, nil)
+			if err != nil {
+				return err
+			}
+			values[i] = value
+		}
+		queryInfo.Values = append(queryInfo.Values, values)
+	}
+	return nil
+}
+
+func UpdateVisitor(node *sqlparser.Update, queryInfo *QueryInfo) error {
+	queryInfo.TableName = node.Table.Name.String()
+	for _, expr := range node.Exprs {
+		queryInfo.SetExpressions = append(queryInfo.SetExpressions, &SetExpression{
+			ColName: expr.Name.String(),
+			Expr:    expr.Expr,
+		})
+	}
+	queryInfo.Where = whereExpr(node.Where)
+	return nil
+}
+
+// PragmaVisitor reads the name and the value of a PRAGMA, a name used as value is kept as a string.
+func PragmaVisitor(node *sqlparser.Pragma, queryInfo *QueryInfo) error {
+	queryInfo.PragmaName = node.Name.Lowered()
+	if column, ok := node.Value.(*sqlparser.ColName); ok && column.Qualifier.Name.IsEmpty() {
+		queryInfo.PragmaValue = column.Name.String()
+		return nil
+	}
+	if node.Value == nil {
+		return nil
+	}
+	value, err := EvalExpr(node.Value, nil)
+	if err != nil {
+		return err
+	}
+	queryInfo.PragmaValue = value
+	return nil
+}
+
+func whereExpr(where *sqlparser.Where) sqlparser.Expr {
+	if where == nil {
+		return nil
+	}
+	return where.Expr
+}
+
+// LiteralValue converts a literal to its Go value: nil, int64, float64, string or []byte.
+// An integer literal too big for int64 is a real like in sqlite.
+func LiteralValue(expr sqlparser.Expr) (any, error) {
+	switch node := expr.(type) {
+	case *sqlparser.NullVal:
+		return nil, nil
+	case *sqlparser.SQLVal:
+		switch node.Type {
+		case sqlparser.StrVal:
+			return string(node.Val), nil
+		case sqlparser.IntVal:
+			i, err := strconv.ParseInt(string(node.Val), 10, 64)
+			if err == nil {
+				return i, nil
+			}
+			if !errors.Is(err, strconv.ErrRange) {
+				return nil, err
+			}
+			return strconv.ParseFloat(string(node.Val), 64)
+		case sqlparser.FloatVal:
+			return strconv.ParseFloat(string(node.Val), 64)
+		case sqlparser.HexVal:
+			blob, err := node.HexDecode()
+			if err != nil || len(node.Val)%2 != 0 {
+				return nil, fmt.Errorf("malformed blob literal: x'%s'", node.Val)
+			}
+			return blob, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported literal: %T", expr)
+}
+
 func TableExprVisitor(node sqlparser.TableExpr, tableName *string) error {
 	return sqlparser.Walk(func(node sqlparser.SQLNode) (kontinue bool, err error) {
 		switch nodeType := node.(type) {
@@ -186,6 +322,96 @@ func SelectExprsVisitor(node sqlparser.SQLNode, selectFieldExp *SelectFieldExpre
 	}, node)
 }
 
+var createTablePrefix = regexp.MustCompile(`(?is)^\s*CREATE\s+TABLE\s+(IF\s+NOT\s+EXISTS\s+)?`)
+
+// createTableSQL returns the text of the statement as sqlite stores it in sqlite_master:
+// the keywords are normalized, IF NOT EXISTS is dropped and the text after the name is kept as written.
+func createTableSQL(sql string) string {
+	sql = createTablePrefix.ReplaceAllString(sql, "")
+	return "CREATE TABLE " + strings.TrimRight(sql, "; \t\n\r")
+}
+
+// addColumnSQL returns the definition of the column of ALTER TABLE ADD COLUMN as it's written.
+func addColumnSQL(sql string) (string, error) {
+	tokens, err := sqlparser.Tokenize(sql)
+	if err != nil {
+		return "", err
+	}
+	for i, token := range tokens {
+		if token.ID != sqlparser.ADD {
+			continue
+		}
+		if i+1 < len(tokens) && tokens[i+1].ID == sqlparser.COLUMN {
+			i++
+		}
+		if i+1 < len(tokens) {
+			return strings.TrimRight(sql[tokens[i+1].Start:], "; \t\n\r"), nil
+		}
+	}
+	return "", fmt.Errorf("the column definition is missing: %s", sql)
+}
+
+// DDLVisitor reads the statements changing the schema.
+func DDLVisitor(node *sqlparser.DDL, queryInfo *QueryInfo) error {
+	queryInfo.TableName = node.Table.Name.String()
+	switch node.Action {
+	case sqlparser.CreateStr:
+		queryInfo.QueryType = CREATE_TABLE
+		queryInfo.TableName = node.NewName.Name.String()
+		queryInfo.DDL = DDLInfo{Action: DDLCreateTable, IfNotExists: node.IfNotExists}
+		return TableSchemaVisitor(node, &queryInfo.DDL.Table)
+	case sqlparser.DropStr:
+		queryInfo.QueryType = DROP_TABLE
+		queryInfo.DDL = DDLInfo{Action: DDLDropTable, IfExists: node.IfExists}
+	case sqlparser.DropIndexStr:
+		queryInfo.QueryType = DROP_INDEX
+		queryInfo.DDL = DDLInfo{Action: DDLDropIndex, IfExists: node.IfExists, Name: node.IndexSpec.Name.String()}
+	case sqlparser.RenameStr:
+		queryInfo.QueryType = ALTER_TABLE
+		queryInfo.DDL = DDLInfo{Action: DDLRenameTable, Name: node.NewName.Name.String()}
+	case sqlparser.RenameColumnStr:
+		queryInfo.QueryType = ALTER_TABLE
+		queryInfo.DDL = DDLInfo{
+			Action:    DDLRenameColumn,
+			Column:    node.Column.String(),
+			NewColumn: TableColumnInfo{Name: node.NewColumn.String()},
+		}
+	case sqlparser.AddColumnStr:
+		queryInfo.QueryType = ALTER_TABLE
+		queryInfo.DDL = DDLInfo{Action: DDLAddColumn}
+		column, err := columnInfo(node.TableSpec.Columns[0])
+		if err != nil {
+			return err
+		}
+		queryInfo.DDL.NewColumn = column
+		columnType := node.TableSpec.Columns[0].Type
+		queryInfo.DDL.Constraints = ColumnConstraints{
+			PrimaryKey: columnType.KeyOpt == sqlparser.ColKeyPrimary,
+			Unique:     columnType.KeyOpt == sqlparser.ColKeyUnique,
+			NotNull:    bool(columnType.NotNull),
+		}
+	case sqlparser.DropColumnStr:
+		queryInfo.QueryType = ALTER_TABLE
+		queryInfo.DDL = DDLInfo{Action: DDLDropColumn, Column: node.Column.String()}
+	default:
+		return fmt.Errorf("DDLVisitor unsupported action type: %v", node.Action)
+	}
+	return nil
+}
+
+// columnInfo returns the name and the type of the column definition.
+func columnInfo(column *sqlparser.ColumnDefinition) (TableColumnInfo, error) {
+	// a column without any type has no affinity
+	if column.Type.Type == "" {
+		return TableColumnInfo{Name: column.Name.String(), Type: Null}, nil
+	}
+	fieldType, found := StringToFieldType(column.Type.Type)
+	if !found {
+		return TableColumnInfo{}, fmt.Errorf("unsupported column type: %v", column.Type.Type)
+	}
+	return TableColumnInfo{Name: column.Name.String(), Type: fieldType}, nil
+}
+
 func TableSchemaVisitor(node sqlparser.SQLNode, info *TableSchemaInfo) error {
 	parse, ok := node.(*sqlparser.DDL)
 	if !ok {
@@ -197,21 +423,89 @@ func TableSchemaVisitor(node sqlparser.SQLNode, info *TableSchemaInfo) error {
 	info.Name = parse.NewName.Name.String()
 
 	for i, column := range parse.TableSpec.Columns {
-		fieldType, found := StringToFieldType(column.Type.Type)
-		if !found {
-			return fmt.Errorf("TableSchemaVisitor unsupported column type: %T", column.Type.Type)
-		}
-		columnInfo := TableColumnInfo{
-			Idx:  int64(i),
-			Name: column.Name.String(),
-			Type: fieldType,
+		columnInfo, err := columnInfo(column)
+		if err != nil {
+			return err
 		}
+		columnInfo.Idx = int64(i)
 
-		if column.Type.KeyOpt == sqlparser.ColKeyPrimary {
+		switch column.Type.KeyOpt {
+		case sqlparser.ColKeyPrimary:
+			if info.HasPrimaryKey() {
+				return fmt.Errorf("table %q has more than one primary key", info.Name)
+			}
 			info.PrimaryKey = columnInfo
+			info.Constraints = append(info.Constraints, UniqueConstraint{
+				PrimaryKey: true,
+				Columns:    []IndexColumnInfo{{Name: columnInfo.Name}},
+			})
+		case sqlparser.ColKeyUnique:
+			info.Constraints = append(info.Constraints, UniqueConstraint{Columns: []IndexColumnInfo{{Name: columnInfo.Name}}})
+		}
+		if column.Type.Autoincrement {
+			if column.Type.KeyOpt != sqlparser.ColKeyPrimary || fieldTypeMapping[columnInfo.Type] != "INTEGER" {
+				return fmt.Errorf("AUTOINCREMENT is only allowed on an INTEGER PRIMARY KEY")
+			}
+			info.IsAutoIncrement = true
+			info.AutoIncrementColumn = uint64(i)
 		}
 		info.Columns = append(info.Columns, columnInfo)
 
 	}
+
+	for _, tableConstraint := range parse.TableSpec.Constraints {
+		constraint := UniqueConstraint{PrimaryKey: bool(tableConstraint.PrimaryKey)}
+		for _, indexColumn := range tableConstraint.Columns {
+			column, ok := info.Column(indexColumn.Column.String())
+			if !ok {
+				return fmt.Errorf("no such column: %s", indexColumn.Column.String())
+			}
+			constraint.Columns = append(constraint.Columns, IndexColumnInfo{Name: column.Name, Desc: bool(indexColumn.Desc)})
+		}
+		if constraint.PrimaryKey {
+			if info.HasPrimaryKey() || info.hasCompositeKey() {
+				return fmt.Errorf("table %q has more than one primary key", info.Name)
+			}
+			// a single column is the PRIMARY KEY column, INTEGER whatever its order makes it the rowid
+			if len(constraint.Columns) == 1 {
+				info.PrimaryKey, _ = info.Column(constraint.Columns[0].Name)
+			}
+		}
+		info.Constraints = append(info.Constraints, constraint)
+	}
+
+	info.WithoutRowID = bool(parse.TableSpec.WithoutRowID)
+	if info.WithoutRowID {
+		if info.hasCompositeKey() {
+			return fmt.Errorf("a PRIMARY KEY of several columns isn't supported on WITHOUT ROWID tables")
+		}
+		if !info.HasPrimaryKey() {
+			return fmt.Errorf("PRIMARY KEY missing on table %s", info.Name)
+		}
+		if info.IsAutoIncrement {
+			return fmt.Errorf("AUTOINCREMENT not allowed on WITHOUT ROWID tables")
+		}
+	}
+	return nil
+}
+
+func IndexSchemaVisitor(node sqlparser.SQLNode, info *IndexSchemaInfo) error {
+	parse, ok := node.(*sqlparser.DDL)
+	if !ok {
+		return fmt.Errorf("IndexSchemaVisitor unsupported node type: %T", node)
+	}
+	if parse.Action != sqlparser.CreateIndexStr {
+		return fmt.Errorf("IndexSchemaVisitor unsupported action type: %v", parse.Action)
+	}
+	info.Name = parse.IndexSpec.Name.String()
+	info.TableName = parse.Table.Name.String()
+	info.Unique = bool(parse.IndexSpec.Unique)
+
+	for _, column := range parse.IndexSpec.Columns {
+		info.Columns = append(info.Columns, IndexColumnInfo{
+			Name: column.Column.String(),
+			Desc: bool(column.Desc),
+		})
+	}
 	return nil
 }
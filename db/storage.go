@@ -2,41 +2,165 @@ package db
 
 import (
 	"fmt"
+	"strings"
+
 	"github.com/adzimzf/sqlite-go/sql"
-	"os"
+	"github.com/adzimzf/sqlite-go/vfs"
 )
 
 type DB struct {
 	header *DatabaseHeader
-	file   *os.File
+	file   vfs.File
+	pager  *Pager
+	// nextAutoVacuum is the auto-vacuum mode the next VACUUM gives to the database, see SetAutoVacuum
+	nextAutoVacuum *AutoVacuumMode
 }
 
-func NewDB(file *os.File) (*DB, error) {
-	databaseHeader, err := ReadDatabaseHeader(file)
+// Open opens the database name from the storage fs.
+func Open(fs vfs.VFS, name string, options PagerOptions) (*DB, error) {
+	file, err := fs.Open(name, vfs.OpenReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	database, err := NewDBWithOptions(file, options)
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
+	return database, nil
+}
 
-	return &DB{header: databaseHeader, file: file}, nil
+func NewDB(file vfs.File) (*DB, error) {
+	return NewDBWithOptions(file, PagerOptions{})
 }
 
-func (d *DB) FindSQLiteMaster() (*TableLeafPage, error) {
-	rootPage, err := NewTableLeafPage(d.file, int(d.header.PageSize), 1)
+// NewDBWithOptions opens the database with a custom page cache size.
+// A hot journal left behind by a crash is played back first, it requires a writable file.
+// A database in WAL mode is read through the committed frames of its -wal file.
+func NewDBWithOptions(file vfs.File, options PagerOptions) (*DB, error) {
+	journal := newJournal(file)
+	if journal != nil {
+		if err := journal.recover(file); err != nil {
+			return nil, err
+		}
+	}
+
+	databaseHeader, err := ReadDatabaseHeader(file)
 	if err != nil {
 		return nil, err
 	}
-	return rootPage, nil
+
+	var log *wal
+	if databaseHeader.FileFormatRead == 2 {
+		if log, err = openWAL(file, int(databaseHeader.PageSize)); err != nil {
+			return nil, err
+		}
+		if databaseHeader, err = log.databaseHeader(file); err != nil {
+			log.close()
+			return nil, err
+		}
+	}
+
+	pager := NewPager(file, databaseHeader, options)
+	pager.journal = journal
+	pager.wal = log
+	return &DB{
+		header: databaseHeader,
+		file:   file,
+		pager:  pager,
+	}, nil
+}
+
+// Close closes the database file.
+func (d *DB) Close() error {
+	if d.pager.wal != nil {
+		d.pager.wal.close()
+	}
+	return d.file.Close()
+}
+
+// Begin starts a write transaction.
+func (d *DB) Begin() error {
+	return d.pager.Begin()
+}
+
+// BeginRead starts a read transaction, the rows read until EndRead are the ones of the last commit
+// when it started.
+func (d *DB) BeginRead() error {
+	return d.pager.BeginRead()
+}
+
+// EndRead ends the read transaction.
+func (d *DB) EndRead() {
+	d.pager.EndRead()
+}
+
+// Commit writes the changes of the transaction to the database file.
+func (d *DB) Commit() error {
+	return d.pager.Commit()
+}
+
+// Rollback drops the changes of the transaction.
+func (d *DB) Rollback() error {
+	return d.pager.Rollback()
 }
 
-func (d *DB) FindPageID(pageID int) (*TableLeafPage, error) {
-	rootPage, err := NewTableLeafPage(d.file, int(d.header.PageSize), pageID)
+// JournalMode returns the journal mode of the database.
+func (d *DB) JournalMode() JournalMode {
+	return d.pager.JournalMode()
+}
+
+// SetJournalMode switches the database between the rollback journal and the WAL modes.
+func (d *DB) SetJournalMode(mode JournalMode) error {
+	return d.pager.SetJournalMode(mode)
+}
+
+// Checkpoint copies the frames of the log of a database in WAL mode to the database file.
+func (d *DB) Checkpoint(mode CheckpointMode) (CheckpointResult, error) {
+	return d.pager.Checkpoint(mode)
+}
+
+// Header returns the database header read when the database has been opened.
+func (d *DB) Header() *DatabaseHeader {
+	return d.header
+}
+
+// CacheStats returns the page cache hits, misses and evictions since the database has been opened.
+func (d *DB) CacheStats() CacheStats {
+	return d.pager.CacheStats()
+}
+
+// FindSQLiteMaster returns the sqlite_master table b-tree which is always rooted at page 1.
+func (d *DB) FindSQLiteMaster() (*TableBTree, error) {
+	return d.FindPageID(1)
+}
+
+// FindPageID returns the table b-tree rooted at pageID.
+func (d *DB) FindPageID(pageID int) (*TableBTree, error) {
+	if pageID < 1 {
+		return nil, fmt.Errorf("invalid root page: %d", pageID)
+	}
+	return NewTableBTree(d.pager, pageID), nil
+}
+
+// TableCursor returns a cursor over the rows of the table, the records of a WITHOUT ROWID table
+// have their fields in the stored order, see TableSchemaInfo.TableRecord.
+func (d *DB) TableCursor(schema TableSchemaInfo) (*BTreeCursor, error) {
+	if schema.WithoutRowID {
+		table, err := d.FindWithoutRowIDTable(schema)
+		if err != nil {
+			return nil, err
+		}
+		return table.Cursor(), nil
+	}
+	tree, err := d.FindPageID(int(schema.PageID))
 	if err != nil {
 		return nil, err
 	}
-	return rootPage, nil
+	return tree.Cursor(), nil
 }
 
-func (d *DB) FindTablePage(name string) (*TableLeafPage, error) {
+func (d *DB) FindTablePage(name string) (*TableBTree, error) {
 
 	sqLiteMaster, err := d.FindSQLiteMaster()
 	if err != nil {
@@ -59,16 +183,11 @@ func (d *DB) FindTablePage(name string) (*TableLeafPage, error) {
 		}
 
 		if data.(string) == name {
-			pageNumber, _, err := record.FieldData(3)
-			if err != nil {
-				return nil, err
-			}
-
-			leafPage, err := d.FindPageID(int(pageNumber.(int8)))
+			pageNumber, err := record.IntField(3)
 			if err != nil {
 				return nil, err
 			}
-			return leafPage, nil
+			return d.FindPageID(int(pageNumber))
 
 		}
 	}
@@ -105,13 +224,13 @@ func (d *DB) FindTableSchema(tableName string) (TableSchemaInfo, error) {
 			if err != nil {
 				return TableSchemaInfo{}, err
 			}
-			rootPage, _, err := record.FieldData(3)
+			rootPage, err := record.IntField(3)
 			if err != nil {
 				return TableSchemaInfo{}, err
 			}
 
 			tableSchema := TableSchemaInfo{
-				PageID: int64(rootPage.(int8)),
+				PageID: rootPage,
 				RawSQL: rawQuery.(string),
 			}
 
@@ -149,3 +268,137 @@ func (d *DB) FindSQLiteSchema() (TableSchemaInfo, error) {
 
 	return tableSchema, nil
 }
+
+// FindIndex returns the index b-tree of the index name found in sqlite_master.
+func (d *DB) FindIndex(name string) (*IndexBTree, error) {
+	indexSchema, err := d.FindIndexSchema(name)
+	if err != nil {
+		return nil, err
+	}
+	tableSchema, err := d.FindTableSchema(indexSchema.TableName)
+	if err != nil {
+		return nil, err
+	}
+	return d.newIndexBTree(indexSchema, tableSchema)
+}
+
+// FindTableIndexes returns the index b-trees of every index of the table.
+func (d *DB) FindTableIndexes(tableName string) ([]*IndexBTree, error) {
+	indexSchemas, err := d.indexSchemas()
+	if err != nil {
+		return nil, err
+	}
+	var indexes []*IndexBTree
+	var tableSchema *TableSchemaInfo
+	for _, indexSchema := range indexSchemas {
+		if indexSchema.TableName != tableName {
+			continue
+		}
+		if tableSchema == nil {
+			schema, err := d.FindTableSchema(tableName)
+			if err != nil {
+				return nil, err
+			}
+			tableSchema = &schema
+		}
+		index, err := d.newIndexBTree(indexSchema, *tableSchema)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// newIndexBTree returns the b-tree of the index of the table.
+func (d *DB) newIndexBTree(indexSchema IndexSchemaInfo, tableSchema TableSchemaInfo) (*IndexBTree, error) {
+	index := NewIndexBTree(d.pager, int(indexSchema.PageID))
+	index.Name = indexSchema.Name
+	index.TableName = indexSchema.TableName
+	index.Unique = indexSchema.Unique
+	index.Columns = indexSchema.Columns
+	index.withoutRowID = tableSchema.WithoutRowID
+	if indexSchema.RawSQL != "" {
+		return index, nil
+	}
+
+	// the automatic index of a PRIMARY KEY or of a UNIQUE constraint
+	for _, autoIndex := range tableSchema.AutoIndexes() {
+		if strings.EqualFold(autoIndex.Name, indexSchema.Name) {
+			index.Unique = true
+			index.Columns = autoIndex.Columns
+		}
+	}
+	return index, nil
+}
+
+func (d *DB) FindIndexSchema(name string) (IndexSchemaInfo, error) {
+	indexSchemas, err := d.indexSchemas()
+	if err != nil {
+		return IndexSchemaInfo{}, err
+	}
+	for _, indexSchema := range indexSchemas {
+		if indexSchema.Name == name {
+			return indexSchema, nil
+		}
+	}
+	return IndexSchemaInfo{}, fmt.Errorf("index not found: %v", name)
+}
+
+// indexSchemas returns every index found in sqlite_master.
+func (d *DB) indexSchemas() ([]IndexSchemaInfo, error) {
+	sqliteMaster, err := d.FindSQLiteMaster()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := sqliteMaster.GetRecords()
+	if err != nil {
+		return nil, err
+	}
+	var indexSchemas []IndexSchemaInfo
+	for _, record := range records {
+		objectType, err := record.Value(0)
+		if err != nil {
+			return nil, err
+		}
+		if objectType != "index" {
+			continue
+		}
+		objectName, err := record.Value(1)
+		if err != nil {
+			return nil, err
+		}
+		tableName, err := record.Value(2)
+		if err != nil {
+			return nil, err
+		}
+		rootPage, err := record.IntField(3)
+		if err != nil {
+			return nil, err
+		}
+		indexSchema := IndexSchemaInfo{
+			PageID:    rootPage,
+			Name:      fmt.Sprint(objectName),
+			TableName: fmt.Sprint(tableName),
+		}
+
+		// the automatic indexes don't have any sql
+		rawQuery, err := record.Value(4)
+		if err != nil {
+			return nil, err
+		}
+		if rawSQL, ok := rawQuery.(string); ok {
+			indexSchema.RawSQL = rawSQL
+			parse, err := sql.Parse(rawSQL)
+			if err != nil {
+				return nil, err
+			}
+			if err := IndexSchemaVisitor(parse, &indexSchema); err != nil {
+				return nil, err
+			}
+		}
+		indexSchemas = append(indexSchemas, indexSchema)
+	}
+	return indexSchemas, nil
+}
@@ -1,14 +1,42 @@
 package db
 
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
 type DDLAction int
 
 const (
 	DDLCreateTable DDLAction = iota
+	DDLDropTable
+	DDLDropIndex
+	DDLRenameTable
+	DDLRenameColumn
+	DDLAddColumn
+	DDLDropColumn
 )
 
 type DDLInfo struct {
-	Action DDLAction
-	Table  TableSchemaInfo
+	Action      DDLAction
+	Table       TableSchemaInfo   // CREATE TABLE
+	IfNotExists bool              // CREATE TABLE IF NOT EXISTS
+	IfExists    bool              // DROP TABLE, DROP INDEX
+	Name        string            // DROP INDEX, the new name of RENAME TO
+	Column      string            // RENAME COLUMN, DROP COLUMN
+	NewColumn   TableColumnInfo   // ADD COLUMN, only the name is set for RENAME COLUMN
+	Constraints ColumnConstraints // ADD COLUMN, the constraints of the new column
+	ColumnSQL   string            // ADD COLUMN, the definition of the new column as written
+}
+
+// ColumnConstraints are the constraints of a column added by ALTER TABLE ADD COLUMN,
+// the ones it can't add are kept so that it can reject them.
+type ColumnConstraints struct {
+	PrimaryKey bool
+	Unique     bool
+	NotNull    bool
 }
 
 type TableSchemaInfo struct {
@@ -19,6 +47,37 @@ type TableSchemaInfo struct {
 	Name                string
 	PrimaryKey          TableColumnInfo
 	Columns             []TableColumnInfo
+	// WithoutRowID tables are stored in an index b-tree keyed by the PRIMARY KEY,
+	// its column is stored first in the records followed by the other columns.
+	WithoutRowID bool
+	// Constraints are the PRIMARY KEY and UNIQUE constraints in the order they're written,
+	// the column ones first. PrimaryKey is only set for a PRIMARY KEY of a single column.
+	Constraints []UniqueConstraint
+}
+
+// UniqueConstraint is a PRIMARY KEY or a UNIQUE constraint, sqlite keeps each of them
+// in an automatic index unless it's the rowid.
+type UniqueConstraint struct {
+	PrimaryKey bool
+	Columns    []IndexColumnInfo
+}
+
+// hasColumn reports whether the column is one of the columns of the constraint.
+func (constraint UniqueConstraint) hasColumn(name string) bool {
+	for _, column := range constraint.Columns {
+		if strings.EqualFold(column.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoIndex is the automatic index of a PRIMARY KEY or of a UNIQUE constraint.
+// The PRIMARY KEY of a WITHOUT ROWID table is the table b-tree itself, it doesn't have
+// any b-tree or any row in sqlite_master.
+type AutoIndex struct {
+	Name string
+	UniqueConstraint
 }
 
 func (info TableSchemaInfo) ColumnIndex(fields ...string) []int64 {
@@ -37,8 +96,257 @@ func (info TableSchemaInfo) HasPrimaryKey() bool {
 	return info.PrimaryKey != (TableColumnInfo{})
 }
 
+// kind returns the name of the constraint of the index.
+func (index AutoIndex) kind() string {
+	if index.PrimaryKey {
+		return "PRIMARY KEY"
+	}
+	return "UNIQUE"
+}
+
+// constraintColumns returns the table followed by the columns of the constraint like t(a, b).
+func (info TableSchemaInfo) constraintColumns(constraint UniqueConstraint) string {
+	names := make([]string, len(constraint.Columns))
+	for i, column := range constraint.Columns {
+		names[i] = column.Name
+	}
+	return info.Name + "(" + strings.Join(names, ", ") + ")"
+}
+
+// hasCompositeKey reports whether the PRIMARY KEY has several columns.
+func (info TableSchemaInfo) hasCompositeKey() bool {
+	for _, constraint := range info.Constraints {
+		if constraint.PrimaryKey && len(constraint.Columns) > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// AutoIndexes returns the automatic indexes of the constraints named the way sqlite does:
+// sqlite_autoindex_<table>_<n> numbered in the order of the constraints. A constraint on the same
+// columns as a previous one shares its index and the INTEGER PRIMARY KEY doesn't have any as it's
+// the rowid, but the INTEGER PRIMARY KEY of a WITHOUT ROWID table comes last.
+// See https://www.sqlite.org/fileformat2.html#representation_of_sql_indices
+func (info TableSchemaInfo) AutoIndexes() []AutoIndex {
+	var indexes []AutoIndex
+	add := func(constraint UniqueConstraint) {
+		for i, index := range indexes {
+			if sameIndexColumns(index.Columns, constraint.Columns) {
+				indexes[i].PrimaryKey = index.PrimaryKey || constraint.PrimaryKey
+				return
+			}
+		}
+		indexes = append(indexes, AutoIndex{
+			Name:             fmt.Sprintf("sqlite_autoindex_%s_%d", info.Name, len(indexes)+1),
+			UniqueConstraint: constraint,
+		})
+	}
+
+	var integerKey *UniqueConstraint
+	for i, constraint := range info.Constraints {
+		if constraint.PrimaryKey && len(constraint.Columns) == 1 && fieldTypeMapping[info.PrimaryKey.Type] == "INTEGER" {
+			integerKey = &info.Constraints[i]
+			continue
+		}
+		add(constraint)
+	}
+	if integerKey != nil && info.WithoutRowID {
+		add(*integerKey)
+	}
+	return indexes
+}
+
+// sameIndexColumns reports whether both indexes have the same columns in the same order.
+func sameIndexColumns(a, b []IndexColumnInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i].Name, b[i].Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// RowIDColumn returns the INTEGER PRIMARY KEY column, it's an alias of the rowid
+// so its value isn't stored in the record but as the rowid. A WITHOUT ROWID table doesn't have any.
+func (info TableSchemaInfo) RowIDColumn() (TableColumnInfo, bool) {
+	if info.WithoutRowID || !info.HasPrimaryKey() || fieldTypeMapping[info.PrimaryKey.Type] != "INTEGER" {
+		return TableColumnInfo{}, false
+	}
+	return info.PrimaryKey, true
+}
+
+// Column returns the column with the name, the names are case insensitive.
+func (info TableSchemaInfo) Column(name string) (TableColumnInfo, bool) {
+	for _, column := range info.Columns {
+		if strings.EqualFold(column.Name, name) {
+			return column, true
+		}
+	}
+	return TableColumnInfo{}, false
+}
+
+// IndexKey returns the values of the index columns of the row followed by the rowid, or by the
+// PRIMARY KEY of a WITHOUT ROWID table unless it's one of the index columns.
+func (info TableSchemaInfo) IndexKey(index *IndexBTree, row []any, rowID int64) ([]any, error) {
+	if len(index.Columns) == 0 {
+		return nil, fmt.Errorf("index %s columns are unknown", index.Name)
+	}
+	key := make([]any, 0, len(index.Columns)+1)
+	for _, indexColumn := range index.Columns {
+		column, ok := info.Column(indexColumn.Name)
+		if !ok {
+			return nil, fmt.Errorf("index %s column %s doesn't exist", index.Name, indexColumn.Name)
+		}
+		value := row[column.Idx]
+		if rowIDColumn, ok := info.RowIDColumn(); ok && rowIDColumn.Idx == column.Idx {
+			value = rowID
+		}
+		key = append(key, value)
+	}
+	if !info.WithoutRowID {
+		return append(key, rowID), nil
+	}
+	for _, indexColumn := range index.Columns {
+		if strings.EqualFold(indexColumn.Name, info.PrimaryKey.Name) {
+			return key, nil
+		}
+	}
+	return append(key, row[info.PrimaryKey.Idx]), nil
+}
+
+// storedIndex returns the position of the column in the records of the table.
+func (info TableSchemaInfo) storedIndex(column int64) int64 {
+	if !info.WithoutRowID {
+		return column
+	}
+	switch {
+	case column == info.PrimaryKey.Idx:
+		return 0
+	case column < info.PrimaryKey.Idx:
+		return column + 1
+	}
+	return column
+}
+
+// StoredRow returns the values of the row in the order they're stored in the records of the table.
+func (info TableSchemaInfo) StoredRow(row []any) []any {
+	if !info.WithoutRowID {
+		return row
+	}
+	stored := make([]any, len(row))
+	for i, value := range row {
+		stored[info.storedIndex(int64(i))] = value
+	}
+	return stored
+}
+
+// TableRecord returns the record of the table with its fields in the order of the columns,
+// the columns added after the record has been written are NULL.
+func (info TableSchemaInfo) TableRecord(record Record) Record {
+	if !info.WithoutRowID {
+		return record
+	}
+	fields := make([]RecordField, len(info.Columns))
+	for i := range fields {
+		stored := info.storedIndex(int64(i))
+		if stored < int64(len(record.Header.Fields)) {
+			fields[i] = record.Header.Fields[stored]
+		} else {
+			fields[i] = RecordField{FieldType: Null}
+		}
+		fields[i].FieldIdx = int64(i)
+	}
+	record.Header.Fields = fields
+	return record
+}
+
 type TableColumnInfo struct {
 	Idx  int64
 	Name string
 	Type FieldType
 }
+
+// ApplyAffinity converts the value to the storage class preferred by the column type,
+// the way sqlite does before storing a value.
+// See https://www.sqlite.org/datatype3.html#type_affinity
+func (c TableColumnInfo) ApplyAffinity(value any) any {
+	value = normalizeValue(value)
+	switch fieldTypeMapping[c.Type] {
+	case "INTEGER":
+		return integerAffinity(value)
+	case "TEXT":
+		switch v := value.(type) {
+		case int64:
+			return strconv.FormatInt(v, 10)
+		case float64:
+			return formatFloat(v)
+		}
+	}
+	return value
+}
+
+// integerAffinity converts the text looking like a number to a number and the reals without
+// a fractional part to integers.
+func integerAffinity(value any) any {
+	if s, ok := value.(string); ok {
+		text := strings.TrimSpace(s)
+		if !isNumericText(text) {
+			return s
+		}
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i
+		}
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return s
+		}
+		value = f
+	}
+	if f, ok := value.(float64); ok && f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		return int64(f)
+	}
+	return value
+}
+
+// isNumericText reports whether the text is a decimal integer or real literal.
+func isNumericText(text string) bool {
+	if text == "" {
+		return false
+	}
+	digits := false
+	for i, ch := range text {
+		switch {
+		case ch >= '0' && ch <= '9':
+			digits = true
+		case ch == '+' || ch == '-':
+			if i != 0 && text[i-1] != 'e' && text[i-1] != 'E' {
+				return false
+			}
+		case ch == '.' || ch == 'e' || ch == 'E':
+		default:
+			return false
+		}
+	}
+	return digits
+}
+
+// IndexSchemaInfo describes an index found in sqlite_master.
+// The automatic indexes created for UNIQUE and PRIMARY KEY constraints don't have any SQL,
+// their columns are left empty, they're resolved from the constraints of the table.
+type IndexSchemaInfo struct {
+	PageID    int64
+	RawSQL    string
+	Name      string
+	TableName string
+	Unique    bool
+	Columns   []IndexColumnInfo
+}
+
+type IndexColumnInfo struct {
+	Name string
+	Desc bool
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"unicode/utf16"
+)
+
+// byteOrder returns the byte order of the UTF-16 encodings, nil for UTF-8.
+func (e TextEncoding) byteOrder() binary.ByteOrder {
+	switch e {
+	case EncodingUTF16le:
+		return binary.LittleEndian
+	case EncodingUTF16be:
+		return binary.BigEndian
+	default:
+		return nil
+	}
+}
+
+// Decode converts a text value stored in the encoding to a Go string.
+// A database which doesn't set its encoding yet is treated as UTF-8.
+func (e TextEncoding) Decode(data []byte) string {
+	order := e.byteOrder()
+	if order == nil {
+		return string(data)
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// Encode converts a Go string to the bytes stored in the encoding.
+func (e TextEncoding) Encode(s string) []byte {
+	order := e.byteOrder()
+	if order == nil {
+		return []byte(s)
+	}
+
+	units := utf16.Encode([]rune(s))
+	data := make([]byte, len(units)*2)
+	for i, unit := range units {
+		order.PutUint16(data[i*2:], unit)
+	}
+	return data
+}
+
+// Compare compares two strings with the BINARY collation, which compares the encoded bytes.
+// The UTF-16 order differs from the UTF-8 one for the characters outside of the basic multilingual plane
+// and, in little-endian, for every character above U+00FF.
+func (e TextEncoding) Compare(a, b string) int {
+	if e.byteOrder() == nil {
+		return strings.Compare(a, b)
+	}
+	return bytes.Compare(e.Encode(a), e.Encode(b))
+}
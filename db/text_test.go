@@ -0,0 +1,24 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextEncoding(t *testing.T) {
+	require.Equal(t, []byte{'h', 0, 0xe9, 0}, EncodingUTF16le.Encode("hé"))
+	require.Equal(t, []byte{0, 'h', 0, 0xe9}, EncodingUTF16be.Encode("hé"))
+	require.Equal(t, []byte("hé"), EncodingUTF8.Encode("hé"))
+
+	for _, encoding := range []TextEncoding{0, EncodingUTF8, EncodingUTF16le, EncodingUTF16be} {
+		for _, s := range []string{"", "abc", "héllo", "日本", "😀 emoji"} {
+			require.Equal(t, s, encoding.Decode(encoding.Encode(s)), "%s %q", encoding, s)
+		}
+	}
+
+	// the BINARY collation compares the stored bytes
+	require.Negative(t, EncodingUTF8.Compare("ｚ", "😀"))
+	require.Positive(t, EncodingUTF16be.Compare("ｚ", "😀"))
+	require.Negative(t, EncodingUTF16le.Compare("ā", "b"))
+}
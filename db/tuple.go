@@ -2,7 +2,7 @@ package db
 
 import (
 	"fmt"
-	"log"
+	"math"
 	"strings"
 )
 
@@ -13,6 +13,8 @@ const (
 	TupleTypeString
 	TupleTypeInt8
 	TupleTypeNull
+	TupleTypeFloat64
+	TupleTypeBlob
 )
 
 type Tuple struct {
@@ -36,6 +38,48 @@ func NewNullTuple() *Tuple {
 	return &Tuple{typeID: TupleTypeNull}
 }
 
+func NewFloat64Tuple(value float64) *Tuple {
+	return &Tuple{value: value, typeID: TupleTypeFloat64}
+}
+
+func NewBlobTuple(value []byte) *Tuple {
+	return &Tuple{value: value, typeID: TupleTypeBlob}
+}
+
+// String formats the value the way the sqlite3 shell prints it.
+func (t *Tuple) String() string {
+	switch v := t.value.(type) {
+	case nil:
+		return "NULL"
+	case float64:
+		return formatFloat(v)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// formatFloat prints a float with 15 significant digits and always keeps a decimal point, e.g. 1.0 or 1.0e+20.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	s := fmt.Sprintf("%.15g", v)
+	if strings.Contains(s, ".") {
+		return s
+	}
+	if i := strings.IndexByte(s, 'e'); i >= 0 {
+		return s[:i] + ".0" + s[i:]
+	}
+	return s + ".0"
+}
+
 type RecordTuple []*Tuple
 
 type Rows []RecordTuple
@@ -43,38 +87,50 @@ type Rows []RecordTuple
 func RecordsToRows(records []Record, tableInfo TableSchemaInfo) (Rows, error) {
 	var rows Rows
 	for _, record := range records {
-		var recordTuple RecordTuple
-		for i := 0; i < len(record.Header.Fields); i++ {
-			data, fieldType, err := record.FieldData(i)
-			if err != nil {
-				return rows, nil
-			}
-			switch fieldType {
-			case Null:
-				// if it's null however the column as primary id and auto increment
-				// the value equal to RowID
-				if tableInfo.HasPrimaryKey() && record.Header.Fields[i].FieldIdx == tableInfo.PrimaryKey.Idx {
-					recordTuple = append(recordTuple, NewInt64Tuple(record.RowID))
-				} else {
-					recordTuple = append(recordTuple, NewNullTuple())
-				}
-			case Int8:
-				recordTuple = append(recordTuple, NewInt8Tuple(data.(int8)))
-			case Int64:
-				recordTuple = append(recordTuple, NewInt64Tuple(data.(int64)))
-			case String:
-				recordTuple = append(recordTuple, NewStringTuple(data.(string)))
-			default:
-				log.Printf("Unsupported field type %v", fieldType)
-			}
+		recordTuple, err := RecordToRow(record, tableInfo)
+		if err != nil {
+			return nil, err
 		}
 		rows = append(rows, recordTuple)
 	}
 	return rows, nil
 }
 
-func (r Rows) RowsString(selectedFields []*SelectFieldExpression) string {
+func RecordToRow(record Record, tableInfo TableSchemaInfo) (RecordTuple, error) {
+	var recordTuple RecordTuple
+	for i := 0; i < len(record.Header.Fields); i++ {
+		data, fieldType, err := record.FieldData(i)
+		if err != nil {
+			return nil, err
+		}
+		switch fieldType {
+		case Null:
+			// if it's null however the column as primary id and auto increment
+			// the value equal to RowID
+			if tableInfo.HasPrimaryKey() && record.Header.Fields[i].FieldIdx == tableInfo.PrimaryKey.Idx {
+				recordTuple = append(recordTuple, NewInt64Tuple(record.RowID))
+			} else {
+				recordTuple = append(recordTuple, NewNullTuple())
+			}
+		case Int8:
+			recordTuple = append(recordTuple, NewInt8Tuple(data.(int8)))
+		case Int16, Int24, Int32, Int48, Int64, Zero, One:
+			recordTuple = append(recordTuple, NewInt64Tuple(normalizeValue(data).(int64)))
+		case Float64:
+			recordTuple = append(recordTuple, NewFloat64Tuple(data.(float64)))
+		case String:
+			recordTuple = append(recordTuple, NewStringTuple(data.(string)))
+		case Blob:
+			recordTuple = append(recordTuple, NewBlobTuple(data.([]byte)))
+		default:
+			return nil, fmt.Errorf("unsupported field type %v", fieldType)
+		}
+	}
+	return recordTuple, nil
+}
 
+// HeaderString returns the selected field names separated by a comma.
+func HeaderString(selectedFields []*SelectFieldExpression) string {
 	res := strings.Builder{}
 	for i, field := range selectedFields {
 		if field.IsAgg {
@@ -90,18 +146,28 @@ func (r Rows) RowsString(selectedFields []*SelectFieldExpression) string {
 			res.WriteString(fmt.Sprintf(", "))
 		}
 	}
+	return res.String()
+}
+
+// String returns the row values separated by a comma.
+func (r RecordTuple) String() string {
+	res := strings.Builder{}
+	for i, tuple := range r {
+		res.WriteString(tuple.String())
+		if i < len(r)-1 {
+			res.WriteString(fmt.Sprintf(", "))
+		}
+	}
+	return res.String()
+}
+
+func (r Rows) RowsString(selectedFields []*SelectFieldExpression) string {
+
+	res := strings.Builder{}
+	res.WriteString(HeaderString(selectedFields))
 	res.WriteString("\n")
 	for _, row := range r {
-		for i, tuple := range row {
-			if tuple.value == nil {
-				res.WriteString("NULL")
-			} else {
-				res.WriteString(fmt.Sprintf("%v", tuple.value))
-			}
-			if i < len(row)-1 {
-				res.WriteString(fmt.Sprintf(", "))
-			}
-		}
+		res.WriteString(row.String())
 		res.WriteString("\n")
 	}
 	return res.String()
@@ -1,9 +1,11 @@
 package db
 
 // DatabaseHeader represents the SQLite database header structure
+// See https://www.sqlite.org/fileformat2.html#the_database_header
 type DatabaseHeader struct {
-	HeaderString        [100]byte
-	PageSize            uint16
+	HeaderString [100]byte
+	// PageSize is already converted, the value 1 stored in the file means 65536
+	PageSize            uint32
 	FileFormatWrite     byte
 	FileFormatRead      byte
 	Reserved1           byte
@@ -11,12 +13,48 @@ type DatabaseHeader struct {
 	MinEmbeddedPayload  byte
 	LeafPayloadFraction byte
 	FileChangeCounter   uint32
-	SizeOfPageCache     uint32
-	SchemaCookie        uint32
-	SchemaFormat        uint32
-	DefaultPageCache    byte
-	IncrementalVacuum   byte
-	ApplicationID       uint32
-	VersionValidFor     uint32
-	VersionUsed         uint32
+	// DatabaseSize is the number of pages, it's computed from the file size
+	// when the value stored in the header isn't valid anymore
+	DatabaseSize      uint32
+	FreelistTrunkPage uint32
+	FreelistCount     uint32
+	SchemaCookie      uint32
+	SchemaFormat      uint32
+	DefaultPageCache  uint32
+	// LargestRootPage is only set for the auto-vacuum databases
+	LargestRootPage   uint32
+	TextEncoding      TextEncoding
+	UserVersion       uint32
+	IncrementalVacuum uint32
+	ApplicationID     uint32
+	VersionValidFor   uint32
+	VersionUsed       uint32
+}
+
+// UsableSize is the size of a page without the reserved space at the end of the page.
+func (h *DatabaseHeader) UsableSize() int {
+	return int(h.PageSize) - int(h.Reserved1)
+}
+
+// TextEncoding is the encoding used by every text value of the database.
+type TextEncoding uint32
+
+const (
+	EncodingUTF8    TextEncoding = 1
+	EncodingUTF16le TextEncoding = 2
+	EncodingUTF16be TextEncoding = 3
+)
+
+func (e TextEncoding) String() string {
+	encodingNames := map[TextEncoding]string{
+		EncodingUTF8:    "utf8",
+		EncodingUTF16le: "utf16le",
+		EncodingUTF16be: "utf16be",
+	}
+
+	s, ok := encodingNames[e]
+	if ok {
+		return s
+	}
+	return "unknown"
 }
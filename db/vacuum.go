@@ -0,0 +1,206 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+)
+
+// Vacuum rebuilds the database: every table and index is copied in order to a new image of the
+// database, which then replaces the content of the database file in a single transaction.
+// The pages of the new image are densely packed, it has no freelist and the file shrinks to
+// the pages which are used. The rowids are kept. A change of the auto-vacuum mode waiting for
+// the database to be rebuilt is applied.
+// See https://www.sqlite.org/lang_vacuum.html
+func (d *DB) Vacuum() error {
+	if d.pager.InTransaction() {
+		return fmt.Errorf("cannot VACUUM from within a transaction")
+	}
+	// the RESERVED lock keeps the other connections from writing while the image is built
+	if err := d.pager.Begin(); err != nil {
+		return err
+	}
+	if err := d.vacuum(); err != nil {
+		d.pager.Rollback()
+		return err
+	}
+	return d.pager.Commit()
+}
+
+func (d *DB) vacuum() error {
+	image := vfs.NewMemFile(nil)
+	header, err := d.vacuumTo(image)
+	if err != nil {
+		return err
+	}
+
+	data := image.Bytes()
+	pageSize := d.pager.limits.pageSize
+	for pageNumber := uint32(1); pageNumber <= header.DatabaseSize; pageNumber++ {
+		if pageNumber == d.pager.pendingBytePage() {
+			continue
+		}
+		offset := int(pageNumber-1) * pageSize
+		if err := d.pager.Write(pageNumber, data[offset:offset+pageSize]); err != nil {
+			return err
+		}
+	}
+	// the journal mode and the change counter of the database are kept
+	d.pager.header.DatabaseSize = header.DatabaseSize
+	d.pager.header.FreelistTrunkPage = 0
+	d.pager.header.FreelistCount = 0
+	d.pager.header.SchemaCookie = header.SchemaCookie
+	d.pager.header.LargestRootPage = header.LargestRootPage
+	d.pager.header.IncrementalVacuum = header.IncrementalVacuum
+	return nil
+}
+
+// VacuumInto writes a rebuilt copy of the database to the file name of the storage of the database,
+// the file must not exist or be empty. The copy uses the rollback journal mode.
+// See https://www.sqlite.org/lang_vacuum.html#vacuuminto
+func (d *DB) VacuumInto(name string) error {
+	if d.pager.InTransaction() {
+		return fmt.Errorf("cannot VACUUM from within a transaction")
+	}
+	named, ok := d.file.(vfs.NamedFile)
+	if !ok || named.VFS() == nil {
+		return fmt.Errorf("cannot VACUUM INTO %s: the storage of the database is unknown", name)
+	}
+	file, err := named.VFS().Open(name, vfs.OpenReadWrite|vfs.OpenCreate)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	size, err := file.Size()
+	if err != nil {
+		return err
+	}
+	if size > 0 {
+		return fmt.Errorf("output file already exists: %s", name)
+	}
+	_, err = d.vacuumTo(file)
+	return err
+}
+
+// vacuumTo writes the new image of the database to the empty file and returns its header.
+// The sqlite_master records are copied in order, every b-tree they have is copied as soon
+// as its record is reached and the record takes the root page of the copy.
+func (d *DB) vacuumTo(file vfs.File) (*DatabaseHeader, error) {
+	header := *d.pager.header
+	header.FileFormatWrite, header.FileFormatRead = 1, 1
+	header.FileChangeCounter = 0
+	header.DatabaseSize = 1
+	header.FreelistTrunkPage = 0
+	header.FreelistCount = 0
+	header.SchemaCookie++
+	// the pointer-map pages are only added or removed when the database is rebuilt
+	mode := d.AutoVacuum()
+	if d.nextAutoVacuum != nil {
+		mode = *d.nextAutoVacuum
+	}
+	header.LargestRootPage = 0
+	header.IncrementalVacuum = 0
+	if mode != AutoVacuumNone {
+		header.LargestRootPage = 1
+	}
+	if mode == AutoVacuumIncremental {
+		header.IncrementalVacuum = 1
+	}
+
+	target := NewPager(file, &header, PagerOptions{})
+	if err := target.Begin(); err != nil {
+		return nil, err
+	}
+	if err := d.copySchema(target); err != nil {
+		target.Rollback()
+		return nil, err
+	}
+	if err := target.Commit(); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// copySchema creates sqlite_master in page 1 of the target and copies every object of the schema.
+func (d *DB) copySchema(target *Pager) error {
+	if err := target.initSchema(); err != nil {
+		return err
+	}
+
+	cursor := NewBTreeCursor(d.pager, 1)
+	defer cursor.Close()
+	err := cursor.First()
+	for ; err == nil && cursor.Valid(); err = cursor.Next() {
+		rowID, err := cursor.RowID()
+		if err != nil {
+			return err
+		}
+		record, err := cursor.Record()
+		if err != nil {
+			return err
+		}
+		values := make([]any, len(record.Header.Fields))
+		for i := range values {
+			if values[i], err = record.Value(i); err != nil {
+				return err
+			}
+		}
+
+		if len(values) != 5 {
+			return fmt.Errorf("sqlite_master row %d has %d columns", rowID, len(values))
+		}
+		// the views and the triggers don't have any b-tree
+		if rootPage, ok := values[3].(int64); ok && rootPage != 0 {
+			newRoot, err := copyBTree(d.pager, target, uint32(rootPage))
+			if err != nil {
+				return fmt.Errorf("cannot copy %v: %w", values[1], err)
+			}
+			values[3] = int64(newRoot)
+		}
+		payload, err := EncodeRecord(values, target.encoding)
+		if err != nil {
+			return err
+		}
+		if err := target.appendEntry(1, rowID, payload); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// copyBTree copies the entries of the b-tree rooted at the page of the source in order to a new
+// b-tree of the target and returns its root page.
+func copyBTree(source, target *Pager, rootPage uint32) (uint32, error) {
+	root, err := source.readBTreePage(rootPage)
+	if err != nil {
+		return 0, err
+	}
+	leafType := BTREE_LEAF_INDEX
+	if root.IsTable() {
+		leafType = BTREE_LEAF_TABLE
+	}
+	newRoot, err := target.CreateBTree(leafType)
+	if err != nil {
+		return 0, err
+	}
+
+	cursor := NewBTreeCursor(source, int(rootPage))
+	defer cursor.Close()
+	err = cursor.First()
+	for ; err == nil && cursor.Valid(); err = cursor.Next() {
+		var rowID int64
+		if leafType == BTREE_LEAF_TABLE {
+			if rowID, err = cursor.RowID(); err != nil {
+				return 0, err
+			}
+		}
+		payload, err := cursor.payload()
+		if err != nil {
+			return 0, err
+		}
+		if err := target.appendEntry(newRoot, rowID, payload); err != nil {
+			return 0, err
+		}
+	}
+	return newRoot, err
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVacuum(t *testing.T) {
+	original, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	fs := vfs.NewMemVFS()
+	fs.AddFile("sample.db", original)
+	file, err := fs.Open("sample.db", vfs.OpenReadWrite)
+	require.NoError(t, err)
+	database, err := NewDB(file)
+	require.NoError(t, err)
+	defer database.Close()
+
+	// the deleted rows leave the pages half empty and put pages on the freelist
+	tree, err := database.FindTablePage("oranges")
+	require.NoError(t, err)
+	require.NoError(t, database.Begin())
+	for rowID := int64(100); rowID < 2100; rowID++ {
+		record, err := EncodeRecord([]any{nil, strings.Repeat("o", int(rowID%300)), "juicy"}, EncodingUTF8)
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(rowID, record))
+	}
+	require.NoError(t, database.Commit())
+	require.NoError(t, database.Begin())
+	for rowID := int64(100); rowID < 2100; rowID++ {
+		if rowID%4 != 0 {
+			require.NoError(t, tree.Delete(rowID))
+		}
+	}
+	require.NoError(t, database.Commit())
+	want, err := tree.GetRecords()
+	require.NoError(t, err)
+	size := database.Header().DatabaseSize
+	require.Positive(t, database.Header().FreelistCount)
+
+	require.NoError(t, database.VacuumInto("copy.db"))
+	require.Error(t, database.VacuumInto("copy.db"), "the copy already exists")
+	require.NoError(t, database.Vacuum())
+	require.Less(t, database.Header().DatabaseSize, size/2)
+	require.Zero(t, database.Header().FreelistCount)
+	fileSize, err := file.Size()
+	require.NoError(t, err)
+	require.Equal(t, int64(database.Header().DatabaseSize*database.Header().PageSize), fileSize)
+
+	for _, name := range []string{"sample.db", "copy.db"} {
+		copied, err := Open(fs, name, PagerOptions{})
+		require.NoError(t, err)
+		require.Equal(t, database.Header().DatabaseSize, copied.Header().DatabaseSize)
+		tree, err := copied.FindTablePage("oranges")
+		require.NoError(t, err)
+		records, err := tree.GetRecords()
+		require.NoError(t, err)
+		require.Equal(t, want, records)
+		messages, err := copied.IntegrityCheck(DefaultIntegrityErrors, false)
+		require.NoError(t, err)
+		require.Empty(t, messages)
+		require.NoError(t, copied.Close())
+	}
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"errors"
+)
+
+// MaxVarintLen is the longest a sqlite varint can be.
+const MaxVarintLen = 9
+
+var errVarintTooShort = errors.New("buffer too small to contain varint")
+
+// GetVarint decodes the sqlite varint at the start of buf and returns its value and how many bytes it takes.
+// A varint is big-endian, the first 8 bytes hold 7 bits each with the high bit set when another byte follows,
+// the 9th byte holds 8 bits. The 64 bits are two's complement so a negative rowid takes all 9 bytes.
+// See https://www.sqlite.org/fileformat2.html#varint
+func GetVarint(buf []byte) (int64, int, error) {
+	var value uint64
+	for i := 0; i < MaxVarintLen; i++ {
+		if i >= len(buf) {
+			return 0, 0, errVarintTooShort
+		}
+		if i == MaxVarintLen-1 {
+			value = value<<8 | uint64(buf[i])
+			return int64(value), MaxVarintLen, nil
+		}
+		value = value<<7 | uint64(buf[i]&0x7f)
+		if buf[i]&0x80 == 0 {
+			return int64(value), i + 1, nil
+		}
+	}
+	return int64(value), MaxVarintLen, nil
+}
+
+// PutVarint encodes v into buf as a sqlite varint and returns the number of bytes written.
+// buf must hold at least VarintLen(v) bytes.
+func PutVarint(buf []byte, v int64) int {
+	value := uint64(v)
+	if value > 1<<56-1 {
+		// the 9th byte takes the low 8 bits, the 8 bytes before it 7 bits each
+		buf[8] = byte(value)
+		value >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(value&0x7f) | 0x80
+			value >>= 7
+		}
+		return MaxVarintLen
+	}
+
+	n := VarintLen(v)
+	for i := n - 1; i >= 0; i-- {
+		buf[i] = byte(value&0x7f) | 0x80
+		value >>= 7
+	}
+	buf[n-1] &= 0x7f
+	return n
+}
+
+// AppendVarint appends v encoded as a sqlite varint to buf.
+func AppendVarint(buf []byte, v int64) []byte {
+	var tmp [MaxVarintLen]byte
+	n := PutVarint(tmp[:], v)
+	return append(buf, tmp[:n]...)
+}
+
+// VarintLen returns how many bytes v takes as a sqlite varint.
+func VarintLen(v int64) int {
+	value := uint64(v)
+	if value > 1<<56-1 {
+		return MaxVarintLen
+	}
+	n := 1
+	for value >>= 7; value != 0; value >>= 7 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVarint(t *testing.T) {
+	tests := []struct {
+		value int64
+		bytes []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x81, 0x00}},
+		{240, []byte{0x81, 0x70}},
+		{16383, []byte{0xff, 0x7f}},
+		{16384, []byte{0x81, 0x80, 0x00}},
+		{1<<56 - 1, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}},
+		{1 << 56, []byte{0x80, 0xc0, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}},
+		{math.MaxInt64, []byte{0xbf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{-1, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{math.MinInt64, []byte{0xc0, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}},
+	}
+	for _, tt := range tests {
+		require.Equal(t, len(tt.bytes), VarintLen(tt.value), "length of %d", tt.value)
+
+		buf := make([]byte, MaxVarintLen)
+		n := PutVarint(buf, tt.value)
+		require.Equal(t, tt.bytes, buf[:n], "encoding of %d", tt.value)
+		require.Equal(t, tt.bytes, AppendVarint(nil, tt.value))
+
+		// trailing bytes must not be read
+		value, n, err := GetVarint(append(tt.bytes, 0xff, 0xff))
+		require.NoError(t, err)
+		require.Equal(t, tt.value, value)
+		require.Equal(t, len(tt.bytes), n)
+	}
+
+	_, _, err := GetVarint([]byte{0x81, 0x80})
+	require.Error(t, err)
+}
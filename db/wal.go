@@ -0,0 +1,681 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+
+	"github.com/adzimzf/sqlite-go/constant"
+	"github.com/adzimzf/sqlite-go/vfs"
+)
+
+const (
+	// walMagic starts the header of a WAL file, the least significant bit is set
+	// when the checksums are computed on big-endian words
+	walMagic         = 0x377f0682
+	walFormatVersion = 3007000
+	walHeaderSize    = 32
+	walFrameHeader   = 24
+)
+
+// walAutoCheckpoint is the number of frames above which a commit runs a passive checkpoint,
+// the default of sqlite's wal_autocheckpoint
+const walAutoCheckpoint = 1000
+
+// JournalMode is how a database makes its commits atomic.
+type JournalMode string
+
+const (
+	// JournalModeDelete saves the original pages to a rollback journal deleted by the commit
+	JournalModeDelete JournalMode = "delete"
+	// JournalModeWAL appends the new pages to the write-ahead log
+	JournalModeWAL JournalMode = "wal"
+)
+
+// CheckpointMode is how much a checkpoint waits for the other connections,
+// see https://www.sqlite.org/c3ref/wal_checkpoint_v2.html
+type CheckpointMode int
+
+const (
+	// CheckpointPassive copies as many frames as possible without waiting for any lock
+	CheckpointPassive CheckpointMode = iota
+	// CheckpointFull waits for the writer to finish and copies every frame
+	CheckpointFull
+	// CheckpointRestart is a full checkpoint after which the next writer restarts the log
+	CheckpointRestart
+	// CheckpointTruncate is a restart checkpoint which also truncates the log to zero bytes
+	CheckpointTruncate
+)
+
+// CheckpointResult is what sqlite's PRAGMA wal_checkpoint returns. Busy is set when the checkpoint
+// couldn't take its locks, the counts of frames are -1 when the database isn't in WAL mode.
+type CheckpointResult struct {
+	Busy               bool
+	LogFrames          int
+	CheckpointedFrames int
+}
+
+// wal is the write-ahead log of a database in WAL mode (header bytes 18 and 19 set to 2).
+// The committed transactions are appended to the log as frames holding the new version of the
+// pages, the database file only gets them when the log is checkpointed. A page is read from
+// the last frame holding it up to the last commit frame, from the database file otherwise.
+// See https://www.sqlite.org/fileformat2.html#the_write_ahead_log
+type wal struct {
+	fs   vfs.VFS
+	name string
+	// file is nil until the log exists
+	file     vfs.File
+	pageSize int
+
+	// the header of the log, the frames are only valid with the same salts
+	bigEndian     bool
+	checkpointSeq uint32
+	salt          [2]uint32
+
+	// the wal-index, sqlite shares it between the connections in the -shm file. Every connection
+	// reads it again from the log when the size or the header of the log changes, only the read
+	// marks and the checkpoint progress are shared, between the connections of the process
+	shared *walIndex
+	size   int64
+	header []byte
+	// frames maps a page number to the last committed frame holding it, the frames are numbered from 1
+	frames map[uint32]uint32
+	// pages is the page number of every frame up to the last commit frame
+	pages []uint32
+	// frameCount is the number of frames up to the last commit frame
+	frameCount uint32
+	// backfilled is the number of frames copied to the database file by a checkpoint
+	backfilled uint32
+	// databaseSize is the size of the database in pages after the last commit
+	databaseSize uint32
+	// checksum is the cumulative checksum of the last commit frame
+	checksum [2]uint32
+}
+
+// walIndex is the part of the wal-index shared by the connections of the process which opened the
+// same log. Only connections of the same process can use a database in WAL mode at the same time.
+type walIndex struct {
+	mu sync.Mutex
+	// connections is the number of connections using the index, it's dropped with the last one
+	connections int
+	// backfilled is the number of frames copied to the database file of the log started with salt
+	salt       [2]uint32
+	backfilled uint32
+	// marks is the end of the log seen by the snapshot of every connection in a transaction,
+	// the frames after a mark can't be checkpointed and the frames before it can't be overwritten
+	marks map[*wal]uint32
+}
+
+type walIndexKey struct {
+	fs   vfs.VFS
+	name string
+}
+
+var walIndexes = struct {
+	sync.Mutex
+	indexes map[walIndexKey]*walIndex
+}{indexes: map[walIndexKey]*walIndex{}}
+
+// acquireWALIndex returns the index shared by the connections of the log.
+func acquireWALIndex(fs vfs.VFS, name string) *walIndex {
+	walIndexes.Lock()
+	defer walIndexes.Unlock()
+	key := walIndexKey{fs: fs, name: name}
+	index, ok := walIndexes.indexes[key]
+	if !ok {
+		index = &walIndex{marks: map[*wal]uint32{}}
+		walIndexes.indexes[key] = index
+	}
+	index.connections++
+	return index
+}
+
+// releaseIndex drops the read mark of the connection and the index once no connection uses it.
+func (w *wal) releaseIndex() {
+	walIndexes.Lock()
+	defer walIndexes.Unlock()
+	w.shared.mu.Lock()
+	delete(w.shared.marks, w)
+	w.shared.mu.Unlock()
+	if w.shared.connections--; w.shared.connections == 0 {
+		delete(walIndexes.indexes, walIndexKey{fs: w.fs, name: w.name})
+	}
+}
+
+// openWAL opens the log of the database file and reads its index, the log is created by the
+// first commit when it doesn't exist. It returns nil when the database file doesn't know its name.
+func openWAL(file vfs.File, pageSize int) (*wal, error) {
+	fs, name, ok := companionFile(file, "-wal")
+	if !ok {
+		return nil, nil
+	}
+	w := &wal{fs: fs, name: name, pageSize: pageSize, frames: map[uint32]uint32{}}
+	w.shared = acquireWALIndex(fs, name)
+	w.salt = [2]uint32{rand.Uint32(), rand.Uint32()}
+	if err := w.open(false); err != nil {
+		w.releaseIndex()
+		return nil, err
+	}
+	if w.file == nil {
+		return w, nil
+	}
+	w.shared.mu.Lock()
+	err := w.readIndex()
+	w.shared.mu.Unlock()
+	if err != nil {
+		w.close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// open opens the log, read-only when it can't be written. The log is only created with create set.
+func (w *wal) open(create bool) error {
+	if w.file != nil {
+		return nil
+	}
+	flags := vfs.OpenReadWrite
+	if create {
+		flags |= vfs.OpenCreate
+	} else if exists, err := w.fs.Exists(w.name); err != nil || !exists {
+		return err
+	}
+	file, err := w.fs.Open(w.name, flags)
+	if err != nil && !create {
+		file, err = w.fs.Open(w.name, vfs.OpenReadOnly)
+	}
+	if err != nil {
+		return err
+	}
+	w.file = file
+	return nil
+}
+
+// readIndex reads the header and the frames of the log and maps every page to its last committed
+// frame. The frames are read until the first one which salts or checksum don't match, the frames
+// after the last commit frame belong to a transaction which hasn't committed and are ignored.
+// A log which header isn't valid is empty. The caller holds the lock of the shared index.
+func (w *wal) readIndex() error {
+	w.frames, w.pages = map[uint32]uint32{}, nil
+	w.frameCount, w.backfilled, w.databaseSize = 0, 0, 0
+	w.size, w.header = 0, nil
+
+	size, err := w.file.Size()
+	if err != nil {
+		return err
+	}
+	w.size = size
+	if size < walHeaderSize {
+		return nil
+	}
+	header := make([]byte, walHeaderSize)
+	if err := readFullAt(w.file, header, 0); err != nil {
+		return err
+	}
+	w.header = header
+	magic := binary.BigEndian.Uint32(header[0:4])
+	if magic&^1 != walMagic {
+		return nil
+	}
+	if version := binary.BigEndian.Uint32(header[4:8]); version != walFormatVersion {
+		return fmt.Errorf("unsupported WAL format version: %d", version)
+	}
+	if pageSize := int(binary.BigEndian.Uint32(header[8:12])); pageSize != w.pageSize {
+		return fmt.Errorf("the WAL page size %d doesn't match the database page size %d", pageSize, w.pageSize)
+	}
+	bigEndian := magic&1 == 1
+	s0, s1 := walChecksum(bigEndian, header[:24], 0, 0)
+	if s0 != binary.BigEndian.Uint32(header[24:28]) || s1 != binary.BigEndian.Uint32(header[28:32]) {
+		return nil
+	}
+	w.bigEndian = bigEndian
+	w.checkpointSeq = binary.BigEndian.Uint32(header[12:16])
+	w.salt = [2]uint32{binary.BigEndian.Uint32(header[16:20]), binary.BigEndian.Uint32(header[20:24])}
+	w.checksum = [2]uint32{s0, s1}
+
+	frameSize := int64(walFrameHeader + w.pageSize)
+	frame := make([]byte, frameSize)
+	pending := map[uint32]uint32{}
+	for frameNumber := uint32(1); walHeaderSize+int64(frameNumber)*frameSize <= size; frameNumber++ {
+		if err := readFullAt(w.file, frame, w.frameOffset(frameNumber)); err != nil {
+			return err
+		}
+		pageNumber := binary.BigEndian.Uint32(frame[0:4])
+		if pageNumber == 0 ||
+			binary.BigEndian.Uint32(frame[8:12]) != w.salt[0] || binary.BigEndian.Uint32(frame[12:16]) != w.salt[1] {
+			break
+		}
+		s0, s1 = walChecksum(w.bigEndian, frame[:8], s0, s1)
+		s0, s1 = walChecksum(w.bigEndian, frame[walFrameHeader:], s0, s1)
+		if s0 != binary.BigEndian.Uint32(frame[16:20]) || s1 != binary.BigEndian.Uint32(frame[20:24]) {
+			break
+		}
+
+		pending[pageNumber] = frameNumber
+		w.pages = append(w.pages, pageNumber)
+		if commitSize := binary.BigEndian.Uint32(frame[4:8]); commitSize != 0 {
+			for pageNumber, frameNumber := range pending {
+				w.frames[pageNumber] = frameNumber
+			}
+			pending = map[uint32]uint32{}
+			w.frameCount, w.databaseSize = frameNumber, commitSize
+			w.checksum = [2]uint32{s0, s1}
+		}
+	}
+	w.pages = w.pages[:w.frameCount]
+	if w.shared.salt == w.salt {
+		w.backfilled = min(w.shared.backfilled, w.frameCount)
+	}
+	return nil
+}
+
+// frameOffset returns where the frame starts in the log.
+func (w *wal) frameOffset(frameNumber uint32) int64 {
+	return walHeaderSize + int64(frameNumber-1)*int64(walFrameHeader+w.pageSize)
+}
+
+// readPage returns the last committed version of the page, ok is false when the log doesn't have it.
+func (w *wal) readPage(pageNumber uint32) (data []byte, ok bool, err error) {
+	frameNumber, ok := w.frames[pageNumber]
+	if !ok {
+		return nil, false, nil
+	}
+	data, err = w.readFrame(frameNumber)
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// readFrame returns the page held by the frame.
+func (w *wal) readFrame(frameNumber uint32) ([]byte, error) {
+	data := make([]byte, w.pageSize)
+	if err := readFullAt(w.file, data, w.frameOffset(frameNumber)+walFrameHeader); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// databaseHeader reads the database header of the last commit, from page 1 in the log when the log
+// has it and from the database file otherwise.
+func (w *wal) databaseHeader(file vfs.File) (*DatabaseHeader, error) {
+	header, err := ReadDatabaseHeader(file)
+	if err != nil || w == nil || w.frameCount == 0 {
+		return header, err
+	}
+	data, ok, err := w.readPage(1)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if header, err = decodeDatabaseHeader(data[:constant.HeaderSize]); err != nil {
+			return nil, err
+		}
+	}
+	header.DatabaseSize = w.databaseSize
+	return header, nil
+}
+
+// beginRead takes the snapshot of a transaction: the index is read again when another connection
+// changed the log since it has been read, and the read mark keeps the frames of the snapshot from
+// being checkpointed over or overwritten until endRead. It reports whether the index changed.
+func (w *wal) beginRead() (bool, error) {
+	w.shared.mu.Lock()
+	defer w.shared.mu.Unlock()
+	changed, err := w.stale()
+	if err != nil {
+		return false, err
+	}
+	if changed {
+		if err := w.readIndex(); err != nil {
+			return false, err
+		}
+	}
+	w.shared.marks[w] = w.frameCount
+	return changed, nil
+}
+
+// endRead drops the read mark of the snapshot.
+func (w *wal) endRead() {
+	w.shared.mu.Lock()
+	defer w.shared.mu.Unlock()
+	delete(w.shared.marks, w)
+}
+
+// stale reports whether another connection changed the log since the index has been read.
+func (w *wal) stale() (bool, error) {
+	if w.file == nil {
+		if err := w.open(false); err != nil || w.file == nil {
+			return false, err
+		}
+	}
+	size, err := w.file.Size()
+	if err != nil {
+		return false, err
+	}
+	var header []byte
+	if size >= walHeaderSize {
+		header = make([]byte, walHeaderSize)
+		if err := readFullAt(w.file, header, 0); err != nil {
+			return false, err
+		}
+	}
+	return size != w.size || !bytes.Equal(header, w.header), nil
+}
+
+// otherReaders reports whether another connection has a snapshot reading frames of the log,
+// the caller holds the lock of the shared index.
+func (w *wal) otherReaders() bool {
+	for reader, mark := range w.shared.marks {
+		if reader != w && mark > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// commit appends the pages of a transaction to the log, the last frame is the commit frame holding
+// the size of the database. The log is restarted from its beginning when every frame has been
+// checkpointed and no other connection reads from it, the new salts invalidate the frames left
+// from before.
+func (w *wal) commit(pages []*Page, databaseSize uint32) error {
+	if err := w.open(true); err != nil {
+		return err
+	}
+	w.shared.mu.Lock()
+	defer w.shared.mu.Unlock()
+	if w.shared.salt == w.salt {
+		w.backfilled = max(w.backfilled, min(w.shared.backfilled, w.frameCount))
+	}
+
+	// a restarted log has a new header with the next checkpoint sequence and new salts
+	var buf bytes.Buffer
+	offset := w.frameOffset(w.frameCount + 1)
+	restart := w.frameCount == 0 || (w.backfilled >= w.frameCount && !w.otherReaders())
+	bigEndian, salt, checksum := w.bigEndian, w.salt, w.checksum
+	var header []byte
+	if restart {
+		bigEndian, salt = false, [2]uint32{w.salt[0] + 1, rand.Uint32()}
+		header = make([]byte, walHeaderSize)
+		binary.BigEndian.PutUint32(header[0:4], walMagic)
+		binary.BigEndian.PutUint32(header[4:8], walFormatVersion)
+		binary.BigEndian.PutUint32(header[8:12], uint32(w.pageSize))
+		binary.BigEndian.PutUint32(header[12:16], w.checkpointSeq+1)
+		binary.BigEndian.PutUint32(header[16:20], salt[0])
+		binary.BigEndian.PutUint32(header[20:24], salt[1])
+		checksum[0], checksum[1] = walChecksum(bigEndian, header[:24], 0, 0)
+		binary.BigEndian.PutUint32(header[24:28], checksum[0])
+		binary.BigEndian.PutUint32(header[28:32], checksum[1])
+		buf.Write(header)
+		offset = 0
+	}
+
+	frameHeader := make([]byte, walFrameHeader)
+	for i, page := range pages {
+		binary.BigEndian.PutUint32(frameHeader[0:4], page.Number)
+		commitSize := uint32(0)
+		if i == len(pages)-1 {
+			commitSize = databaseSize
+		}
+		binary.BigEndian.PutUint32(frameHeader[4:8], commitSize)
+		binary.BigEndian.PutUint32(frameHeader[8:12], salt[0])
+		binary.BigEndian.PutUint32(frameHeader[12:16], salt[1])
+		checksum[0], checksum[1] = walChecksum(bigEndian, frameHeader[:8], checksum[0], checksum[1])
+		checksum[0], checksum[1] = walChecksum(bigEndian, page.Data, checksum[0], checksum[1])
+		binary.BigEndian.PutUint32(frameHeader[16:20], checksum[0])
+		binary.BigEndian.PutUint32(frameHeader[20:24], checksum[1])
+		buf.Write(frameHeader)
+		buf.Write(page.Data)
+	}
+	if _, err := w.file.WriteAt(buf.Bytes(), offset); err != nil {
+		return err
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+
+	if restart {
+		w.frames, w.pages = map[uint32]uint32{}, nil
+		w.frameCount, w.backfilled = 0, 0
+		w.bigEndian, w.salt, w.header = bigEndian, salt, header
+		w.checkpointSeq++
+		w.shared.salt, w.shared.backfilled = salt, 0
+	}
+	for i, page := range pages {
+		w.frames[page.Number] = w.frameCount + uint32(i) + 1
+		w.pages = append(w.pages, page.Number)
+	}
+	w.frameCount += uint32(len(pages))
+	w.databaseSize = databaseSize
+	w.checksum = checksum
+	size, err := w.file.Size()
+	w.size = size
+	return err
+}
+
+// checkpoint copies the last committed version of the pages which aren't in the database file yet
+// from the log to the database file, and truncates the database file to the size of the last commit.
+// The frames after the read mark of another connection are left in the log, the database file must
+// keep the pages of its snapshot. The log is synced first so a crash during the copy never loses a page.
+func (w *wal) checkpoint(file vfs.File) error {
+	w.shared.mu.Lock()
+	defer w.shared.mu.Unlock()
+	if w.shared.salt == w.salt {
+		w.backfilled = max(w.backfilled, min(w.shared.backfilled, w.frameCount))
+	}
+	last := w.frameCount
+	for reader, mark := range w.shared.marks {
+		if reader != w {
+			last = min(last, mark)
+		}
+	}
+	if w.backfilled >= last {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+
+	frames := map[uint32]uint32{}
+	for frameNumber := w.backfilled + 1; frameNumber <= last; frameNumber++ {
+		frames[w.pages[frameNumber-1]] = frameNumber
+	}
+	for pageNumber, frameNumber := range frames {
+		data, err := w.readFrame(frameNumber)
+		if err != nil {
+			return err
+		}
+		if _, err := file.WriteAt(data, int64(pageNumber-1)*int64(w.pageSize)); err != nil {
+			return err
+		}
+	}
+	if last == w.frameCount {
+		if err := file.Truncate(int64(w.databaseSize) * int64(w.pageSize)); err != nil {
+			return err
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	w.backfilled = last
+	w.shared.salt, w.shared.backfilled = w.salt, last
+	return nil
+}
+
+// truncate empties the log once every frame has been checkpointed and no other connection reads
+// from it, it reports whether it did.
+func (w *wal) truncate() (bool, error) {
+	if w.file == nil {
+		return true, nil
+	}
+	w.shared.mu.Lock()
+	defer w.shared.mu.Unlock()
+	if w.backfilled < w.frameCount || w.otherReaders() {
+		return false, nil
+	}
+	if err := w.file.Truncate(0); err != nil {
+		return false, err
+	}
+	if err := w.file.Sync(); err != nil {
+		return false, err
+	}
+	w.frames, w.pages = map[uint32]uint32{}, nil
+	w.frameCount, w.backfilled, w.databaseSize = 0, 0, 0
+	w.size, w.header = 0, nil
+	w.shared.backfilled = 0
+	return true, nil
+}
+
+// delete closes and removes the log.
+func (w *wal) delete() error {
+	if err := w.close(); err != nil {
+		return err
+	}
+	return w.fs.Delete(w.name)
+}
+
+func (w *wal) close() error {
+	if w.shared != nil {
+		w.releaseIndex()
+		w.shared = nil
+	}
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// walChecksum adds the data to the checksum, the data is read as pairs of 32-bit words.
+func walChecksum(bigEndian bool, data []byte, s0, s1 uint32) (uint32, uint32) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	for i := 0; i+8 <= len(data); i += 8 {
+		s0 += order.Uint32(data[i:]) + s1
+		s1 += order.Uint32(data[i+4:]) + s0
+	}
+	return s0, s1
+}
+
+// JournalMode returns the journal mode of the database.
+func (p *Pager) JournalMode() JournalMode {
+	if p.header.FileFormatRead == 2 {
+		return JournalModeWAL
+	}
+	return JournalModeDelete
+}
+
+// SetJournalMode switches the database between the rollback journal and the WAL modes, the header
+// bytes 18 and 19 record the mode for the next connections. The log is checkpointed and deleted
+// before leaving the WAL mode.
+func (p *Pager) SetJournalMode(mode JournalMode) error {
+	if p.inTransaction {
+		return fmt.Errorf("cannot change journal mode from within a transaction")
+	}
+	if mode == p.JournalMode() {
+		return nil
+	}
+
+	formatVersion := byte(1)
+	switch mode {
+	case JournalModeWAL:
+		if _, _, ok := companionFile(p.file, "-wal"); !ok {
+			return fmt.Errorf("cannot change into wal mode: the database file has no name")
+		}
+		formatVersion = 2
+	case JournalModeDelete:
+		if p.wal != nil {
+			result, err := p.Checkpoint(CheckpointTruncate)
+			if err != nil {
+				return err
+			}
+			if result.Busy {
+				return fmt.Errorf("cannot change out of wal mode: %w", vfs.ErrBusy)
+			}
+			if err := p.wal.delete(); err != nil {
+				return err
+			}
+			p.wal = nil
+		}
+	default:
+		return fmt.Errorf("unsupported journal mode: %s", mode)
+	}
+
+	if err := p.Begin(); err != nil {
+		return err
+	}
+	p.header.FileFormatWrite = formatVersion
+	p.header.FileFormatRead = formatVersion
+	if err := p.Commit(); err != nil {
+		p.Rollback()
+		return err
+	}
+	if mode == JournalModeWAL {
+		var err error
+		p.wal, err = openWAL(p.file, p.limits.pageSize)
+		return err
+	}
+	return nil
+}
+
+// Checkpoint copies the frames of the log to the database file. The passive checkpoint doesn't take
+// any lock, the others hold the RESERVED lock so no writer appends frames meanwhile, they only run
+// the passive checkpoint and report Busy when the lock is taken. The frames after the snapshot of
+// a read transaction of another connection aren't copied, the other checkpoints report Busy then.
+// The truncate checkpoint empties the log afterward, the restart checkpoint doesn't need anything
+// more since a writer restarts the log once every frame has been checkpointed and no other
+// connection reads from it.
+func (p *Pager) Checkpoint(mode CheckpointMode) (CheckpointResult, error) {
+	if p.inTransaction || p.readers > 0 {
+		return CheckpointResult{}, fmt.Errorf("cannot checkpoint from within a transaction")
+	}
+	if p.wal == nil {
+		return CheckpointResult{LogFrames: -1, CheckpointedFrames: -1}, nil
+	}
+
+	var result CheckpointResult
+	if mode != CheckpointPassive {
+		err := p.file.Lock(vfs.LockShared)
+		if err == nil {
+			if err = p.file.Lock(vfs.LockReserved); err != nil {
+				p.file.Unlock(vfs.LockNone)
+			}
+		}
+		if errors.Is(err, vfs.ErrBusy) {
+			result.Busy = true
+		} else if err != nil {
+			return CheckpointResult{}, err
+		} else {
+			defer p.file.Unlock(vfs.LockNone)
+		}
+	}
+	if err := p.beginSnapshot(); err != nil {
+		return CheckpointResult{}, err
+	}
+	defer p.endSnapshot()
+	if p.wal.file != nil {
+		if err := p.wal.checkpoint(p.file); err != nil {
+			return CheckpointResult{}, err
+		}
+	}
+	// the frames read by the other connections are left for the next checkpoint
+	if mode != CheckpointPassive && p.wal.backfilled < p.wal.frameCount {
+		result.Busy = true
+	}
+	if mode == CheckpointTruncate && !result.Busy {
+		truncated, err := p.wal.truncate()
+		if err != nil {
+			return CheckpointResult{}, err
+		}
+		result.Busy = !truncated
+	}
+	result.LogFrames = int(p.wal.frameCount)
+	result.CheckpointedFrames = int(p.wal.backfilled)
+	return result, nil
+}
@@ -0,0 +1,263 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALRead(t *testing.T) {
+	original, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	original[18], original[19] = 2, 2
+
+	// the committed version of the database, its changed pages are the frames of the log
+	committed := append([]byte(nil), original...)
+	committed[18], committed[19] = 1, 1
+	file := vfs.NewMemFile(committed)
+	database, err := NewDB(file)
+	require.NoError(t, err)
+	tree, err := database.FindTablePage("oranges")
+	require.NoError(t, err)
+	record, err := EncodeRecord([]any{nil, "Clementine", "orange"}, EncodingUTF8)
+	require.NoError(t, err)
+	require.NoError(t, database.Begin())
+	for rowID := int64(1000); rowID < 1200; rowID++ {
+		require.NoError(t, tree.Insert(rowID, record))
+	}
+	require.NoError(t, database.Commit())
+	committed = file.Bytes()
+	committed[18], committed[19] = 2, 2
+	want, err := tree.GetRecords()
+	require.NoError(t, err)
+
+	pageSize := int(database.Header().PageSize)
+	databaseSize := uint32(len(committed) / pageSize)
+	log := newTestWAL(pageSize, [2]uint32{7, 11})
+	for pageNumber := uint32(1); pageNumber <= databaseSize; pageNumber++ {
+		data := committed[int(pageNumber-1)*pageSize : int(pageNumber)*pageSize]
+		if int(pageNumber)*pageSize <= len(original) && bytes.Equal(data, original[int(pageNumber-1)*pageSize:int(pageNumber)*pageSize]) {
+			continue
+		}
+		commitSize := uint32(0)
+		if pageNumber == databaseSize {
+			commitSize = databaseSize
+		}
+		log.appendFrame(pageNumber, commitSize, data)
+	}
+	// a transaction which hasn't committed, a torn frame and a frame left from a previous log are ignored
+	log.appendFrame(2, 0, make([]byte, pageSize))
+	log.appendFrame(3, databaseSize, make([]byte, pageSize))
+	log.Bytes()[log.Len()-1] = 1
+	stale := newTestWAL(pageSize, [2]uint32{8, 11})
+	stale.appendFrame(2, databaseSize, make([]byte, pageSize))
+	log.Write(stale.Bytes()[walHeaderSize:])
+
+	fs := vfs.NewMemVFS()
+	fs.AddFile("sample.db", original)
+	fs.AddFile("sample.db-wal", log.Bytes())
+	database, err = Open(fs, "sample.db", PagerOptions{})
+	require.NoError(t, err)
+	defer database.Close()
+	require.Equal(t, databaseSize, database.Header().DatabaseSize)
+	require.Equal(t, uint32(len(log.frames)-2), database.pager.wal.frameCount)
+	tree, err = database.FindTablePage("oranges")
+	require.NoError(t, err)
+	got, err := tree.GetRecords()
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestWALWriteAndCheckpoint(t *testing.T) {
+	original, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	fs := vfs.NewMemVFS()
+	fs.AddFile("sample.db", original)
+	file, err := fs.Open("sample.db", vfs.OpenReadWrite)
+	require.NoError(t, err)
+	database, err := NewDB(file)
+	require.NoError(t, err)
+	require.NoError(t, database.SetJournalMode(JournalModeWAL))
+	require.Equal(t, JournalModeWAL, database.JournalMode())
+
+	tree, err := database.FindTablePage("oranges")
+	require.NoError(t, err)
+	record, err := EncodeRecord([]any{nil, "Clementine", "orange"}, EncodingUTF8)
+	require.NoError(t, err)
+	for rowID := int64(1000); rowID < 1300; rowID += 100 {
+		require.NoError(t, database.Begin())
+		for i := rowID; i < rowID+100; i++ {
+			require.NoError(t, tree.Insert(i, record))
+		}
+		require.NoError(t, database.Commit())
+	}
+	want, err := tree.GetRecords()
+	require.NoError(t, err)
+	// the commits only went to the log
+	inFile := append([]byte(nil), file.(*vfs.MemFile).Bytes()...)
+
+	// another connection reads the committed pages from the log
+	readRecords := func() []Record {
+		file, err := fs.Open("sample.db", vfs.OpenReadOnly)
+		require.NoError(t, err)
+		other, err := NewDB(file)
+		require.NoError(t, err)
+		defer other.Close()
+		tree, err := other.FindTablePage("oranges")
+		require.NoError(t, err)
+		records, err := tree.GetRecords()
+		require.NoError(t, err)
+		return records
+	}
+	require.Equal(t, want, readRecords())
+
+	result, err := database.Checkpoint(CheckpointPassive)
+	require.NoError(t, err)
+	require.False(t, result.Busy)
+	require.Positive(t, result.LogFrames)
+	require.Equal(t, result.LogFrames, result.CheckpointedFrames)
+	require.NotEqual(t, inFile, file.(*vfs.MemFile).Bytes())
+	require.Equal(t, want, readRecords())
+
+	// the log restarts once it's fully checkpointed
+	require.NoError(t, database.Begin())
+	require.NoError(t, tree.Delete(1000))
+	require.NoError(t, database.Commit())
+	want, err = tree.GetRecords()
+	require.NoError(t, err)
+	require.Equal(t, want, readRecords())
+	require.Less(t, database.pager.wal.frameCount, uint32(result.LogFrames))
+
+	result, err = database.Checkpoint(CheckpointTruncate)
+	require.NoError(t, err)
+	require.Equal(t, CheckpointResult{}, result)
+	log, err := fs.Open("sample.db-wal", vfs.OpenReadOnly)
+	require.NoError(t, err)
+	size, err := log.Size()
+	require.NoError(t, err)
+	require.Zero(t, size)
+	require.Equal(t, want, readRecords())
+
+	require.NoError(t, database.SetJournalMode(JournalModeDelete))
+	exists, err := fs.Exists("sample.db-wal")
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.Equal(t, byte(1), file.(*vfs.MemFile).Bytes()[18])
+	require.Equal(t, want, readRecords())
+}
+
+func TestWALReadMarks(t *testing.T) {
+	original, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	fs := vfs.NewMemVFS()
+	fs.AddFile("sample.db", original)
+	open := func() (*DB, *TableBTree) {
+		file, err := fs.Open("sample.db", vfs.OpenReadWrite)
+		require.NoError(t, err)
+		database, err := NewDB(file)
+		require.NoError(t, err)
+		tree, err := database.FindTablePage("oranges")
+		require.NoError(t, err)
+		return database, tree
+	}
+	writer, writerTree := open()
+	require.NoError(t, writer.SetJournalMode(JournalModeWAL))
+	insert := func(from, to int64) {
+		record, err := EncodeRecord([]any{nil, "Clementine", "orange"}, EncodingUTF8)
+		require.NoError(t, err)
+		require.NoError(t, writer.Begin())
+		for rowID := from; rowID < to; rowID++ {
+			require.NoError(t, writerTree.Insert(rowID, record))
+		}
+		require.NoError(t, writer.Commit())
+	}
+	count := func(tree *TableBTree) int {
+		records, err := tree.GetRecords()
+		require.NoError(t, err)
+		return len(records)
+	}
+
+	// the reader opened before the commit reads the log again when its read transaction starts
+	reader, readerTree := open()
+	defer reader.Close()
+	insert(1000, 1100)
+	require.NoError(t, reader.BeginRead())
+	require.Equal(t, 106, count(readerTree))
+
+	// the frames committed after the snapshot of the reader stay in the log
+	insert(1100, 1200)
+	result, err := writer.Checkpoint(CheckpointFull)
+	require.NoError(t, err)
+	require.True(t, result.Busy)
+	require.Less(t, result.CheckpointedFrames, result.LogFrames)
+	require.Equal(t, 106, count(readerTree))
+	result, err = writer.Checkpoint(CheckpointTruncate)
+	require.NoError(t, err)
+	require.True(t, result.Busy)
+	reader.EndRead()
+
+	require.NoError(t, reader.BeginRead())
+	require.Equal(t, 206, count(readerTree))
+	// the log isn't restarted under the reader even once every frame has been checkpointed
+	result, err = writer.Checkpoint(CheckpointPassive)
+	require.NoError(t, err)
+	require.Equal(t, result.LogFrames, result.CheckpointedFrames)
+	insert(1200, 1300)
+	require.Greater(t, writer.pager.wal.frameCount, uint32(result.LogFrames))
+	require.Equal(t, 206, count(readerTree))
+	reader.EndRead()
+
+	require.NoError(t, reader.BeginRead())
+	require.Equal(t, 306, count(readerTree))
+	reader.EndRead()
+	result, err = writer.Checkpoint(CheckpointTruncate)
+	require.NoError(t, err)
+	require.Equal(t, CheckpointResult{}, result)
+	messages, err := reader.IntegrityCheck(DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
+
+// testWAL builds a log frame by frame.
+type testWAL struct {
+	bytes.Buffer
+	pageSize int
+	salt     [2]uint32
+	checksum [2]uint32
+	frames   []uint32
+}
+
+func newTestWAL(pageSize int, salt [2]uint32) *testWAL {
+	header := make([]byte, walHeaderSize)
+	binary.BigEndian.PutUint32(header[0:4], walMagic)
+	binary.BigEndian.PutUint32(header[4:8], walFormatVersion)
+	binary.BigEndian.PutUint32(header[8:12], uint32(pageSize))
+	binary.BigEndian.PutUint32(header[16:20], salt[0])
+	binary.BigEndian.PutUint32(header[20:24], salt[1])
+	s0, s1 := walChecksum(false, header[:24], 0, 0)
+	binary.BigEndian.PutUint32(header[24:28], s0)
+	binary.BigEndian.PutUint32(header[28:32], s1)
+	w := &testWAL{pageSize: pageSize, salt: salt, checksum: [2]uint32{s0, s1}}
+	w.Write(header)
+	return w
+}
+
+func (w *testWAL) appendFrame(pageNumber, commitSize uint32, data []byte) {
+	header := make([]byte, walFrameHeader)
+	binary.BigEndian.PutUint32(header[0:4], pageNumber)
+	binary.BigEndian.PutUint32(header[4:8], commitSize)
+	binary.BigEndian.PutUint32(header[8:12], w.salt[0])
+	binary.BigEndian.PutUint32(header[12:16], w.salt[1])
+	s0, s1 := walChecksum(false, header[:8], w.checksum[0], w.checksum[1])
+	s0, s1 = walChecksum(false, data, s0, s1)
+	binary.BigEndian.PutUint32(header[16:20], s0)
+	binary.BigEndian.PutUint32(header[20:24], s1)
+	w.checksum = [2]uint32{s0, s1}
+	w.frames = append(w.frames, pageNumber)
+	w.Write(header)
+	w.Write(data)
+}
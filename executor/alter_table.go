@@ -0,0 +1,22 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/adzimzf/sqlite-go/db"
+)
+
+// ExecuteAlterTable renames the table of the ALTER TABLE query, or renames, adds or drops one of its columns.
+func ExecuteAlterTable(database *db.DB, info db.QueryInfo) error {
+	switch info.DDL.Action {
+	case db.DDLRenameTable:
+		return database.RenameTable(info.TableName, info.DDL.Name)
+	case db.DDLRenameColumn:
+		return database.RenameColumn(info.TableName, info.DDL.Column, info.DDL.NewColumn.Name)
+	case db.DDLAddColumn:
+		return database.AddColumn(info.TableName, info.DDL.NewColumn, info.DDL.Constraints, info.DDL.ColumnSQL)
+	case db.DDLDropColumn:
+		return database.DropColumn(info.TableName, info.DDL.Column)
+	}
+	return fmt.Errorf("unsupported ALTER TABLE action: %v", info.DDL.Action)
+}
@@ -0,0 +1,8 @@
+package executor
+
+import "github.com/adzimzf/sqlite-go/db"
+
+// ExecuteCreateTable creates the table of the CREATE TABLE query.
+func ExecuteCreateTable(database *db.DB, info db.QueryInfo) error {
+	return database.CreateTable(info.DDL.Table, info.DDL.IfNotExists)
+}
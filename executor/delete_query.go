@@ -0,0 +1,139 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adzimzf/sqlite-go/constant"
+	"github.com/adzimzf/sqlite-go/db"
+	sqlparser "github.com/adzimzf/sqlite-go/sql"
+)
+
+// ExecuteDeleteQuery deletes the rows matching the WHERE expression in a single transaction
+// and returns how many rows have been deleted.
+func ExecuteDeleteQuery(database *db.DB, info db.QueryInfo) (int64, error) {
+	if strings.EqualFold(info.TableName, constant.SqliteMasterName) {
+		return 0, fmt.Errorf("table %s may not be modified", info.TableName)
+	}
+	tableInfo, err := database.FindTableSchema(info.TableName)
+	if err != nil {
+		return 0, err
+	}
+	if err := db.CheckColumns(info.Where, tableInfo); err != nil {
+		return 0, err
+	}
+	tree, err := findTableTree(database, tableInfo)
+	if err != nil {
+		return 0, err
+	}
+	indexes, err := database.FindTableIndexes(info.TableName)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := database.Begin(); err != nil {
+		return 0, err
+	}
+	rows, err := matchRows(database, tableInfo, tree, info.Where)
+	for i := 0; err == nil && i < len(rows); i++ {
+		err = deleteRow(tableInfo, tree, indexes, rows[i])
+	}
+	if err == nil {
+		err = database.Commit()
+	}
+	if err != nil {
+		database.Rollback()
+		return 0, err
+	}
+	return int64(len(rows)), nil
+}
+
+// deleteRow removes the row from the table and its keys from every index of the table.
+func deleteRow(tableInfo db.TableSchemaInfo, tree *tableTree, indexes []*db.IndexBTree, row db.ExprRow) error {
+	for _, index := range indexes {
+		key, err := tableInfo.IndexKey(index, row.Values, row.RowID)
+		if err != nil {
+			return err
+		}
+		if err := index.Delete(key); err != nil {
+			return err
+		}
+	}
+	return tree.delete(row)
+}
+
+// matchRows returns the rows for which the WHERE expression holds, every row when there's none.
+// The rows are all read before any of them is modified. A WHERE expression on the rowid, or on the
+// PRIMARY KEY of a WITHOUT ROWID table, only reads the row with a seek.
+func matchRows(database *db.DB, tableInfo db.TableSchemaInfo, tree *tableTree, where sqlparser.Expr) ([]db.ExprRow, error) {
+	cursor := tree.cursor()
+	defer cursor.Close()
+
+	var rows []db.ExprRow
+	match := func() error {
+		rowID, err := cursor.RowID()
+		if err != nil {
+			return err
+		}
+		record, err := cursor.Record()
+		if err != nil {
+			return err
+		}
+		record = tableInfo.TableRecord(record)
+		row := db.ExprRow{
+			Table:    tableInfo,
+			RowID:    rowID,
+			Values:   make([]any, len(tableInfo.Columns)),
+			Encoding: database.Header().TextEncoding,
+		}
+		for i := range row.Values {
+			// the columns added after the row has been written are NULL
+			if i >= len(record.Header.Fields) {
+				break
+			}
+			if row.Values[i], err = record.Value(i); err != nil {
+				return err
+			}
+		}
+		if where != nil {
+			matched, err := db.IsTrue(where, &row)
+			if err != nil || !matched {
+				return err
+			}
+		}
+		rows = append(rows, row)
+		return nil
+	}
+
+	if rowID, ok := db.RowIDLookup(where, tableInfo); ok {
+		found, err := cursor.SeekRowID(rowID)
+		if err != nil || !found {
+			return nil, err
+		}
+		if err := match(); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+	if key, ok := db.PrimaryKeyLookup(where, tableInfo); ok {
+		found, err := cursor.SeekKey([]any{key})
+		if err != nil || !found {
+			return nil, err
+		}
+		if err := match(); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+
+	err := cursor.First()
+	for ; err == nil && cursor.Valid(); err = cursor.Next() {
+		if err := match(); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
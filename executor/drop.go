@@ -0,0 +1,13 @@
+package executor
+
+import "github.com/adzimzf/sqlite-go/db"
+
+// ExecuteDropTable drops the table of the DROP TABLE query with its indexes.
+func ExecuteDropTable(database *db.DB, info db.QueryInfo) error {
+	return database.DropTable(info.TableName, info.DDL.IfExists)
+}
+
+// ExecuteDropIndex drops the index of the DROP INDEX query.
+func ExecuteDropIndex(database *db.DB, info db.QueryInfo) error {
+	return database.DropIndex(info.DDL.Name, info.DDL.IfExists)
+}
@@ -0,0 +1,237 @@
+package executor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/adzimzf/sqlite-go/db"
+	"github.com/adzimzf/sqlite-go/vfs"
+	"github.com/stretchr/testify/require"
+)
+
+// execute runs the statement the way the cli does and returns how many rows it changed.
+func execute(database *db.DB, query string) (int64, error) {
+	info, err := db.ExtractQueryInfo(query)
+	if err != nil {
+		return 0, err
+	}
+	switch info.QueryType {
+	case db.INSERT:
+		return ExecuteInsertQuery(database, info)
+	case db.DELETE:
+		return ExecuteDeleteQuery(database, info)
+	case db.UPDATE:
+		return ExecuteUpdateQuery(database, info)
+	case db.CREATE_TABLE:
+		return 0, ExecuteCreateTable(database, info)
+	case db.DROP_TABLE:
+		return 0, ExecuteDropTable(database, info)
+	case db.DROP_INDEX:
+		return 0, ExecuteDropIndex(database, info)
+	case db.ALTER_TABLE:
+		return 0, ExecuteAlterTable(database, info)
+	}
+	return 0, nil
+}
+
+// openSample opens a memory copy of sample.db.
+func openSample(t *testing.T) *db.DB {
+	data, err := os.ReadFile("../sample.db")
+	require.NoError(t, err)
+	database, err := db.NewDB(vfs.NewMemFile(data))
+	require.NoError(t, err)
+	return database
+}
+
+// newTestDB creates an empty memory database and runs the statements on it.
+func newTestDB(t *testing.T, queries ...string) *db.DB {
+	database, err := db.CreateDB(vfs.NewMemFile(nil), db.CreateOptions{})
+	require.NoError(t, err)
+	for _, query := range queries {
+		_, err := execute(database, query)
+		require.NoError(t, err, query)
+	}
+	return database
+}
+
+// selectRows returns the rows of the SELECT query as the cli prints them.
+func selectRows(t *testing.T, database *db.DB, query string) []string {
+	info, err := db.ExtractQueryInfo(query)
+	require.NoError(t, err)
+	rows, err := ExecuteSelectQuery(database, info)
+	require.NoError(t, err)
+	lines := []string{}
+	for _, row := range rows {
+		lines = append(lines, row.String())
+	}
+	return lines
+}
+
+func TestDeleteQuery(t *testing.T) {
+	database := openSample(t)
+
+	tests := []struct {
+		query string
+		count int64
+		want  []string
+	}{
+		// the rowid and its alias are found with a seek, the matched row is still returned
+		{"delete from apples where id = 2", 1, []string{"1, Granny Smith, Light Green", "3, Honeycrisp, Blush Red", "4, Golden Delicious, Yellow"}},
+		{"delete from apples where rowid = 2", 0, []string{"1, Granny Smith, Light Green", "3, Honeycrisp, Blush Red", "4, Golden Delicious, Yellow"}},
+		{"delete from apples where color = 'Yellow' or name = 'Granny Smith'", 2, []string{"3, Honeycrisp, Blush Red"}},
+		{"delete from apples where name = 'Fuji'", 0, []string{"3, Honeycrisp, Blush Red"}},
+		{"delete from apples", 1, []string{}},
+	}
+	for _, test := range tests {
+		count, err := execute(database, test.query)
+		require.NoError(t, err, test.query)
+		require.Equal(t, test.count, count, test.query)
+		require.Equal(t, test.want, selectRows(t, database, "select * from apples"), test.query)
+	}
+	require.Len(t, selectRows(t, database, "select * from oranges"), 6)
+
+	_, err := execute(database, "delete from apples where size = 1")
+	require.EqualError(t, err, "no such column: size")
+}
+
+func TestUpdateQuery(t *testing.T) {
+	database := openSample(t)
+
+	tests := []struct {
+		query string
+		count int64
+		want  []string
+	}{
+		{"update apples set color = 'Green' where id = 1", 1, []string{"1, Granny Smith, Green", "2, Fuji, Red", "3, Honeycrisp, Blush Red", "4, Golden Delicious, Yellow"}},
+		{"update apples set color = 'Red' where color = 'Blush Red' or name = 'Golden Delicious'", 2, []string{"1, Granny Smith, Green", "2, Fuji, Red", "3, Honeycrisp, Red", "4, Golden Delicious, Red"}},
+		{"update apples set id = 10 where id = 3", 1, []string{"1, Granny Smith, Green", "2, Fuji, Red", "4, Golden Delicious, Red", "10, Honeycrisp, Red"}},
+		{"update apples set name = 'Gala' where id = 3", 0, []string{"1, Granny Smith, Green", "2, Fuji, Red", "4, Golden Delicious, Red", "10, Honeycrisp, Red"}},
+	}
+	for _, test := range tests {
+		count, err := execute(database, test.query)
+		require.NoError(t, err, test.query)
+		require.Equal(t, test.count, count, test.query)
+		require.Equal(t, test.want, selectRows(t, database, "select * from apples"), test.query)
+	}
+
+	_, err := execute(database, "update apples set id = 1 where id = 2")
+	require.EqualError(t, err, "UNIQUE constraint failed: apples.id")
+}
+
+func TestCreateTableQuery(t *testing.T) {
+	database := newTestDB(t,
+		"create table fruits (id integer primary key, name text)",
+		"create table if not exists fruits (name text)",
+		"create table seeds (name text)",
+	)
+
+	// every table gets the next free page as its root page
+	require.Equal(t, []string{
+		"table, fruits, fruits, 2, CREATE TABLE fruits (id integer primary key, name text)",
+		"table, seeds, seeds, 3, CREATE TABLE seeds (name text)",
+	}, selectRows(t, database, "select * from sqlite_master"))
+	_, err := execute(database, "create table Fruits (name text)")
+	require.EqualError(t, err, "table Fruits already exists")
+
+	_, err = execute(database, "insert into seeds values ('pip')")
+	require.NoError(t, err)
+	require.Equal(t, []string{"pip"}, selectRows(t, database, "select * from seeds"))
+	require.Empty(t, selectRows(t, database, "select * from fruits"))
+}
+
+func TestDDLQueries(t *testing.T) {
+	database := newTestDB(t,
+		"create table fruits (id integer primary key, name text)",
+		"insert into fruits (name) values ('apple'), ('banana')",
+	)
+
+	for _, query := range []string{
+		"alter table fruits rename to produce",
+		"alter table produce rename column name to title",
+		"alter table produce add column color text",
+		"update produce set color = 'red' where id = 1",
+		"create table seeds (name text)",
+		"drop table seeds",
+	} {
+		_, err := execute(database, query)
+		require.NoError(t, err, query)
+	}
+	require.Equal(t, []string{"1, apple, red", "2, banana, NULL"}, selectRows(t, database, "select * from produce"))
+	require.Equal(t, []string{"table, produce, produce, 2, CREATE TABLE \"produce\" (id integer primary key, title text, color text)"},
+		selectRows(t, database, "select * from sqlite_master"))
+
+	for query, wantErr := range map[string]string{
+		"alter table produce add column code text unique":   "Cannot add a UNIQUE column",
+		"alter table produce add column code text not null": "Cannot add a NOT NULL column with default value NULL",
+		"alter table produce rename column title to Title":  "error in table produce after rename: duplicate column name: Title",
+		"alter table produce drop column id":                `cannot drop PRIMARY KEY column: "id"`,
+		"alter table seeds rename to pips":                  "no such table: seeds",
+	} {
+		_, err := execute(database, query)
+		require.EqualError(t, err, wantErr, query)
+	}
+
+	_, err := execute(database, "alter table produce drop column title")
+	require.NoError(t, err)
+	require.Equal(t, []string{"1, red", "2, NULL"}, selectRows(t, database, "select * from produce"))
+}
+
+func TestWithoutRowIDQueries(t *testing.T) {
+	database := newTestDB(t,
+		"create table stock (name text primary key, quantity integer) without rowid",
+		"insert into stock values ('pear', 3), ('apple', 5), ('fig', 1)",
+	)
+
+	// the PRIMARY KEY is found with a seek, the rows are sorted by it
+	count, err := execute(database, "update stock set quantity = 4 where name = 'pear'")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	count, err = execute(database, "delete from stock where name = 'apple'")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+	count, err = execute(database, "delete from stock where name = 'grape'")
+	require.NoError(t, err)
+	require.Zero(t, count)
+	require.Equal(t, []string{"fig, 1", "pear, 4"}, selectRows(t, database, "select * from stock"))
+
+	_, err = execute(database, "insert into stock values ('fig', 2)")
+	require.EqualError(t, err, "UNIQUE constraint failed: stock.name")
+}
+
+func TestUniqueConstraintQueries(t *testing.T) {
+	database := newTestDB(t,
+		"create table fruits (id integer primary key, name text unique, color text, size integer, unique(color, size))",
+		"insert into fruits (name, color, size) values ('apple', 'red', 3), ('banana', 'yellow', 5), ('fig', NULL, 1), ('plum', NULL, 1)",
+	)
+
+	// every constraint has an automatic index without any SQL, the NULL values are all distinct
+	require.Equal(t, []string{
+		"table, fruits, fruits, 2, CREATE TABLE fruits (id integer primary key, name text unique, color text, size integer, unique(color, size))",
+		"index, sqlite_autoindex_fruits_1, fruits, 3, NULL",
+		"index, sqlite_autoindex_fruits_2, fruits, 4, NULL",
+	}, selectRows(t, database, "select * from sqlite_master"))
+	for query, wantErr := range map[string]string{
+		"insert into fruits (name) values ('apple')":                "UNIQUE constraint failed: fruits.name",
+		"insert into fruits (color, size) values ('red', 3)":        "UNIQUE constraint failed: fruits.color, fruits.size",
+		"update fruits set color = 'yellow', size = 5 where id = 1": "UNIQUE constraint failed: fruits.color, fruits.size",
+		"update fruits set name = 'banana' where id = 1":            "UNIQUE constraint failed: fruits.name",
+	} {
+		_, err := execute(database, query)
+		require.EqualError(t, err, wantErr, query)
+	}
+
+	// the indexes follow the rows updated and deleted
+	for _, query := range []string{
+		"update fruits set name = 'cherry' where id = 1",
+		"delete from fruits where id = 2",
+		"insert into fruits (name, color, size) values ('apple', 'yellow', 5), ('banana', 'red', 4)",
+	} {
+		_, err := execute(database, query)
+		require.NoError(t, err, query)
+	}
+	require.Equal(t, []string{"1, cherry, red, 3", "3, fig, NULL, 1", "4, plum, NULL, 1", "5, apple, yellow, 5", "6, banana, red, 4"},
+		selectRows(t, database, "select * from fruits"))
+	messages, err := database.IntegrityCheck(db.DefaultIntegrityErrors, false)
+	require.NoError(t, err)
+	require.Empty(t, messages)
+}
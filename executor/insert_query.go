@@ -0,0 +1,183 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adzimzf/sqlite-go/constant"
+	"github.com/adzimzf/sqlite-go/db"
+)
+
+// ExecuteInsertQuery inserts every row of the query in a single transaction
+// and returns how many rows have been inserted.
+func ExecuteInsertQuery(database *db.DB, info db.QueryInfo) (int64, error) {
+	if strings.EqualFold(info.TableName, constant.SqliteMasterName) {
+		return 0, fmt.Errorf("table %s may not be modified", info.TableName)
+	}
+	tableInfo, err := database.FindTableSchema(info.TableName)
+	if err != nil {
+		return 0, err
+	}
+	tree, err := findTableTree(database, tableInfo)
+	if err != nil {
+		return 0, err
+	}
+	indexes, err := database.FindTableIndexes(info.TableName)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := database.Begin(); err != nil {
+		return 0, err
+	}
+	// the largest rowid the AUTOINCREMENT table ever had, written to sqlite_sequence once every row is inserted
+	var sequence int64
+	if tableInfo.IsAutoIncrement {
+		if sequence, err = database.Sequence(tableInfo.Name); err != nil {
+			database.Rollback()
+			return 0, err
+		}
+	}
+	for _, values := range info.Values {
+		row, err := insertRow(tableInfo, info.TargetCols, values)
+		if err == nil {
+			err = insert(database, tableInfo, tree, indexes, row, &sequence)
+		}
+		if err != nil {
+			database.Rollback()
+			return 0, err
+		}
+	}
+	if tableInfo.IsAutoIncrement {
+		if err := database.SetSequence(tableInfo.Name, sequence); err != nil {
+			database.Rollback()
+			return 0, err
+		}
+	}
+	if err := database.Commit(); err != nil {
+		database.Rollback()
+		return 0, err
+	}
+	return int64(len(info.Values)), nil
+}
+
+// insertRow returns the values of every column of the table, the columns missing from the query are NULL.
+func insertRow(tableInfo db.TableSchemaInfo, targetCols []string, values []any) ([]any, error) {
+	row := make([]any, len(tableInfo.Columns))
+	if len(targetCols) == 0 {
+		if len(values) != len(tableInfo.Columns) {
+			return nil, fmt.Errorf("table %s has %d columns but %d values were supplied",
+				tableInfo.Name, len(tableInfo.Columns), len(values))
+		}
+		copy(row, values)
+	} else {
+		if len(values) != len(targetCols) {
+			return nil, fmt.Errorf("%d values for %d columns", len(values), len(targetCols))
+		}
+		for i, name := range targetCols {
+			column, ok := tableInfo.Column(name)
+			if !ok {
+				return nil, fmt.Errorf("table %s has no column named %s", tableInfo.Name, name)
+			}
+			row[column.Idx] = values[i]
+		}
+	}
+
+	for i, column := range tableInfo.Columns {
+		row[i] = column.ApplyAffinity(row[i])
+	}
+	return row, nil
+}
+
+// insert adds the row to the table and to every index of the table. The sequence of an AUTOINCREMENT
+// table is raised to the rowid of the row, a WITHOUT ROWID table doesn't have any rowid.
+func insert(database *db.DB, tableInfo db.TableSchemaInfo, tree *tableTree, indexes []*db.IndexBTree, row []any, sequence *int64) error {
+	var rowID int64
+	if !tableInfo.WithoutRowID {
+		var err error
+		if rowID, err = rowIDOf(tableInfo, tree.rowID, row, *sequence); err != nil {
+			return err
+		}
+		*sequence = max(*sequence, rowID)
+	}
+
+	keys := make([][]any, len(indexes))
+	for i, index := range indexes {
+		var err error
+		keys[i], err = tableInfo.IndexKey(index, row, rowID)
+		if err != nil {
+			return err
+		}
+		if err := checkUnique(tableInfo, index, keys[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := tree.insert(rowID, row, database.Header().TextEncoding); err != nil {
+		return err
+	}
+	for i, index := range indexes {
+		if err := index.Insert(keys[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowIDOf returns the rowid of the new row: the value of the INTEGER PRIMARY KEY column when it's set,
+// a new rowid otherwise, never used before by an AUTOINCREMENT table. The INTEGER PRIMARY KEY column
+// is stored as NULL in the record.
+func rowIDOf(tableInfo db.TableSchemaInfo, tree *db.TableBTree, row []any, sequence int64) (int64, error) {
+	column, ok := tableInfo.RowIDColumn()
+	if !ok || row[column.Idx] == nil {
+		if tableInfo.IsAutoIncrement {
+			return tree.NewAutoIncrementRowID(sequence)
+		}
+		return tree.NewRowID()
+	}
+
+	rowID, ok := row[column.Idx].(int64)
+	if !ok {
+		return 0, fmt.Errorf("datatype mismatch")
+	}
+	row[column.Idx] = nil
+
+	cursor := tree.Cursor()
+	defer cursor.Close()
+	found, err := cursor.SeekRowID(rowID)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return 0, fmt.Errorf("UNIQUE constraint failed: %s.%s", tableInfo.Name, column.Name)
+	}
+	return rowID, nil
+}
+
+// checkUnique fails when a UNIQUE index already has the key, the NULL values are all distinct.
+func checkUnique(tableInfo db.TableSchemaInfo, index *db.IndexBTree, key []any) error {
+	if !index.Unique {
+		return nil
+	}
+	prefix := key[:len(index.Columns)]
+	for _, value := range prefix {
+		if value == nil {
+			return nil
+		}
+	}
+
+	found := false
+	err := index.Seek(prefix, func(record db.Record) (bool, error) {
+		found = true
+		return false, nil
+	})
+	if err != nil || !found {
+		return err
+	}
+
+	names := make([]string, len(index.Columns))
+	for i, column := range index.Columns {
+		names[i] = tableInfo.Name + "." + column.Name
+	}
+	return fmt.Errorf("UNIQUE constraint failed: %s", strings.Join(names, ", "))
+}
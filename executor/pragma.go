@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/adzimzf/sqlite-go/db"
+)
+
+// checkpointModes are the arguments of PRAGMA wal_checkpoint.
+var checkpointModes = map[string]db.CheckpointMode{
+	"passive":  db.CheckpointPassive,
+	"full":     db.CheckpointFull,
+	"restart":  db.CheckpointRestart,
+	"truncate": db.CheckpointTruncate,
+}
+
+// autoVacuumModes are the values of PRAGMA auto_vacuum.
+var autoVacuumModes = map[string]db.AutoVacuumMode{
+	"none":        db.AutoVacuumNone,
+	"full":        db.AutoVacuumFull,
+	"incremental": db.AutoVacuumIncremental,
+	"0":           db.AutoVacuumNone,
+	"1":           db.AutoVacuumFull,
+	"2":           db.AutoVacuumIncremental,
+}
+
+// ExecutePragma runs the PRAGMA statement and returns its result rows,
+// the pragmas which aren't supported are ignored like sqlite does with the unknown ones.
+func ExecutePragma(database *db.DB, info db.QueryInfo) (db.Rows, error) {
+	switch info.PragmaName {
+	case "journal_mode":
+		if info.PragmaValue != nil {
+			mode := db.JournalMode(strings.ToLower(fmt.Sprint(info.PragmaValue)))
+			if err := database.SetJournalMode(mode); err != nil {
+				return nil, err
+			}
+		}
+		return db.Rows{{db.NewStringTuple(string(database.JournalMode()))}}, nil
+	case "wal_checkpoint":
+		// an unknown mode is a passive checkpoint
+		mode := checkpointModes[strings.ToLower(fmt.Sprint(info.PragmaValue))]
+		result, err := database.Checkpoint(mode)
+		if err != nil {
+			return nil, err
+		}
+		busy := int64(0)
+		if result.Busy {
+			busy = 1
+		}
+		return db.Rows{{
+			db.NewInt64Tuple(busy),
+			db.NewInt64Tuple(int64(result.LogFrames)),
+			db.NewInt64Tuple(int64(result.CheckpointedFrames)),
+		}}, nil
+	case "auto_vacuum":
+		if info.PragmaValue == nil {
+			return db.Rows{{db.NewInt64Tuple(int64(database.AutoVacuum()))}}, nil
+		}
+		// like sqlite an unknown mode is none
+		mode := autoVacuumModes[strings.ToLower(fmt.Sprint(info.PragmaValue))]
+		return nil, database.SetAutoVacuum(mode)
+	case "incremental_vacuum":
+		// every free page is removed without a number of pages
+		pages, _ := info.PragmaValue.(int64)
+		return nil, database.IncrementalVacuum(int(max(min(pages, math.MaxInt32), 0)))
+	case "integrity_check", "quick_check":
+		maxErrors := db.DefaultIntegrityErrors
+		if limit, ok := info.PragmaValue.(int64); ok && limit > 0 {
+			maxErrors = int(limit)
+		}
+		// the problems found before a record couldn't be read are returned with the error
+		messages, err := database.IntegrityCheck(maxErrors, info.PragmaName == "quick_check")
+		if len(messages) == 0 && err == nil {
+			messages = []string{"ok"}
+		}
+		rows := make(db.Rows, len(messages))
+		for i, message := range messages {
+			rows[i] = db.RecordTuple{db.NewStringTuple(message)}
+		}
+		return rows, err
+	}
+	return nil, nil
+}
@@ -2,90 +2,133 @@ package executor
 
 import (
 	"github.com/adzimzf/sqlite-go/db"
-	"os"
 )
 
-func ExecuteSelectQuery(file *os.File, info db.QueryInfo) (db.Rows, error) {
-	newDB, err := db.NewDB(file)
-	if err != nil {
-		return nil, err
-	}
+// SelectQuery is a SELECT query whose tables and columns have been resolved, it's ready to run.
+type SelectQuery struct {
+	database *db.DB
+	tables   []selectTable
+	// countOnly is set for count(*) which doesn't need any row, only how many there are
+	countOnly bool
+}
 
-	var records []db.Record
-	var rows db.Rows
-	tableInfoMap := map[string]db.TableSchemaInfo{}
+// selectTable is a table read by the query with the fields of its records the query returns.
+type selectTable struct {
+	info   db.TableSchemaInfo
+	fields []int64
+	master bool
+}
 
-	// if the select into sqlite_master no need to go to the master
-	for _, table := range info.JoinTables {
+// PrepareSelectQuery resolves the tables and the columns of the query, nothing is read from them yet.
+func PrepareSelectQuery(database *db.DB, info db.QueryInfo) (*SelectQuery, error) {
+	query := &SelectQuery{database: database}
+	for _, field := range info.SelectFields {
+		if field.IsAgg && field.ColName == "*" && field.AggType == db.COUNT_AGGREGATE {
+			query.countOnly = true
+		}
+	}
 
+	for _, table := range info.JoinTables {
+		// if the select into sqlite_master no need to go to the master
 		if table == "sqlite_master" {
-			sqLiteMaster, err := newDB.FindSQLiteMaster()
+			tableInfo, err := database.FindSQLiteSchema()
 			if err != nil {
 				return nil, err
 			}
-			record, err := sqLiteMaster.GetRecords()
-			if err != nil {
-				return nil, err
-			}
-
-			sqLiteSchema, err := newDB.FindSQLiteSchema()
-			if err != nil {
-				return nil, err
-			}
-
-			rows1, err := db.RecordsToRows(record, sqLiteSchema)
-			if err != nil {
-				return nil, err
-			}
-			rows = append(rows, rows1...)
+			query.tables = append(query.tables, selectTable{info: tableInfo, master: true})
 			continue
 		}
 
-		tableInfo, err := newDB.FindTableSchema(table)
+		tableInfo, err := database.FindTableSchema(table)
 		if err != nil {
 			return nil, err
 		}
-		tableInfoMap[table] = tableInfo
+		fields := tableInfo.ColumnIndex(info.FieldNameByTable(table)...)
+		// every column, the rows written before a column has been added don't have it
+		if len(fields) == 0 {
+			for _, column := range tableInfo.Columns {
+				fields = append(fields, column.Idx)
+			}
+		}
+		query.tables = append(query.tables, selectTable{info: tableInfo, fields: fields})
+	}
+	return query, nil
+}
 
-		page, err := newDB.FindTablePage(table)
+// Stream runs the query and calls fn for every row as soon as it's read,
+// the tables are walked with a cursor so only the current row is kept in memory.
+func (q *SelectQuery) Stream(fn func(row db.RecordTuple) error) error {
+	count := int64(0)
+	for _, table := range q.tables {
+		cursor, err := q.cursor(table)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		fieldNameByTable := info.FieldNameByTable(table)
-		indexList := tableInfo.ColumnIndex(fieldNameByTable...)
+		defer cursor.Close()
 
-		record, err := page.GetRecordsFields(indexList)
-		if err != nil {
-			return nil, err
+		err = cursor.First()
+		for ; err == nil && cursor.Valid(); err = cursor.Next() {
+			count++
+			if q.countOnly {
+				continue
+			}
+
+			record, err := cursor.Record()
+			if err != nil {
+				return err
+			}
+			row, err := db.RecordToRow(table.info.TableRecord(record).SelectFields(table.fields), table.info)
+			if err != nil {
+				return err
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
 		}
-		rows1, err := db.RecordsToRows(record, tableInfo)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		rows = append(rows, rows1...)
+	}
 
-		if len(info.SelectFields) == 1 && info.SelectFields[0].ColName == "*" {
-			newSelectedFields := []*db.SelectFieldExpression{}
-			for _, column := range tableInfo.Columns {
-				newSelectedFields = append(newSelectedFields, &db.SelectFieldExpression{
-					ColName: column.Name,
-				})
-			}
-			info.SelectFields = newSelectedFields
-		}
+	if q.countOnly {
+		return fn(db.RecordTuple{db.NewInt64Tuple(count)})
 	}
+	return nil
+}
 
-	for _, field := range info.SelectFields {
-		if field.IsAgg {
-			if field.ColName == "*" && field.AggType == db.COUNT_AGGREGATE {
-				return db.Rows{
-					{
-						db.NewInt64Tuple(int64(len(records))),
-					},
-				}, nil
-			}
+func (q *SelectQuery) cursor(table selectTable) (*db.BTreeCursor, error) {
+	if table.master {
+		tree, err := q.database.FindSQLiteMaster()
+		if err != nil {
+			return nil, err
 		}
+		return tree.Cursor(), nil
 	}
+	return q.database.TableCursor(table.info)
+}
 
+// ExecuteSelectQuery runs the query and returns every row in memory.
+func ExecuteSelectQuery(database *db.DB, info db.QueryInfo) (db.Rows, error) {
+	var rows db.Rows
+	err := StreamSelectQuery(database, info, func(row db.RecordTuple) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return rows, nil
 }
+
+// StreamSelectQuery runs the query within a read transaction and calls fn for every row as soon as it's read.
+func StreamSelectQuery(database *db.DB, info db.QueryInfo, fn func(row db.RecordTuple) error) error {
+	if err := database.BeginRead(); err != nil {
+		return err
+	}
+	defer database.EndRead()
+	query, err := PrepareSelectQuery(database, info)
+	if err != nil {
+		return err
+	}
+	return query.Stream(fn)
+}
@@ -0,0 +1,78 @@
+package executor
+
+import (
+	"github.com/adzimzf/sqlite-go/db"
+)
+
+// tableTree is the b-tree the rows of a table are stored in: a table b-tree keyed by the rowid,
+// or an index b-tree keyed by the PRIMARY KEY for a WITHOUT ROWID table.
+type tableTree struct {
+	rowID        *db.TableBTree
+	withoutRowID *db.WithoutRowIDTable
+}
+
+func findTableTree(database *db.DB, tableInfo db.TableSchemaInfo) (*tableTree, error) {
+	if tableInfo.WithoutRowID {
+		table, err := database.FindWithoutRowIDTable(tableInfo)
+		if err != nil {
+			return nil, err
+		}
+		return &tableTree{withoutRowID: table}, nil
+	}
+	tree, err := database.FindTablePage(tableInfo.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &tableTree{rowID: tree}, nil
+}
+
+// cursor returns a cursor over the rows, the records of a WITHOUT ROWID table have their fields
+// in the stored order, see db.TableSchemaInfo.TableRecord.
+func (t *tableTree) cursor() *db.BTreeCursor {
+	if t.withoutRowID != nil {
+		return t.withoutRowID.Cursor()
+	}
+	return t.rowID.Cursor()
+}
+
+// insert adds the row with the values of every column, a WITHOUT ROWID table ignores the rowid.
+func (t *tableTree) insert(rowID int64, values []any, encoding db.TextEncoding) error {
+	if t.withoutRowID != nil {
+		return t.withoutRowID.Insert(values)
+	}
+	record, err := db.EncodeRecord(values, encoding)
+	if err != nil {
+		return err
+	}
+	return t.rowID.Insert(rowID, record)
+}
+
+// update rewrites the row with the values, a row whose rowid changes moves in the table.
+// The row of a WITHOUT ROWID table is removed and inserted again since its PRIMARY KEY may change.
+func (t *tableTree) update(row db.ExprRow, rowID int64, values []any, encoding db.TextEncoding) error {
+	if t.withoutRowID != nil {
+		if err := t.withoutRowID.Delete(row.Values); err != nil {
+			return err
+		}
+		return t.withoutRowID.Insert(values)
+	}
+	record, err := db.EncodeRecord(values, encoding)
+	if err != nil {
+		return err
+	}
+	if rowID == row.RowID {
+		return t.rowID.Update(rowID, record)
+	}
+	if err := t.rowID.Delete(row.RowID); err != nil {
+		return err
+	}
+	return t.rowID.Insert(rowID, record)
+}
+
+// delete removes the row, by rowid or by PRIMARY KEY for a WITHOUT ROWID table.
+func (t *tableTree) delete(row db.ExprRow) error {
+	if t.withoutRowID != nil {
+		return t.withoutRowID.Delete(row.Values)
+	}
+	return t.rowID.Delete(row.RowID)
+}
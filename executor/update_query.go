@@ -0,0 +1,157 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adzimzf/sqlite-go/constant"
+	"github.com/adzimzf/sqlite-go/db"
+)
+
+// updateTarget is the column assigned by a SET expression, isRowID is set for
+// the INTEGER PRIMARY KEY column and the rowid aliases.
+type updateTarget struct {
+	column  db.TableColumnInfo
+	isRowID bool
+}
+
+// ExecuteUpdateQuery assigns the SET expressions to the rows matching the WHERE expression
+// in a single transaction and returns how many rows have been updated.
+// The expressions are evaluated with the values the row had before the update.
+func ExecuteUpdateQuery(database *db.DB, info db.QueryInfo) (int64, error) {
+	if strings.EqualFold(info.TableName, constant.SqliteMasterName) {
+		return 0, fmt.Errorf("table %s may not be modified", info.TableName)
+	}
+	tableInfo, err := database.FindTableSchema(info.TableName)
+	if err != nil {
+		return 0, err
+	}
+	targets, err := updateTargets(tableInfo, info.SetExpressions)
+	if err != nil {
+		return 0, err
+	}
+	if err := db.CheckColumns(info.Where, tableInfo); err != nil {
+		return 0, err
+	}
+	tree, err := findTableTree(database, tableInfo)
+	if err != nil {
+		return 0, err
+	}
+	indexes, err := database.FindTableIndexes(info.TableName)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := database.Begin(); err != nil {
+		return 0, err
+	}
+	rows, err := matchRows(database, tableInfo, tree, info.Where)
+	for i := 0; err == nil && i < len(rows); i++ {
+		err = updateRow(database, tableInfo, tree, indexes, info.SetExpressions, targets, rows[i])
+	}
+	if err == nil {
+		err = database.Commit()
+	}
+	if err != nil {
+		database.Rollback()
+		return 0, err
+	}
+	return int64(len(rows)), nil
+}
+
+// updateTargets returns the column assigned by every SET expression.
+func updateTargets(tableInfo db.TableSchemaInfo, sets []*db.SetExpression) ([]updateTarget, error) {
+	targets := make([]updateTarget, len(sets))
+	rowIDColumn, hasRowIDColumn := tableInfo.RowIDColumn()
+	for i, set := range sets {
+		if err := db.CheckColumns(set.Expr, tableInfo); err != nil {
+			return nil, err
+		}
+		column, ok := tableInfo.Column(set.ColName)
+		switch {
+		case ok:
+			targets[i] = updateTarget{column: column, isRowID: hasRowIDColumn && column.Idx == rowIDColumn.Idx}
+		case !tableInfo.WithoutRowID && isRowIDAlias(set.ColName):
+			targets[i] = updateTarget{column: db.TableColumnInfo{Idx: -1, Name: set.ColName, Type: db.Int64}, isRowID: true}
+		default:
+			return nil, fmt.Errorf("no such column: %s", set.ColName)
+		}
+	}
+	return targets, nil
+}
+
+// updateRow rewrites the row and its index keys, a row whose rowid changes moves in the table.
+func updateRow(database *db.DB, tableInfo db.TableSchemaInfo, tree *tableTree, indexes []*db.IndexBTree,
+	sets []*db.SetExpression, targets []updateTarget, row db.ExprRow) error {
+	values := make([]any, len(row.Values))
+	copy(values, row.Values)
+	rowID := row.RowID
+	for i, set := range sets {
+		value, err := db.EvalExpr(set.Expr, &row)
+		if err != nil {
+			return err
+		}
+		value = targets[i].column.ApplyAffinity(value)
+		if !targets[i].isRowID {
+			values[targets[i].column.Idx] = value
+			continue
+		}
+		newRowID, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("datatype mismatch")
+		}
+		rowID = newRowID
+	}
+
+	if rowID != row.RowID {
+		cursor := tree.cursor()
+		found, err := cursor.SeekRowID(rowID)
+		cursor.Close()
+		if err != nil {
+			return err
+		}
+		if found {
+			name := "rowid"
+			if column, ok := tableInfo.RowIDColumn(); ok {
+				name = column.Name
+			}
+			return fmt.Errorf("UNIQUE constraint failed: %s.%s", tableInfo.Name, name)
+		}
+	}
+
+	// the old keys are removed first so a row doesn't conflict with itself
+	keys := make([][]any, len(indexes))
+	for i, index := range indexes {
+		oldKey, err := tableInfo.IndexKey(index, row.Values, row.RowID)
+		if err != nil {
+			return err
+		}
+		if err := index.Delete(oldKey); err != nil {
+			return err
+		}
+		if keys[i], err = tableInfo.IndexKey(index, values, rowID); err != nil {
+			return err
+		}
+		if err := checkUnique(tableInfo, index, keys[i]); err != nil {
+			return err
+		}
+	}
+
+	if err := tree.update(row, rowID, values, database.Header().TextEncoding); err != nil {
+		return err
+	}
+	for i, index := range indexes {
+		if err := index.Insert(keys[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isRowIDAlias(name string) bool {
+	switch strings.ToLower(name) {
+	case "rowid", "oid", "_rowid_":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,11 @@
+package executor
+
+import "github.com/adzimzf/sqlite-go/db"
+
+// ExecuteVacuum rebuilds the database in place, or writes the rebuilt copy to the file of VACUUM INTO.
+func ExecuteVacuum(database *db.DB, info db.QueryInfo) error {
+	if info.VacuumInto != "" {
+		return database.VacuumInto(info.VacuumInto)
+	}
+	return database.Vacuum()
+}
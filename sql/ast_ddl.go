@@ -1,16 +1,25 @@
 package sql
 
 // DDL represents a CREATE, ALTER, DROP, RENAME or TRUNCATE statement.
-// Table is set for AlterStr, DropStr, RenameStr, TruncateStr
+// Table is set for AlterStr, DropStr, RenameStr, TruncateStr, RenameColumnStr, AddColumnStr, DropColumnStr
 // NewName is set for AlterStr, CreateStr, RenameStr.
 // VindexSpec is set for CreateVindexStr, DropVindexStr, AddColVindexStr, DropColVindexStr
 // VindexCols is set for AddColVindexStr
+// IfNotExists is set for CreateStr.
+// IfExists is set for DropStr, DropIndexStr.
+// IndexSpec is set for CreateIndexStr and its name only for DropIndexStr.
+// TableSpec is set for CreateStr and holds the new column for AddColumnStr.
+// Column is set for RenameColumnStr, DropColumnStr and NewColumn for RenameColumnStr.
 type DDL struct {
-	Action    string
-	Table     TableName
-	NewName   TableName
-	IfExists  bool
-	TableSpec *TableSpec
+	Action      string
+	Table       TableName
+	NewName     TableName
+	IfExists    bool
+	IfNotExists bool
+	TableSpec   *TableSpec
+	IndexSpec   *IndexSpec
+	Column      ColIdent
+	NewColumn   ColIdent
 	//PartitionSpec *PartitionSpec
 	//VindexSpec    *VindexSpec
 	VindexCols []ColIdent
@@ -23,9 +32,14 @@ func (node *DDL) iStatement() {
 // DDL strings.
 const (
 	CreateStr        = "create"
+	CreateIndexStr   = "create index"
 	AlterStr         = "alter"
 	DropStr          = "drop"
+	DropIndexStr     = "drop index"
 	RenameStr        = "rename"
+	RenameColumnStr  = "rename column"
+	AddColumnStr     = "add column"
+	DropColumnStr    = "drop column"
 	TruncateStr      = "truncate"
 	CreateVindexStr  = "create vindex"
 	AddColVindexStr  = "add vindex"
@@ -50,7 +64,11 @@ func (node *DDL) walkSubtree(visit Visit) error {
 type TableSpec struct {
 	Columns []*ColumnDefinition
 	//Indexes []*IndexDefinition
-	Options string
+	// Constraints are the PRIMARY KEY and UNIQUE table constraints written after the columns
+	Constraints []*TableConstraint
+	Options     string
+	// WithoutRowID is set by the WITHOUT ROWID table option
+	WithoutRowID BoolVal
 }
 
 // AddColumn appends the given column to the list in the spec
@@ -58,6 +76,11 @@ func (ts *TableSpec) AddColumn(cd *ColumnDefinition) {
 	ts.Columns = append(ts.Columns, cd)
 }
 
+// AddConstraint appends the given table constraint to the list in the spec
+func (ts *TableSpec) AddConstraint(tc *TableConstraint) {
+	ts.Constraints = append(ts.Constraints, tc)
+}
+
 // AddIndex appends the given index to the list in the spec
 //func (ts *TableSpec) AddIndex(id *IndexDefinition) {
 //	ts.Indexes = append(ts.Indexes, id)
@@ -74,6 +97,12 @@ func (ts *TableSpec) walkSubtree(visit Visit) error {
 		}
 	}
 
+	for _, n := range ts.Constraints {
+		if err := Walk(visit, n); err != nil {
+			return err
+		}
+	}
+
 	//for _, n := range ts.Indexes {
 	//	if err := Walk(visit, n); err != nil {
 	//		return err
@@ -83,6 +112,59 @@ func (ts *TableSpec) walkSubtree(visit Visit) error {
 	return nil
 }
 
+// IndexSpec describes the index from a CREATE INDEX statement,
+// the indexed table is stored in DDL.Table
+type IndexSpec struct {
+	Name    ColIdent
+	Unique  BoolVal
+	Columns []*IndexColumn
+}
+
+func (is *IndexSpec) walkSubtree(visit Visit) error {
+	if is == nil {
+		return nil
+	}
+
+	for _, n := range is.Columns {
+		if err := Walk(visit, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexColumn describes a column of an index
+type IndexColumn struct {
+	Column ColIdent
+	Desc   BoolVal
+}
+
+func (ic *IndexColumn) walkSubtree(visit Visit) error {
+	if ic == nil {
+		return nil
+	}
+	return Walk(visit, ic.Column)
+}
+
+// TableConstraint describes a PRIMARY KEY or a UNIQUE constraint on columns of a table
+type TableConstraint struct {
+	PrimaryKey BoolVal
+	Columns    []*IndexColumn
+}
+
+func (tc *TableConstraint) walkSubtree(visit Visit) error {
+	if tc == nil {
+		return nil
+	}
+
+	for _, n := range tc.Columns {
+		if err := Walk(visit, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ColumnDefinition describes a column in a CREATE TABLE statement
 type ColumnDefinition struct {
 	Name ColIdent
@@ -289,7 +371,7 @@ const (
 	ColKeyNone ColumnKeyOption = iota
 	ColKeyPrimary
 	colKeySpatialKey
-	colKeyUnique
+	ColKeyUnique
 	colKeyUniqueKey
 	colKey
 )
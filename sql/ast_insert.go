@@ -0,0 +1,65 @@
+package sql
+
+// Insert represents an INSERT statement.
+type Insert struct {
+	Table   TableName
+	Columns Columns
+	Rows    Values
+}
+
+func (node *Insert) iStatement() {}
+
+func (node *Insert) walkSubtree(visit Visit) error {
+	if node == nil {
+		return nil
+	}
+	return Walk(
+		visit,
+		node.Table,
+		node.Columns,
+		node.Rows,
+	)
+}
+
+// Columns represents an insert column list.
+type Columns []ColIdent
+
+func (node Columns) walkSubtree(visit Visit) error {
+	for _, n := range node {
+		if err := Walk(visit, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Values represents a VALUES clause.
+type Values []ValTuple
+
+func (node Values) walkSubtree(visit Visit) error {
+	for _, n := range node {
+		if err := Walk(visit, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValTuple represents a tuple of actual values.
+type ValTuple Exprs
+
+func (node ValTuple) walkSubtree(visit Visit) error {
+	return Walk(visit, Exprs(node))
+}
+
+// Exprs represents a list of value expressions.
+type Exprs []Expr
+
+func (node Exprs) walkSubtree(visit Visit) error {
+	for _, n := range node {
+		if err := Walk(visit, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
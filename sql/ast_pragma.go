@@ -0,0 +1,21 @@
+package sql
+
+// Pragma represents a PRAGMA statement, Value is nil when the pragma is only queried.
+// The keywords and the names used as values are column names, e.g. WAL in journal_mode = WAL.
+type Pragma struct {
+	Name  ColIdent
+	Value Expr
+}
+
+func (node *Pragma) iStatement() {}
+
+func (node *Pragma) walkSubtree(visit Visit) error {
+	if node == nil {
+		return nil
+	}
+	return Walk(
+		visit,
+		node.Name,
+		node.Value,
+	)
+}
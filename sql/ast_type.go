@@ -56,6 +56,8 @@ func NewValArg(in []byte) *SQLVal {
 	return &SQLVal{Type: ValArg, Val: in}
 }
 
+func (node *SQLVal) iExpr() {}
+
 func (node *SQLVal) walkSubtree(visit Visit) error {
 	return nil
 }
@@ -74,6 +76,19 @@ func (node *SQLVal) HexDecode() ([]byte, error) {
 	return dst, err
 }
 
+// NullVal represents a NULL value.
+type NullVal struct{}
+
+func (node *NullVal) iExpr() {}
+
+func (node *NullVal) walkSubtree(visit Visit) error {
+	return nil
+}
+
+func (node *NullVal) replace(from, to Expr) bool {
+	return false
+}
+
 // ValType specifies the type for SQLVal.
 type ValType int
 
@@ -0,0 +1,70 @@
+package sql
+
+// Delete represents a DELETE statement.
+type Delete struct {
+	Table TableName
+	Where *Where
+}
+
+func (node *Delete) iStatement() {}
+
+func (node *Delete) walkSubtree(visit Visit) error {
+	if node == nil {
+		return nil
+	}
+	return Walk(
+		visit,
+		node.Table,
+		node.Where,
+	)
+}
+
+// Update represents an UPDATE statement.
+type Update struct {
+	Table TableName
+	Exprs UpdateExprs
+	Where *Where
+}
+
+func (node *Update) iStatement() {}
+
+func (node *Update) walkSubtree(visit Visit) error {
+	if node == nil {
+		return nil
+	}
+	return Walk(
+		visit,
+		node.Table,
+		node.Exprs,
+		node.Where,
+	)
+}
+
+// UpdateExprs represents a list of update expressions.
+type UpdateExprs []*UpdateExpr
+
+func (node UpdateExprs) walkSubtree(visit Visit) error {
+	for _, n := range node {
+		if err := Walk(visit, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpdateExpr represents an update expression.
+type UpdateExpr struct {
+	Name ColIdent
+	Expr Expr
+}
+
+func (node *UpdateExpr) walkSubtree(visit Visit) error {
+	if node == nil {
+		return nil
+	}
+	return Walk(
+		visit,
+		node.Name,
+		node.Expr,
+	)
+}
@@ -0,0 +1,13 @@
+package sql
+
+// Vacuum represents a VACUUM statement, Into is the file the compacted copy is written to,
+// it's empty when the database is rebuilt in place.
+type Vacuum struct {
+	Into string
+}
+
+func (node *Vacuum) iStatement() {}
+
+func (node *Vacuum) walkSubtree(visit Visit) error {
+	return nil
+}
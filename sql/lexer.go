@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 )
 
@@ -44,6 +43,9 @@ func (l *Lexer) Lex(lval *yySymType) int {
 
 func (l *Lexer) Scan(lval *yySymType) (int, string) {
 	switch ch := l.input[l.pos]; {
+	case (ch == 'x' || ch == 'X') && l.peek(1) == '\'':
+		l.pos++
+		return l.scanString(HEXBLOB)
 	case isLetter(rune(ch)):
 		return l.scanToken(l.pos)
 	default:
@@ -60,24 +62,120 @@ func (l *Lexer) Scan(lval *yySymType) (int, string) {
 			return RPAREN, ")"
 
 		// reserved token
-		case '=', ',', ';', '+', '%', '^', '~':
-			token, ok := tokens[string(ch)]
-			if !ok {
-				return 0, strconv.Itoa(token)
+		case ',':
+			l.pos++
+			return COMMA, ","
+		case ';', '+', '-', '/', '%':
+			l.pos++
+			return int(ch), string(ch)
+		case '=':
+			l.pos++
+			if l.peek(0) == '=' {
+				l.pos++
+			}
+			return int(ch), "="
+		case '<':
+			switch l.peek(1) {
+			case '=':
+				l.pos += 2
+				return LE, "<="
+			case '>':
+				l.pos += 2
+				return NE, "<>"
 			}
 			l.pos++
-			l.lastToken = []byte(string(ch))
-			return token, string(l.lastToken)
+			return int(ch), "<"
+		case '>':
+			if l.peek(1) == '=' {
+				l.pos += 2
+				return GE, ">="
+			}
+			l.pos++
+			return int(ch), ">"
+		case '!':
+			if l.peek(1) == '=' {
+				l.pos += 2
+				return NE, "!="
+			}
 
+		case '\'':
+			return l.scanString(STRING)
+		case '"', '`':
+			return l.scanString(IDENTIFIER)
 		case '.':
-			if isDigit(rune(ch)) {
-				//return tkn.scanNumber(true)
+			if isDigit(rune(l.peek(1))) {
+				return l.scanNumber()
 			}
 			l.pos++
 			return int(ch), "."
 		}
+		if isDigit(rune(ch)) {
+			return l.scanNumber()
+		}
+		// an unknown character is a syntax error, it must not end the statement early
+		l.pos++
+		return LEX_ERROR, string(ch)
+	}
+}
+
+// peek returns the byte n positions after the current one, 0 at the end of the input.
+func (l *Lexer) peek(n int) byte {
+	if l.eof(l.pos + n) {
+		return 0
+	}
+	return l.input[l.pos+n]
+}
+
+// scanString scans a quoted literal or a quoted name, a quote inside is escaped by doubling it.
+func (l *Lexer) scanString(token int) (int, string) {
+	var buf strings.Builder
+	quote := l.input[l.pos]
+	for pos := l.pos + 1; !l.eof(pos); pos++ {
+		if l.input[pos] != quote {
+			buf.WriteByte(l.input[pos])
+			continue
+		}
+		if !l.eof(pos+1) && l.input[pos+1] == quote {
+			buf.WriteByte(quote)
+			pos++
+			continue
+		}
+		l.pos = pos + 1
+		return token, buf.String()
+	}
+	// the quote isn't closed
+	start := l.pos
+	l.pos = len(l.input)
+	return LEX_ERROR, l.input[start:]
+}
+
+// scanNumber scans an integer or a real literal, a real has a decimal point or an exponent.
+func (l *Lexer) scanNumber() (int, string) {
+	start, token := l.pos, INTEGRAL
+	for isDigit(rune(l.peek(0))) {
+		l.pos++
+	}
+	if l.peek(0) == '.' {
+		token = FLOAT
+		l.pos++
+		for isDigit(rune(l.peek(0))) {
+			l.pos++
+		}
+	}
+	if ch := l.peek(0); ch == 'e' || ch == 'E' {
+		exponent := 1
+		if sign := l.peek(1); sign == '+' || sign == '-' {
+			exponent = 2
+		}
+		if isDigit(rune(l.peek(exponent))) {
+			token = FLOAT
+			l.pos += exponent
+			for isDigit(rune(l.peek(0))) {
+				l.pos++
+			}
+		}
 	}
-	return -1, ""
+	return token, l.input[start:l.pos]
 }
 
 func (l *Lexer) Error(err string) {
@@ -133,3 +231,37 @@ func Parse(sql string) (Statement, error) {
 	}
 	return lexer.ParseTree, nil
 }
+
+// Token is a token of a statement and its position in the text of the statement.
+type Token struct {
+	ID    int    // IDENTIFIER, a keyword, a literal or the character of an operator
+	Value string // the names and the literals are unquoted
+	Start int
+	End   int
+}
+
+// Tokenize splits the statement into its tokens, it's used to edit the text of a statement.
+func Tokenize(sql string) ([]Token, error) {
+	lexer := NewLexer(sql)
+	var result []Token
+	var lval yySymType
+	for {
+		for !lexer.eof(lexer.pos) && isWhitespace(rune(lexer.input[lexer.pos])) {
+			lexer.pos++
+		}
+		start := lexer.pos
+		id := lexer.Lex(&lval)
+		if id == 0 {
+			return result, nil
+		}
+		if id == LEX_ERROR {
+			return nil, fmt.Errorf("unrecognized token: %s", lval.str)
+		}
+		result = append(result, Token{ID: id, Value: lval.str, Start: start, End: lexer.pos})
+	}
+}
+
+// QuoteIdentifier returns the name between double quotes, the way sqlite writes the names it edits.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -3,6 +3,8 @@ package sql
 
 import __yyfmt__ "fmt"
 
+import "strings"
+
 func setParseTree(yylex interface{}, stmt Statement) {
 	yylex.(*Lexer).ParseTree = stmt
 }
@@ -31,8 +33,18 @@ type yySymType struct {
 	aliasedTableName *AliasedTableExpr
 	columnDefinition *ColumnDefinition
 	columnType       ColumnType
-	colKeyOpt        ColumnKeyOption
+	tableConstraint  *TableConstraint
+	indexSpec        *IndexSpec
+	indexColumns     []*IndexColumn
+	indexColumn      *IndexColumn
 	ddl              *DDL
+	columns          Columns
+	values           Values
+	valTuple         ValTuple
+	exprs            Exprs
+	where            *Where
+	updateExprs      UpdateExprs
+	updateExpr       *UpdateExpr
 	optVal           *SQLVal
 	boolVal          BoolVal
 
@@ -40,29 +52,81 @@ type yySymType struct {
 	str    string
 }
 
-const SELECT = 57346
-const FROM = 57347
-const COMMA = 57348
-const AS = 57349
-const LPAREN = 57350
-const RPAREN = 57351
-const COUNT = 57352
-const INTEGER = 57353
-const TEXT = 57354
-const BLOB = 57355
-const CREATE = 57356
-const PRIMARY = 57357
-const KEY = 57358
-const AUTOINCREMENT = 57359
-const TABLE = 57360
-const STAR = 57361
-const IDENTIFIER = 57362
-const STRING = 57363
+const OR = 57346
+const AND = 57347
+const NOT = 57348
+const NE = 57349
+const IS = 57350
+const LE = 57351
+const GE = 57352
+const STAR = 57353
+const UNARY = 57354
+const SELECT = 57355
+const FROM = 57356
+const COMMA = 57357
+const AS = 57358
+const LPAREN = 57359
+const RPAREN = 57360
+const COUNT = 57361
+const INTEGER = 57362
+const TEXT = 57363
+const BLOB = 57364
+const CREATE = 57365
+const PRIMARY = 57366
+const KEY = 57367
+const AUTOINCREMENT = 57368
+const TABLE = 57369
+const INDEX = 57370
+const UNIQUE = 57371
+const ON = 57372
+const ASC = 57373
+const DESC = 57374
+const INSERT = 57375
+const INTO = 57376
+const VALUES = 57377
+const NULL = 57378
+const DELETE = 57379
+const UPDATE = 57380
+const SET = 57381
+const WHERE = 57382
+const PRAGMA = 57383
+const VACUUM = 57384
+const IF = 57385
+const EXISTS = 57386
+const DROP = 57387
+const ALTER = 57388
+const RENAME = 57389
+const TO = 57390
+const ADD = 57391
+const COLUMN = 57392
+const WITHOUT = 57393
+const LEX_ERROR = 57394
+const IDENTIFIER = 57395
+const STRING = 57396
+const INTEGRAL = 57397
+const FLOAT = 57398
+const HEXBLOB = 57399
 
 var yyToknames = [...]string{
 	"$end",
 	"error",
 	"$unk",
+	"OR",
+	"AND",
+	"NOT",
+	"'='",
+	"NE",
+	"IS",
+	"'<'",
+	"'>'",
+	"LE",
+	"GE",
+	"'+'",
+	"'-'",
+	"STAR",
+	"'/'",
+	"'%'",
+	"UNARY",
 	"'.'",
 	"SELECT",
 	"FROM",
@@ -79,9 +143,36 @@ var yyToknames = [...]string{
 	"KEY",
 	"AUTOINCREMENT",
 	"TABLE",
-	"STAR",
+	"INDEX",
+	"UNIQUE",
+	"ON",
+	"ASC",
+	"DESC",
+	"INSERT",
+	"INTO",
+	"VALUES",
+	"NULL",
+	"DELETE",
+	"UPDATE",
+	"SET",
+	"WHERE",
+	"PRAGMA",
+	"VACUUM",
+	"IF",
+	"EXISTS",
+	"DROP",
+	"ALTER",
+	"RENAME",
+	"TO",
+	"ADD",
+	"COLUMN",
+	"WITHOUT",
+	"LEX_ERROR",
 	"IDENTIFIER",
 	"STRING",
+	"INTEGRAL",
+	"FLOAT",
+	"HEXBLOB",
 	"';'",
 }
 
@@ -95,96 +186,202 @@ var yyExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
-	-1, 23,
-	4, 18,
-	-2, 17,
+	-1, 60,
+	20, 45,
+	-2, 44,
 }
 
 const yyPrivate = 57344
 
-const yyLast = 80
-
-var yyAct = [...]int8{
-	21, 62, 49, 26, 20, 14, 32, 52, 22, 10,
-	63, 55, 35, 33, 40, 27, 35, 15, 23, 24,
-	73, 34, 72, 40, 70, 39, 35, 44, 45, 46,
-	42, 39, 38, 41, 7, 47, 65, 37, 34, 12,
-	53, 56, 60, 57, 8, 9, 58, 30, 29, 36,
-	1, 69, 71, 64, 66, 59, 43, 25, 11, 6,
-	51, 39, 50, 67, 64, 68, 48, 28, 61, 31,
-	54, 18, 19, 17, 16, 13, 5, 3, 4, 2,
+const yyLast = 362
+
+var yyAct = [...]uint8{
+	43, 209, 70, 198, 68, 52, 133, 148, 192, 130,
+	177, 41, 71, 101, 25, 178, 199, 170, 78, 35,
+	179, 33, 69, 113, 34, 151, 135, 191, 145, 193,
+	143, 65, 144, 203, 72, 193, 229, 141, 111, 53,
+	80, 188, 131, 31, 69, 75, 104, 105, 106, 103,
+	240, 201, 107, 33, 183, 73, 74, 32, 36, 29,
+	126, 127, 35, 204, 161, 82, 131, 79, 178, 32,
+	32, 112, 61, 179, 63, 37, 38, 136, 136, 32,
+	39, 134, 244, 241, 232, 205, 202, 142, 152, 153,
+	154, 155, 156, 157, 158, 159, 146, 162, 163, 164,
+	165, 166, 160, 45, 139, 140, 81, 190, 103, 210,
+	234, 47, 46, 42, 171, 233, 167, 222, 175, 32,
+	176, 172, 51, 22, 54, 32, 124, 76, 180, 239,
+	72, 237, 186, 23, 236, 185, 173, 182, 120, 121,
+	122, 59, 187, 15, 206, 77, 124, 16, 17, 231,
+	32, 18, 19, 194, 195, 20, 21, 129, 60, 55,
+	56, 57, 58, 87, 88, 109, 89, 90, 95, 91,
+	92, 93, 94, 96, 97, 98, 99, 100, 212, 124,
+	225, 211, 123, 102, 117, 115, 196, 116, 114, 28,
+	214, 200, 27, 30, 134, 213, 85, 84, 216, 217,
+	108, 218, 189, 220, 219, 98, 99, 100, 174, 223,
+	24, 227, 72, 45, 1, 132, 50, 215, 228, 226,
+	35, 47, 46, 208, 184, 230, 128, 235, 72, 125,
+	221, 32, 51, 64, 54, 238, 32, 110, 242, 200,
+	45, 62, 14, 243, 32, 138, 207, 181, 47, 46,
+	224, 59, 137, 96, 97, 98, 99, 100, 118, 51,
+	119, 54, 67, 66, 26, 13, 150, 149, 60, 55,
+	56, 57, 58, 147, 83, 197, 86, 169, 59, 87,
+	88, 48, 89, 90, 95, 91, 92, 93, 94, 96,
+	97, 98, 99, 100, 49, 60, 55, 56, 57, 58,
+	44, 168, 87, 88, 40, 89, 90, 95, 91, 92,
+	93, 94, 96, 97, 98, 99, 100, 88, 12, 89,
+	90, 95, 91, 92, 93, 94, 96, 97, 98, 99,
+	100, 89, 90, 95, 91, 92, 93, 94, 96, 97,
+	98, 99, 100, 91, 92, 93, 94, 96, 97, 98,
+	99, 100, 3, 11, 10, 9, 8, 7, 6, 5,
+	4, 2,
 }
 
 var yyPact = [...]int16{
-	29, -1000, -14, -1000, -1000, -1000, 30, -3, 0, -1000,
-	-1000, -1000, -6, 41, -1000, -1000, 5, -1000, -1000, -1000,
-	-1000, 45, 28, -1000, -7, 23, -1000, 15, -1000, -3,
-	-7, -1000, -1000, -5, -1000, -1000, -9, -3, -1000, -1000,
-	-1000, -1000, -6, -1000, -1000, -1000, -1000, -1000, 35, -1000,
-	-1000, -1000, 2, -1000, -1000, -1000, -1000, 26, -1000, -1000,
-	-7, -1000, -1000, -7, -1000, -1000, 8, -1000, -1000, 4,
-	3, -1000, -1000, -1000,
+	102, -32768, -52, -32768, -32768, -32768, -32768, -32768, -32768, -32768,
+	-32768, -32768, -32768, 167, 164, 17, 171, -40, -42, 16,
+	40, 45, 97, 37, -32768, -32768, -28, -39, -42, -40,
+	-40, -2, -32768, -32768, 120, -32768, -44, -11, -11, -40,
+	174, -32768, -32768, 159, -32768, 234, 234, 234, -32768, -32768,
+	-32768, 234, -32768, 180, 140, -32768, -32768, -32768, -32768, -32768,
+	-32768, -13, 35, -32768, -32768, -38, 162, 161, -32768, 110,
+	156, -32768, 21, 132, -6, -42, 207, 207, -32768, -40,
+	-15, -42, -25, -32768, 97, -40, -32768, 234, 234, 234,
+	234, 234, 234, 234, 234, 58, 234, 234, 234, 234,
+	234, -32768, -42, -32768, 324, -32768, -32768, 275, 1, 97,
+	-40, 202, -42, -32768, -32768, -17, -32768, 36, -32768, -32768,
+	-32768, -32768, -32768, -32768, -42, -32768, -32768, -32768, 11, -42,
+	-32768, 234, 18, -32768, 195, -32768, 298, -32768, -32768, 81,
+	-32768, -32768, -32768, -29, -23, -23, -32768, 163, -32768, -32768,
+	-32768, -8, 312, 324, 333, 333, 239, 239, 239, 239,
+	-32768, 7, 189, 189, -32768, -32768, -32768, -32768, -32768, -32768,
+	-32768, -32768, 60, -32768, -19, 25, -32768, -32768, 52, 119,
+	-32768, -32768, -32768, 84, 155, -32768, 298, -32768, -42, 234,
+	-32768, -40, -42, -32768, -39, -42, -40, -32768, -32768, -40,
+	-32768, -32768, -32768, -32768, -40, 92, -42, -32768, 157, -32768,
+	234, -32768, -42, -32768, 298, -32768, -20, -32768, -32768, -32768,
+	-32768, -32768, -42, 123, 78, 84, 108, 298, -32768, -42,
+	103, -32768, 6, -32768, 50, -32768, -32768, 234, -32768, -32768,
+	-32768, 48, 298, -32768, -32768,
 }
 
-var yyPgo = [...]int8{
-	0, 79, 78, 77, 76, 75, 5, 74, 73, 72,
-	71, 4, 70, 69, 6, 0, 1, 68, 67, 66,
-	2, 62, 7, 60, 3, 59, 58, 57, 56, 55,
-	54, 52, 51, 50, 45,
+var yyPgo = [...]int16{
+	0, 361, 360, 359, 358, 357, 356, 355, 354, 353,
+	26, 352, 318, 304, 11, 0, 300, 294, 281, 5,
+	277, 276, 13, 39, 3, 275, 274, 273, 7, 267,
+	25, 266, 4, 265, 264, 263, 262, 10, 260, 258,
+	250, 247, 246, 243, 242, 241, 237, 67, 233, 229,
+	2, 12, 226, 224, 223, 1, 219, 216, 9, 215,
+	6, 214, 210, 8,
 }
 
 var yyR1 = [...]int8{
-	0, 33, 34, 34, 1, 1, 3, 4, 5, 5,
-	6, 6, 7, 8, 8, 10, 10, 11, 15, 12,
-	12, 13, 13, 13, 14, 9, 18, 18, 19, 19,
-	20, 21, 23, 22, 17, 17, 17, 16, 2, 25,
-	26, 27, 27, 24, 28, 28, 28, 29, 30, 32,
-	32, 31, 31,
+	0, 61, 62, 62, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 11, 12, 13, 13, 14, 14, 15,
+	15, 15, 15, 15, 15, 15, 15, 15, 15, 15,
+	15, 15, 15, 15, 15, 15, 15, 15, 16, 16,
+	16, 16, 18, 18, 19, 23, 20, 20, 21, 21,
+	21, 22, 17, 26, 26, 27, 27, 28, 29, 31,
+	30, 25, 25, 25, 24, 3, 52, 52, 53, 53,
+	54, 54, 55, 56, 56, 57, 57, 57, 57, 57,
+	4, 5, 59, 59, 60, 6, 6, 6, 7, 7,
+	10, 10, 10, 58, 58, 2, 2, 33, 46, 46,
+	48, 48, 47, 47, 8, 8, 9, 9, 9, 9,
+	63, 63, 40, 40, 40, 40, 44, 45, 45, 50,
+	50, 51, 49, 49, 49, 34, 34, 35, 35, 36,
+	36, 37, 37, 32, 39, 39, 38, 38, 38, 41,
+	42, 43, 43,
 }
 
 var yyR2 = [...]int8{
-	0, 2, 0, 1, 1, 1, 1, 3, 1, 3,
-	1, 2, 1, 1, 1, 1, 3, 1, 1, 1,
-	1, 0, 1, 2, 1, 4, 0, 2, 1, 3,
-	1, 1, 2, 1, 0, 1, 2, 1, 2, 3,
-	3, 1, 3, 6, 1, 1, 1, 0, 0, 0,
-	2, 0, 1,
+	0, 2, 0, 1, 1, 1, 1, 1, 1, 1,
+	1, 1, 1, 1, 3, 1, 3, 1, 2, 1,
+	3, 3, 2, 3, 3, 3, 3, 3, 3, 3,
+	4, 3, 3, 3, 3, 3, 2, 2, 1, 1,
+	1, 3, 1, 3, 1, 1, 1, 1, 0, 1,
+	2, 1, 4, 0, 2, 1, 3, 1, 1, 2,
+	1, 0, 1, 2, 1, 6, 0, 3, 1, 3,
+	1, 3, 3, 1, 3, 1, 1, 1, 1, 1,
+	4, 5, 1, 3, 3, 2, 4, 5, 1, 3,
+	1, 1, 1, 0, 2, 3, 4, 4, 0, 3,
+	0, 2, 0, 2, 4, 4, 6, 8, 6, 6,
+	0, 1, 0, 3, 2, 4, 6, 0, 1, 1,
+	3, 2, 0, 1, 1, 3, 3, 1, 3, 3,
+	3, 5, 4, 5, 0, 1, 1, 1, 1, 0,
+	0, 0, 1,
 }
 
 var yyChk = [...]int16{
-	-1000, -33, -1, -3, -2, -4, -25, 5, 15, -34,
-	23, -26, 9, -5, -6, 20, -7, -8, -10, -9,
-	-11, -15, 11, 21, 19, -27, -24, 21, -18, 7,
-	6, -13, -14, 8, -11, 21, 4, 9, -22, -15,
-	21, 10, 7, -28, 12, 13, 14, -6, -19, -20,
-	-21, -23, -22, -14, -12, 20, -11, -6, -24, -29,
-	7, -17, -16, 8, -15, 10, -30, -20, -16, -32,
-	16, -31, 18, 17,
+	-32768, -61, -1, -11, -2, -3, -4, -5, -6, -7,
+	-8, -9, -12, -33, -44, 41, 45, 46, 49, 50,
+	53, 54, 21, 31, -62, 66, -34, 25, 25, 42,
+	22, -30, -23, 61, -19, 61, 42, 35, 36, 35,
+	-13, -14, 16, -15, -16, 6, 15, 14, -18, -17,
+	-57, 25, -19, -23, 27, 62, 63, 64, 65, 44,
+	61, 35, -45, 37, -48, 59, -35, -36, -32, 61,
+	-50, -51, -19, -30, -30, 47, 7, 25, 62, -47,
+	51, -47, -30, -26, 23, 22, -21, 4, 5, 7,
+	8, 10, 11, 12, 13, 9, 14, 15, 16, 17,
+	18, -22, 24, -19, -15, -15, -15, -15, 20, 25,
+	-46, 51, 36, 61, 26, 23, 26, 23, -39, -38,
+	28, 29, 30, 26, 23, -49, 39, 40, -52, 25,
+	-58, 48, -59, -60, -19, -10, -15, 45, 38, -10,
+	-30, 52, -19, 55, 57, 53, -14, -27, -28, -29,
+	-31, -30, -15, -15, -15, -15, -15, -15, -15, -15,
+	44, 6, -15, -15, -15, -15, -15, -22, 26, -20,
+	16, -19, -14, -30, 6, -19, -32, -37, 32, 37,
+	-37, -41, -51, 43, -53, -19, -15, -58, 23, 7,
+	26, 56, -63, 58, -63, -63, 23, -25, -24, 24,
+	-23, 44, 26, 52, 38, 33, 25, -42, -54, -55,
+	25, 26, 23, -60, -15, -30, -19, -32, -19, -28,
+	-24, -30, 25, -50, -40, 23, -56, -15, -19, 56,
+	-50, 26, 6, 37, 32, -55, 26, 23, -19, 26,
+	44, 33, -15, -43, 34,
 }
 
-var yyDef = [...]int8{
-	0, -2, 2, 4, 5, 6, 0, 0, 0, 1,
-	3, 38, 0, 26, 8, 10, 21, 12, 13, 14,
-	15, 0, 0, -2, 0, 0, 41, 0, 7, 0,
-	0, 11, 22, 0, 24, 17, 0, 0, 39, 33,
-	18, 40, 0, 47, 44, 45, 46, 9, 27, 28,
-	30, 31, 34, 23, 16, 19, 20, 0, 42, 48,
-	0, 32, 35, 0, 37, 25, 49, 29, 36, 51,
-	0, 43, 52, 50,
+var yyDef = [...]int16{
+	0, -2, 2, 4, 5, 6, 7, 8, 9, 10,
+	11, 12, 13, 0, 0, 0, 0, 0, 0, 88,
+	0, 0, 0, 117, 1, 3, 100, 0, 0, 0,
+	0, 0, 60, 45, 85, 44, 0, 102, 102, 0,
+	53, 15, 17, 48, 19, 0, 0, 0, 38, 39,
+	40, 0, 42, 0, 0, 75, 76, 77, 78, 79,
+	-2, 98, 0, 118, 95, 0, 0, 0, 127, 134,
+	0, 119, 122, 66, 93, 0, 0, 0, 89, 0,
+	0, 0, 0, 14, 0, 0, 18, 0, 0, 0,
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	0, 49, 0, 51, 22, 36, 37, 0, 0, 0,
+	0, 0, 0, 101, 125, 0, 126, 0, 139, 135,
+	136, 137, 138, 96, 0, 121, 123, 124, 0, 0,
+	80, 0, 93, 82, 0, 86, 90, 91, 92, 0,
+	104, 103, 105, 110, 110, 110, 16, 54, 55, 57,
+	58, 61, 20, 21, 23, 24, 25, 26, 27, 28,
+	29, 0, 31, 32, 33, 34, 35, 50, 41, 43,
+	46, 47, 0, 97, 0, 0, 128, 129, 0, 0,
+	130, 140, 120, 0, 0, 68, 94, 81, 0, 0,
+	87, 0, 0, 111, 0, 0, 0, 59, 62, 0,
+	64, 30, 52, 99, 0, 0, 0, 112, 65, 70,
+	0, 67, 0, 83, 84, 106, 0, 108, 109, 56,
+	63, 116, 0, 0, 133, 0, 0, 73, 69, 0,
+	0, 132, 0, 114, 0, 71, 72, 0, 107, 131,
+	113, 141, 74, 115, 142,
 }
 
 var yyTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 4, 3, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 3, 23,
+	3, 3, 3, 3, 3, 3, 3, 18, 3, 3,
+	3, 3, 3, 14, 3, 15, 20, 17, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 3, 66,
+	10, 7, 11,
 }
 
 var yyTok2 = [...]int8{
-	2, 3, 5, 6, 7, 8, 9, 10, 11, 12,
-	13, 14, 15, 16, 17, 18, 19, 20, 21, 22,
+	2, 3, 4, 5, 6, 8, 9, 12, 13, 16,
+	19, 21, 22, 23, 24, 25, 26, 27, 28, 29,
+	30, 31, 32, 33, 34, 35, 36, 37, 38, 39,
+	40, 41, 42, 43, 44, 45, 46, 47, 48, 49,
+	50, 51, 52, 53, 54, 55, 56, 57, 58, 59,
+	60, 61, 62, 63, 64, 65,
 }
 
 var yyTok3 = [...]int8{
@@ -228,7 +425,7 @@ func yyNewParser() yyParser {
 	return &yyParserImpl{}
 }
 
-const yyFlag = -1000
+const yyFlag = -32768
 
 func yyTokname(c int) string {
 	if c >= 1 && c-1 < len(yyToknames) {
@@ -544,245 +741,669 @@ yydefault:
 		{
 			yyVAL.statement = yyDollar[1].selStmt
 		}
-	case 6:
+	case 13:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			sel := yyDollar[1].selStmt.(*Select)
 			yyVAL.selStmt = sel
 		}
-	case 7:
+	case 14:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
 			yyVAL.selStmt = &Select{SelectExprs: yyDollar[2].selectExprs, From: yyDollar[3].tableExprs}
 		}
-	case 8:
+	case 15:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.selectExprs = SelectExprs{yyDollar[1].selectExpr}
 		}
-	case 9:
+	case 16:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
 			yyVAL.selectExprs = append(yyVAL.selectExprs, yyDollar[3].selectExpr)
 		}
-	case 10:
+	case 17:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.selectExpr = &StarExpr{}
 		}
-	case 11:
+	case 18:
 		yyDollar = yyS[yypt-2 : yypt+1]
 		{
 			yyVAL.selectExpr = &AliasedExpr{Expr: yyDollar[1].expr, As: yyDollar[2].colIdent}
 		}
-	case 12:
+	case 19:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
-	case 13:
+	case 20:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &OrExpr{Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+		}
+	case 21:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &AndExpr{Left: yyDollar[1].expr, Right: yyDollar[3].expr}
+		}
+	case 22:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = &NotExpr{Expr: yyDollar[2].expr}
+		}
+	case 23:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &ComparisonExpr{Left: yyDollar[1].expr, Operator: EqualStr, Right: yyDollar[3].expr}
+		}
+	case 24:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &ComparisonExpr{Left: yyDollar[1].expr, Operator: NotEqualStr, Right: yyDollar[3].expr}
+		}
+	case 25:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &ComparisonExpr{Left: yyDollar[1].expr, Operator: LessThanStr, Right: yyDollar[3].expr}
+		}
+	case 26:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &ComparisonExpr{Left: yyDollar[1].expr, Operator: GreaterThanStr, Right: yyDollar[3].expr}
+		}
+	case 27:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &ComparisonExpr{Left: yyDollar[1].expr, Operator: LessEqualStr, Right: yyDollar[3].expr}
+		}
+	case 28:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &ComparisonExpr{Left: yyDollar[1].expr, Operator: GreaterEqualStr, Right: yyDollar[3].expr}
+		}
+	case 29:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &IsExpr{Operator: IsNullStr, Expr: yyDollar[1].expr}
+		}
+	case 30:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.expr = &IsExpr{Operator: IsNotNullStr, Expr: yyDollar[1].expr}
+		}
+	case 31:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &BinaryExpr{Left: yyDollar[1].expr, Operator: PlusStr, Right: yyDollar[3].expr}
+		}
+	case 32:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &BinaryExpr{Left: yyDollar[1].expr, Operator: MinusStr, Right: yyDollar[3].expr}
+		}
+	case 33:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &BinaryExpr{Left: yyDollar[1].expr, Operator: MultStr, Right: yyDollar[3].expr}
+		}
+	case 34:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &BinaryExpr{Left: yyDollar[1].expr, Operator: DivStr, Right: yyDollar[3].expr}
+		}
+	case 35:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &BinaryExpr{Left: yyDollar[1].expr, Operator: ModStr, Right: yyDollar[3].expr}
+		}
+	case 36:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = &UnaryExpr{Operator: UMinusStr, Expr: yyDollar[2].expr}
+		}
+	case 37:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.expr = &UnaryExpr{Operator: UPlusStr, Expr: yyDollar[2].expr}
+		}
+	case 38:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.expr = yyDollar[1].colName
 		}
-	case 14:
+	case 39:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.expr = yyDollar[1].expr
 		}
-	case 15:
+	case 40:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 41:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.expr = &ParenExpr{Expr: yyDollar[2].expr}
+		}
+	case 42:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.colName = &ColName{Name: yyDollar[1].colIdent}
 		}
-	case 16:
+	case 43:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
 			yyVAL.colName = &ColName{Qualifier: TableName{Name: yyDollar[1].tableIdent}, Name: yyDollar[3].colIdent}
 		}
-	case 17:
+	case 44:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.colIdent = NewColIdent(string(yyDollar[1].str))
 		}
-	case 18:
+	case 45:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.tableIdent = NewTableIdent(string(yyDollar[1].str))
 		}
-	case 19:
+	case 46:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.colIdent = NewColIdent(string('*'))
 		}
-	case 20:
+	case 47:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.colIdent = yyDollar[1].colIdent
 		}
-	case 21:
+	case 48:
 		yyDollar = yyS[yypt-0 : yypt+1]
 		{
 			yyVAL.colIdent = ColIdent{}
 		}
-	case 22:
+	case 49:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.colIdent = yyDollar[1].colIdent
 		}
-	case 23:
+	case 50:
 		yyDollar = yyS[yypt-2 : yypt+1]
 		{
 			yyVAL.colIdent = yyDollar[2].colIdent
 		}
-	case 24:
+	case 51:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.colIdent = yyDollar[1].colIdent
 		}
-	case 25:
+	case 52:
 		yyDollar = yyS[yypt-4 : yypt+1]
 		{
 			yyVAL.expr = &FuncExpr{Name: NewColIdent("count"), Exprs: NewSelectExprs(yyDollar[3].selectExpr)}
 		}
-	case 26:
+	case 53:
 		yyDollar = yyS[yypt-0 : yypt+1]
 		{
 			yyVAL.tableExprs = TableExprs{&AliasedTableExpr{Expr: TableName{Name: NewTableIdent("dual")}}}
 		}
-	case 27:
+	case 54:
 		yyDollar = yyS[yypt-2 : yypt+1]
 		{
 			yyVAL.tableExprs = yyDollar[2].tableExprs
 		}
-	case 28:
+	case 55:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.tableExprs = TableExprs{yyDollar[1].tableExpr}
 		}
-	case 29:
+	case 56:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
 			yyVAL.tableExprs = append(yyVAL.tableExprs, yyDollar[3].tableExpr)
 		}
-	case 30:
+	case 57:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.tableExpr = yyDollar[1].tableExpr
 		}
-	case 31:
+	case 58:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.tableExpr = yyDollar[1].aliasedTableName
 		}
-	case 32:
+	case 59:
 		yyDollar = yyS[yypt-2 : yypt+1]
 		{
 			yyVAL.aliasedTableName = &AliasedTableExpr{Expr: yyDollar[1].tableName, As: yyDollar[2].tableIdent}
 		}
-	case 33:
+	case 60:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.tableName = TableName{Name: yyDollar[1].tableIdent}
 		}
-	case 34:
+	case 61:
 		yyDollar = yyS[yypt-0 : yypt+1]
 		{
 			yyVAL.tableIdent = NewTableIdent("")
 		}
-	case 35:
+	case 62:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.tableIdent = yyDollar[1].tableIdent
 		}
-	case 36:
+	case 63:
 		yyDollar = yyS[yypt-2 : yypt+1]
 		{
 			yyVAL.tableIdent = yyDollar[2].tableIdent
 		}
-	case 37:
+	case 64:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.tableIdent = yyDollar[1].tableIdent
 		}
-	case 38:
+	case 65:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.statement = &Insert{Table: yyDollar[3].tableName, Columns: yyDollar[4].columns, Rows: yyDollar[6].values}
+		}
+	case 66:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.columns = nil
+		}
+	case 67:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.columns = yyDollar[2].columns
+		}
+	case 68:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.columns = Columns{yyDollar[1].colIdent}
+		}
+	case 69:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.columns = append(yyVAL.columns, yyDollar[3].colIdent)
+		}
+	case 70:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.values = Values{yyDollar[1].valTuple}
+		}
+	case 71:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.values = append(yyVAL.values, yyDollar[3].valTuple)
+		}
+	case 72:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.valTuple = ValTuple(yyDollar[2].exprs)
+		}
+	case 73:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.exprs = Exprs{yyDollar[1].expr}
+		}
+	case 74:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.exprs = append(yyVAL.exprs, yyDollar[3].expr)
+		}
+	case 75:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewStrVal([]byte(yyDollar[1].str))
+		}
+	case 76:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewIntVal([]byte(yyDollar[1].str))
+		}
+	case 77:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewFloatVal([]byte(yyDollar[1].str))
+		}
+	case 78:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = NewHexVal([]byte(yyDollar[1].str))
+		}
+	case 79:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = &NullVal{}
+		}
+	case 80:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.statement = &Delete{Table: yyDollar[3].tableName, Where: yyDollar[4].where}
+		}
+	case 81:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.statement = &Update{Table: yyDollar[2].tableName, Exprs: yyDollar[4].updateExprs, Where: yyDollar[5].where}
+		}
+	case 82:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.updateExprs = UpdateExprs{yyDollar[1].updateExpr}
+		}
+	case 83:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.updateExprs = append(yyVAL.updateExprs, yyDollar[3].updateExpr)
+		}
+	case 84:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.updateExpr = &UpdateExpr{Name: yyDollar[1].colIdent, Expr: yyDollar[3].expr}
+		}
+	case 85:
 		yyDollar = yyS[yypt-2 : yypt+1]
 		{
+			yyVAL.statement = &Pragma{Name: yyDollar[2].colIdent}
+		}
+	case 86:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.statement = &Pragma{Name: yyDollar[2].colIdent, Value: yyDollar[4].expr}
+		}
+	case 87:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.statement = &Pragma{Name: yyDollar[2].colIdent, Value: yyDollar[4].expr}
+		}
+	case 88:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.statement = &Vacuum{}
+		}
+	case 89:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.statement = &Vacuum{Into: yyDollar[3].str}
+		}
+	case 90:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = yyDollar[1].expr
+		}
+	case 91:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = &ColName{Name: NewColIdent("delete")}
+		}
+	case 92:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.expr = &ColName{Name: NewColIdent("on")}
+		}
+	case 93:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.where = nil
+		}
+	case 94:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.where = NewWhere(WhereStr, yyDollar[2].expr)
+		}
+	case 95:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyDollar[2].TableSpec.WithoutRowID = yyDollar[3].boolVal
 			yyDollar[1].ddl.TableSpec = yyDollar[2].TableSpec
 			yyVAL.statement = yyDollar[1].ddl
 		}
-	case 39:
+	case 96:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyDollar[1].ddl.IndexSpec.Columns = yyDollar[3].indexColumns
+			yyVAL.statement = yyDollar[1].ddl
+		}
+	case 97:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.ddl = &DDL{Action: CreateStr, NewName: yyDollar[4].tableName, IfNotExists: bool(yyDollar[3].boolVal)}
+			setDDL(yylex, yyVAL.ddl)
+		}
+	case 98:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.boolVal = BoolVal(false)
+		}
+	case 99:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.boolVal = BoolVal(true)
+		}
+	case 100:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.boolVal = BoolVal(false)
+		}
+	case 101:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			if !strings.EqualFold(yyDollar[2].str, "rowid") {
+				yylex.Error("unknown table option: " + yyDollar[2].str)
+				return 1
+			}
+			yyVAL.boolVal = BoolVal(true)
+		}
+	case 102:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.boolVal = BoolVal(false)
+		}
+	case 103:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.boolVal = BoolVal(true)
+		}
+	case 104:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.statement = &DDL{Action: DropStr, Table: yyDollar[4].tableName, IfExists: bool(yyDollar[3].boolVal)}
+		}
+	case 105:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.statement = &DDL{Action: DropIndexStr, IndexSpec: &IndexSpec{Name: yyDollar[4].colIdent}, IfExists: bool(yyDollar[3].boolVal)}
+		}
+	case 106:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.statement = &DDL{Action: RenameStr, Table: yyDollar[3].tableName, NewName: yyDollar[6].tableName}
+		}
+	case 107:
+		yyDollar = yyS[yypt-8 : yypt+1]
+		{
+			yyVAL.statement = &DDL{Action: RenameColumnStr, Table: yyDollar[3].tableName, Column: yyDollar[6].colIdent, NewColumn: yyDollar[8].colIdent}
+		}
+	case 108:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.statement = &DDL{Action: AddColumnStr, Table: yyDollar[3].tableName, TableSpec: &TableSpec{Columns: []*ColumnDefinition{yyDollar[6].columnDefinition}}}
+		}
+	case 109:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.statement = &DDL{Action: DropColumnStr, Table: yyDollar[3].tableName, Column: yyDollar[6].colIdent}
+		}
+	case 110:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+		}
+	case 111:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+		}
+	case 112:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.columnType = ColumnType{}
+		}
+	case 113:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
-			yyVAL.ddl = &DDL{Action: CreateStr, NewName: yyDollar[3].tableName}
+			yyVAL.columnType.NotNull = BoolVal(true)
+		}
+	case 114:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			if yyVAL.columnType.KeyOpt != ColKeyPrimary {
+				yyVAL.columnType.KeyOpt = ColKeyUnique
+			}
+		}
+	case 115:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.columnType.KeyOpt = ColKeyPrimary
+			yyVAL.columnType.Autoincrement = yyDollar[4].boolVal
+		}
+	case 116:
+		yyDollar = yyS[yypt-6 : yypt+1]
+		{
+			yyVAL.ddl = &DDL{Action: CreateIndexStr, Table: yyDollar[6].tableName, IndexSpec: &IndexSpec{Name: yyDollar[4].colIdent, Unique: yyDollar[2].boolVal}}
 			setDDL(yylex, yyVAL.ddl)
 		}
-	case 40:
+	case 117:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.boolVal = BoolVal(false)
+		}
+	case 118:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.boolVal = BoolVal(true)
+		}
+	case 119:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.indexColumns = []*IndexColumn{yyDollar[1].indexColumn}
+		}
+	case 120:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.indexColumns = append(yyVAL.indexColumns, yyDollar[3].indexColumn)
+		}
+	case 121:
+		yyDollar = yyS[yypt-2 : yypt+1]
+		{
+			yyVAL.indexColumn = &IndexColumn{Column: yyDollar[1].colIdent, Desc: yyDollar[2].boolVal}
+		}
+	case 122:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.boolVal = BoolVal(false)
+		}
+	case 123:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.boolVal = BoolVal(false)
+		}
+	case 124:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.boolVal = BoolVal(true)
+		}
+	case 125:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
 			yyVAL.TableSpec = yyDollar[2].TableSpec
 		}
-	case 41:
+	case 126:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.TableSpec = yyDollar[2].TableSpec
+		}
+	case 127:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.TableSpec = &TableSpec{}
 			yyVAL.TableSpec.AddColumn(yyDollar[1].columnDefinition)
 		}
-	case 42:
+	case 128:
 		yyDollar = yyS[yypt-3 : yypt+1]
 		{
 			yyVAL.TableSpec.AddColumn(yyDollar[3].columnDefinition)
 		}
-	case 43:
-		yyDollar = yyS[yypt-6 : yypt+1]
+	case 129:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.TableSpec.AddConstraint(yyDollar[3].tableConstraint)
+		}
+	case 130:
+		yyDollar = yyS[yypt-3 : yypt+1]
+		{
+			yyVAL.TableSpec.AddConstraint(yyDollar[3].tableConstraint)
+		}
+	case 131:
+		yyDollar = yyS[yypt-5 : yypt+1]
+		{
+			yyVAL.tableConstraint = &TableConstraint{PrimaryKey: true, Columns: yyDollar[4].indexColumns}
+		}
+	case 132:
+		yyDollar = yyS[yypt-4 : yypt+1]
+		{
+			yyVAL.tableConstraint = &TableConstraint{Columns: yyDollar[3].indexColumns}
+		}
+	case 133:
+		yyDollar = yyS[yypt-5 : yypt+1]
 		{
 			yyDollar[2].columnType.Default = yyDollar[3].optVal
 			yyDollar[2].columnType.OnUpdate = yyDollar[4].optVal
-			yyDollar[2].columnType.KeyOpt = yyDollar[5].colKeyOpt
-			yyDollar[2].columnType.Autoincrement = yyDollar[6].boolVal
+			yyDollar[2].columnType.NotNull = yyDollar[5].columnType.NotNull
+			yyDollar[2].columnType.KeyOpt = yyDollar[5].columnType.KeyOpt
+			yyDollar[2].columnType.Autoincrement = yyDollar[5].columnType.Autoincrement
 			yyVAL.columnDefinition = &ColumnDefinition{Name: NewColIdent(string(yyDollar[1].str)), Type: yyDollar[2].columnType}
 		}
-	case 44:
+	case 134:
+		yyDollar = yyS[yypt-0 : yypt+1]
+		{
+			yyVAL.columnType = ColumnType{}
+		}
+	case 135:
+		yyDollar = yyS[yypt-1 : yypt+1]
+		{
+			yyVAL.columnType = yyDollar[1].columnType
+		}
+	case 136:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.columnType = NewIntegerColumn()
 		}
-	case 45:
+	case 137:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.columnType = NewTextColumn()
 		}
-	case 46:
+	case 138:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.columnType = NewBlobColumn()
 		}
-	case 47:
+	case 139:
 		yyDollar = yyS[yypt-0 : yypt+1]
 		{
 			yyVAL.optVal = nil
 		}
-	case 48:
+	case 140:
 		yyDollar = yyS[yypt-0 : yypt+1]
 		{
 			yyVAL.optVal = nil
 		}
-	case 49:
-		yyDollar = yyS[yypt-0 : yypt+1]
-		{
-			yyVAL.colKeyOpt = ColKeyNone
-		}
-	case 50:
-		yyDollar = yyS[yypt-2 : yypt+1]
-		{
-			yyVAL.colKeyOpt = ColKeyPrimary
-		}
-	case 51:
+	case 141:
 		yyDollar = yyS[yypt-0 : yypt+1]
 		{
 			yyVAL.boolVal = BoolVal(false)
 		}
-	case 52:
+	case 142:
 		yyDollar = yyS[yypt-1 : yypt+1]
 		{
 			yyVAL.boolVal = BoolVal(true)
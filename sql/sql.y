@@ -1,6 +1,8 @@
 %{
 package sql
 
+import "strings"
+
 func setParseTree(yylex interface{}, stmt Statement) {
   yylex.(*Lexer).ParseTree = stmt
 }
@@ -30,8 +32,18 @@ func setDDL(yylex interface{}, ddl *DDL) {
  aliasedTableName *AliasedTableExpr
  columnDefinition *ColumnDefinition
  columnType    ColumnType
- colKeyOpt     ColumnKeyOption
+ tableConstraint  *TableConstraint
+ indexSpec     *IndexSpec
+ indexColumns  []*IndexColumn
+ indexColumn   *IndexColumn
  ddl           *DDL
+ columns       Columns
+ values        Values
+ valTuple      ValTuple
+ exprs         Exprs
+ where         *Where
+ updateExprs   UpdateExprs
+ updateExpr    *UpdateExpr
  optVal        *SQLVal
  boolVal       BoolVal
 
@@ -41,6 +53,14 @@ func setDDL(yylex interface{}, ddl *DDL) {
 
 %type <statement> command
 %type <statement> create_statement
+%type <statement> insert_statement
+%type <statement> delete_statement
+%type <statement> update_statement
+%type <statement> pragma_statement
+%type <statement> vacuum_statement
+%type <statement> drop_statement
+%type <statement> alter_statement
+%type <expr> pragma_value
 %type <selStmt> select_statement
 %type <selStmt> base_select
 %type <selectExprs> select_expression_list
@@ -66,12 +86,40 @@ func setDDL(yylex interface{}, ddl *DDL) {
 %type <ddl> create_table_prefix
 %type <TableSpec> table_spec
 %type <TableSpec> table_column_list
+%type <TableSpec> table_constraint_list
+%type <tableConstraint> table_constraint
 %type <columnType> column_type
+%type <columnType> column_type_opt
+%type <columnType> column_constraint_list
 %type <optVal> column_default_opt
 %type <optVal> on_update_opt
 %type <boolVal> autoincrement_opt
-%type <colKeyOpt> column_key_opt
-
+%type <ddl> create_index_prefix
+%type <boolVal> unique_opt
+%type <boolVal> not_exists_opt
+%type <boolVal> exists_opt
+%type <boolVal> without_rowid_opt
+%type <boolVal> asc_desc_opt
+%type <indexColumns> index_column_list
+%type <indexColumn> index_column
+%type <columns> column_list_opt
+%type <columns> column_list
+%type <values> tuple_list
+%type <valTuple> row_tuple
+%type <exprs> value_list
+%type <expr> literal_value
+%type <where> where_expression_opt
+%type <updateExprs> update_list
+%type <updateExpr> update_expression
+
+%left OR
+%left AND
+%right NOT
+%left '=' NE IS
+%left '<' '>' LE GE
+%left '+' '-'
+%left STAR '/' '%'
+%right UNARY
 %nonassoc <bytes> '.'
 
 %token SELECT
@@ -89,9 +137,37 @@ func setDDL(yylex interface{}, ddl *DDL) {
 %token KEY
 %token AUTOINCREMENT
 %token TABLE
+%token INDEX
+%token UNIQUE
+%token ON
+%token ASC
+%token DESC
+%token INSERT
+%token INTO
+%token VALUES
+%token NULL
+%token DELETE
+%token UPDATE
+%token SET
+%token WHERE
+%token PRAGMA
+%token VACUUM
+%token IF
+%token EXISTS
+%token DROP
+%token ALTER
+%token RENAME
+%token TO
+%token ADD
+%token COLUMN
+%token WITHOUT
+%token LEX_ERROR
 %token <str> STAR
 %token <str> IDENTIFIER
-%token <byte> STRING
+%token <str> STRING
+%token <str> INTEGRAL
+%token <str> FLOAT
+%token <str> HEXBLOB
 
 %start any_command
 
@@ -113,6 +189,13 @@ command:
     $$ = $1
   }
   | create_statement
+  | insert_statement
+  | delete_statement
+  | update_statement
+  | pragma_statement
+  | vacuum_statement
+  | drop_statement
+  | alter_statement
 
 
 select_statement:
@@ -153,6 +236,78 @@ expression:
     {
        $$ = $1
     }
+  | expression OR expression
+    {
+      $$ = &OrExpr{Left: $1, Right: $3}
+    }
+  | expression AND expression
+    {
+      $$ = &AndExpr{Left: $1, Right: $3}
+    }
+  | NOT expression
+    {
+      $$ = &NotExpr{Expr: $2}
+    }
+  | expression '=' expression
+    {
+      $$ = &ComparisonExpr{Left: $1, Operator: EqualStr, Right: $3}
+    }
+  | expression NE expression
+    {
+      $$ = &ComparisonExpr{Left: $1, Operator: NotEqualStr, Right: $3}
+    }
+  | expression '<' expression
+    {
+      $$ = &ComparisonExpr{Left: $1, Operator: LessThanStr, Right: $3}
+    }
+  | expression '>' expression
+    {
+      $$ = &ComparisonExpr{Left: $1, Operator: GreaterThanStr, Right: $3}
+    }
+  | expression LE expression
+    {
+      $$ = &ComparisonExpr{Left: $1, Operator: LessEqualStr, Right: $3}
+    }
+  | expression GE expression
+    {
+      $$ = &ComparisonExpr{Left: $1, Operator: GreaterEqualStr, Right: $3}
+    }
+  | expression IS NULL
+    {
+      $$ = &IsExpr{Operator: IsNullStr, Expr: $1}
+    }
+  | expression IS NOT NULL
+    {
+      $$ = &IsExpr{Operator: IsNotNullStr, Expr: $1}
+    }
+  | expression '+' expression
+    {
+      $$ = &BinaryExpr{Left: $1, Operator: PlusStr, Right: $3}
+    }
+  | expression '-' expression
+    {
+      $$ = &BinaryExpr{Left: $1, Operator: MinusStr, Right: $3}
+    }
+  | expression STAR expression
+    {
+      $$ = &BinaryExpr{Left: $1, Operator: MultStr, Right: $3}
+    }
+  | expression '/' expression
+    {
+      $$ = &BinaryExpr{Left: $1, Operator: DivStr, Right: $3}
+    }
+  | expression '%' expression
+    {
+      $$ = &BinaryExpr{Left: $1, Operator: ModStr, Right: $3}
+    }
+  | '-' expression %prec UNARY
+    {
+      $$ = &UnaryExpr{Operator: UMinusStr, Expr: $2}
+    }
+  | '+' expression %prec UNARY
+    {
+      $$ = &UnaryExpr{Operator: UPlusStr, Expr: $2}
+    }
 
 value_expression:
   column_name
@@ -164,6 +319,16 @@ value_expression:
   {
     $$ = $1
   }
+  |
+  literal_value
+  {
+    $$ = $1
+  }
+  |
+  LPAREN expression RPAREN
+  {
+    $$ = &ParenExpr{Expr: $2}
+  }
 
 column_name:
   sql_id
@@ -286,25 +451,313 @@ table_alias:
     $$ = $1
   }
 
+insert_statement:
+  INSERT INTO table_name column_list_opt VALUES tuple_list
+  {
+    $$ = &Insert{Table: $3, Columns: $4, Rows: $6}
+  }
+
+column_list_opt:
+  {
+    $$ = nil
+  }
+  | LPAREN column_list RPAREN
+  {
+    $$ = $2
+  }
+
+column_list:
+  sql_id
+  {
+    $$ = Columns{$1}
+  }
+  | column_list COMMA sql_id
+  {
+    $$ = append($$, $3)
+  }
+
+tuple_list:
+  row_tuple
+  {
+    $$ = Values{$1}
+  }
+  | tuple_list COMMA row_tuple
+  {
+    $$ = append($$, $3)
+  }
+
+row_tuple:
+  LPAREN value_list RPAREN
+  {
+    $$ = ValTuple($2)
+  }
+
+value_list:
+  expression
+  {
+    $$ = Exprs{$1}
+  }
+  | value_list COMMA expression
+  {
+    $$ = append($$, $3)
+  }
+
+literal_value:
+  STRING
+  {
+    $$ = NewStrVal([]byte($1))
+  }
+  | INTEGRAL
+  {
+    $$ = NewIntVal([]byte($1))
+  }
+  | FLOAT
+  {
+    $$ = NewFloatVal([]byte($1))
+  }
+  | HEXBLOB
+  {
+    $$ = NewHexVal([]byte($1))
+  }
+  | NULL
+  {
+    $$ = &NullVal{}
+  }
+
+delete_statement:
+  DELETE FROM table_name where_expression_opt
+  {
+    $$ = &Delete{Table: $3, Where: $4}
+  }
+
+update_statement:
+  UPDATE table_name SET update_list where_expression_opt
+  {
+    $$ = &Update{Table: $2, Exprs: $4, Where: $5}
+  }
+
+update_list:
+  update_expression
+  {
+    $$ = UpdateExprs{$1}
+  }
+  | update_list COMMA update_expression
+  {
+    $$ = append($$, $3)
+  }
+
+update_expression:
+  sql_id '=' expression
+  {
+    $$ = &UpdateExpr{Name: $1, Expr: $3}
+  }
+
+pragma_statement:
+  PRAGMA sql_id
+  {
+    $$ = &Pragma{Name: $2}
+  }
+  | PRAGMA sql_id '=' pragma_value
+  {
+    $$ = &Pragma{Name: $2, Value: $4}
+  }
+  | PRAGMA sql_id LPAREN pragma_value RPAREN
+  {
+    $$ = &Pragma{Name: $2, Value: $4}
+  }
+
+vacuum_statement:
+  VACUUM
+  {
+    $$ = &Vacuum{}
+  }
+  | VACUUM INTO STRING
+  {
+    $$ = &Vacuum{Into: $3}
+  }
+
+pragma_value:
+  expression
+  {
+    $$ = $1
+  }
+  | DELETE
+  {
+    $$ = &ColName{Name: NewColIdent("delete")}
+  }
+  | ON
+  {
+    $$ = &ColName{Name: NewColIdent("on")}
+  }
+
+where_expression_opt:
+  {
+    $$ = nil
+  }
+  | WHERE expression
+  {
+    $$ = NewWhere(WhereStr, $2)
+  }
+
 create_statement:
-  create_table_prefix table_spec
+  create_table_prefix table_spec without_rowid_opt
   {
+    $2.WithoutRowID = $3
     $1.TableSpec = $2
     $$ = $1
   }
+  | create_index_prefix LPAREN index_column_list RPAREN
+  {
+    $1.IndexSpec.Columns = $3
+    $$ = $1
+  }
 
 create_table_prefix:
-  CREATE TABLE table_name
+  CREATE TABLE not_exists_opt table_name
   {
-    $$ = &DDL{Action: CreateStr, NewName: $3}
+    $$ = &DDL{Action: CreateStr, NewName: $4, IfNotExists: bool($3)}
     setDDL(yylex, $$)
   }
 
+not_exists_opt:
+  {
+    $$ = BoolVal(false)
+  }
+  | IF NOT EXISTS
+  {
+    $$ = BoolVal(true)
+  }
+
+without_rowid_opt:
+  {
+    $$ = BoolVal(false)
+  }
+  | WITHOUT IDENTIFIER
+  {
+    if !strings.EqualFold($2, "rowid") {
+      yylex.Error("unknown table option: " + $2)
+      return 1
+    }
+    $$ = BoolVal(true)
+  }
+
+exists_opt:
+  {
+    $$ = BoolVal(false)
+  }
+  | IF EXISTS
+  {
+    $$ = BoolVal(true)
+  }
+
+drop_statement:
+  DROP TABLE exists_opt table_name
+  {
+    $$ = &DDL{Action: DropStr, Table: $4, IfExists: bool($3)}
+  }
+  | DROP INDEX exists_opt sql_id
+  {
+    $$ = &DDL{Action: DropIndexStr, IndexSpec: &IndexSpec{Name: $4}, IfExists: bool($3)}
+  }
+
+alter_statement:
+  ALTER TABLE table_name RENAME TO table_name
+  {
+    $$ = &DDL{Action: RenameStr, Table: $3, NewName: $6}
+  }
+  | ALTER TABLE table_name RENAME column_opt sql_id TO sql_id
+  {
+    $$ = &DDL{Action: RenameColumnStr, Table: $3, Column: $6, NewColumn: $8}
+  }
+  | ALTER TABLE table_name ADD column_opt column_definition
+  {
+    $$ = &DDL{Action: AddColumnStr, Table: $3, TableSpec: &TableSpec{Columns: []*ColumnDefinition{$6}}}
+  }
+  | ALTER TABLE table_name DROP column_opt sql_id
+  {
+    $$ = &DDL{Action: DropColumnStr, Table: $3, Column: $6}
+  }
+
+column_opt:
+/*empty*/ {}
+| COLUMN {}
+
+// the constraints of a column in any order, a PRIMARY KEY column which is also UNIQUE
+// has a single automatic index so it's only kept as the PRIMARY KEY
+column_constraint_list:
+  {
+    $$ = ColumnType{}
+  }
+  | column_constraint_list NOT NULL
+  {
+    $$.NotNull = BoolVal(true)
+  }
+  | column_constraint_list UNIQUE
+  {
+    if $$.KeyOpt != ColKeyPrimary {
+      $$.KeyOpt = ColKeyUnique
+    }
+  }
+  | column_constraint_list PRIMARY KEY autoincrement_opt
+  {
+    $$.KeyOpt = ColKeyPrimary
+    $$.Autoincrement = $4
+  }
+
+create_index_prefix:
+  CREATE unique_opt INDEX sql_id ON table_name
+  {
+    $$ = &DDL{Action: CreateIndexStr, Table: $6, IndexSpec: &IndexSpec{Name: $4, Unique: $2}}
+    setDDL(yylex, $$)
+  }
+
+unique_opt:
+  {
+    $$ = BoolVal(false)
+  }
+  | UNIQUE
+  {
+    $$ = BoolVal(true)
+  }
+
+index_column_list:
+  index_column
+  {
+    $$ = []*IndexColumn{$1}
+  }
+  | index_column_list COMMA index_column
+  {
+    $$ = append($$, $3)
+  }
+
+index_column:
+  sql_id asc_desc_opt
+  {
+    $$ = &IndexColumn{Column: $1, Desc: $2}
+  }
+
+asc_desc_opt:
+  {
+    $$ = BoolVal(false)
+  }
+  | ASC
+  {
+    $$ = BoolVal(false)
+  }
+  | DESC
+  {
+    $$ = BoolVal(true)
+  }
+
 table_spec:
   LPAREN table_column_list RPAREN
   {
     $$ = $2
   }
+  | LPAREN table_constraint_list RPAREN
+  {
+    $$ = $2
+  }
 
 table_column_list:
   column_definition
@@ -318,16 +771,47 @@ table_column_list:
     $$.AddColumn($3)
   }
 
+// the table constraints come after every column
+table_constraint_list:
+  table_column_list COMMA table_constraint
+  {
+    $$.AddConstraint($3)
+  }
+  | table_constraint_list COMMA table_constraint
+  {
+    $$.AddConstraint($3)
+  }
+
+table_constraint:
+  PRIMARY KEY LPAREN index_column_list RPAREN
+  {
+    $$ = &TableConstraint{PrimaryKey: true, Columns: $4}
+  }
+  | UNIQUE LPAREN index_column_list RPAREN
+  {
+    $$ = &TableConstraint{Columns: $3}
+  }
+
 column_definition:
-  IDENTIFIER column_type column_default_opt on_update_opt column_key_opt autoincrement_opt
+  IDENTIFIER column_type_opt column_default_opt on_update_opt column_constraint_list
   {
     $2.Default = $3
     $2.OnUpdate = $4
-    $2.KeyOpt = $5
-    $2.Autoincrement = $6
+    $2.NotNull = $5.NotNull
+    $2.KeyOpt = $5.KeyOpt
+    $2.Autoincrement = $5.Autoincrement
     $$ = &ColumnDefinition{Name: NewColIdent(string($1)), Type: $2}
   }
 
+column_type_opt:
+  {
+    $$ = ColumnType{}
+  }
+  | column_type
+  {
+    $$ = $1
+  }
+
 column_type:
    INTEGER
    {
@@ -352,15 +836,6 @@ on_update_opt:
     $$ = nil
   }
 
-column_key_opt:
-  {
-    $$ = ColKeyNone
-  }
-  | PRIMARY KEY
-  {
-    $$ = ColKeyPrimary
-  }
-
 autoincrement_opt:
   {
     $$ = BoolVal(false)
@@ -234,6 +234,80 @@ func TestListSupportedSQl(t *testing.T) {
 				VindexCols: nil,
 			},
 		},
+		{
+			sql: "create table if not exists pears (name text)",
+			st: &DDL{
+				Action:      "create",
+				NewName:     TableName{Name: TableIdent{v: "pears"}},
+				IfNotExists: true,
+				TableSpec: &TableSpec{
+					Columns: []*ColumnDefinition{{Name: ColIdent{val: "name"}, Type: ColumnType{Type: "TEXT"}}},
+				},
+			},
+		},
+		{
+			sql: "CREATE TABLE plums (name text primary key, size integer) WITHOUT ROWID",
+			st: &DDL{
+				Action:  "create",
+				NewName: TableName{Name: TableIdent{v: "plums"}},
+				TableSpec: &TableSpec{
+					Columns: []*ColumnDefinition{
+						{Name: ColIdent{val: "name"}, Type: ColumnType{Type: "TEXT", KeyOpt: ColKeyPrimary}},
+						{Name: ColIdent{val: "size"}, Type: ColumnType{Type: "INTEGER"}},
+					},
+					WithoutRowID: true,
+				},
+			},
+		},
+		{
+			sql: "CREATE TABLE pears (id integer, name text not null unique, color text, PRIMARY KEY(id), UNIQUE(color, name desc))",
+			st: &DDL{
+				Action:  "create",
+				NewName: TableName{Name: TableIdent{v: "pears"}},
+				TableSpec: &TableSpec{
+					Columns: []*ColumnDefinition{
+						{Name: ColIdent{val: "id"}, Type: ColumnType{Type: "INTEGER"}},
+						{Name: ColIdent{val: "name"}, Type: ColumnType{Type: "TEXT", NotNull: true, KeyOpt: ColKeyUnique}},
+						{Name: ColIdent{val: "color"}, Type: ColumnType{Type: "TEXT"}},
+					},
+					Constraints: []*TableConstraint{
+						{PrimaryKey: true, Columns: []*IndexColumn{{Column: ColIdent{val: "id"}}}},
+						{Columns: []*IndexColumn{{Column: ColIdent{val: "color"}}, {Column: ColIdent{val: "name"}, Desc: true}}},
+					},
+				},
+			},
+		},
+
+		{
+			sql: "CREATE UNIQUE INDEX idx_apples_name on apples (name, color desc)",
+			st: &DDL{
+				Action: "create index",
+				Table: TableName{
+					Name: TableIdent{
+						v: "apples",
+					},
+				},
+				IndexSpec: &IndexSpec{
+					Name: ColIdent{
+						val: "idx_apples_name",
+					},
+					Unique: true,
+					Columns: []*IndexColumn{
+						{
+							Column: ColIdent{
+								val: "name",
+							},
+						},
+						{
+							Column: ColIdent{
+								val: "color",
+							},
+							Desc: true,
+						},
+					},
+				},
+			},
+		},
 
 		{
 			sql: "SELECT COUNT(*) as col FROM table_name as tn",
@@ -271,6 +345,162 @@ func TestListSupportedSQl(t *testing.T) {
 				Lock: "",
 			},
 		},
+		{
+			sql: "INSERT INTO apples (name, size, weight) VALUES ('it''s', -12, 1.5e3), (NULL, x'0aff', .5)",
+			st: &Insert{
+				Table: TableName{
+					Name: TableIdent{
+						v: "apples",
+					},
+				},
+				Columns: Columns{
+					ColIdent{val: "name"},
+					ColIdent{val: "size"},
+					ColIdent{val: "weight"},
+				},
+				Rows: Values{
+					ValTuple{NewStrVal([]byte("it's")), &UnaryExpr{Operator: UMinusStr, Expr: NewIntVal([]byte("12"))}, NewFloatVal([]byte("1.5e3"))},
+					ValTuple{&NullVal{}, NewHexVal([]byte("0aff")), NewFloatVal([]byte(".5"))},
+				},
+			},
+		},
+		{
+			sql: "DELETE FROM apples WHERE size > 10 AND NOT name IS NULL OR rowid = 1",
+			st: &Delete{
+				Table: TableName{Name: TableIdent{v: "apples"}},
+				Where: &Where{
+					Type: WhereStr,
+					Expr: &OrExpr{
+						Left: &AndExpr{
+							Left: &ComparisonExpr{
+								Left:     &ColName{Name: ColIdent{val: "size"}},
+								Operator: GreaterThanStr,
+								Right:    NewIntVal([]byte("10")),
+							},
+							Right: &NotExpr{Expr: &IsExpr{Operator: IsNullStr, Expr: &ColName{Name: ColIdent{val: "name"}}}},
+						},
+						Right: &ComparisonExpr{
+							Left:     &ColName{Name: ColIdent{val: "rowid"}},
+							Operator: EqualStr,
+							Right:    NewIntVal([]byte("1")),
+						},
+					},
+				},
+			},
+		},
+		{
+			sql: "UPDATE apples SET size = (size + 1) * 2, name = 'big' WHERE size <> -1",
+			st: &Update{
+				Table: TableName{Name: TableIdent{v: "apples"}},
+				Exprs: UpdateExprs{
+					{
+						Name: ColIdent{val: "size"},
+						Expr: &BinaryExpr{
+							Left: &ParenExpr{Expr: &BinaryExpr{
+								Left:     &ColName{Name: ColIdent{val: "size"}},
+								Operator: PlusStr,
+								Right:    NewIntVal([]byte("1")),
+							}},
+							Operator: MultStr,
+							Right:    NewIntVal([]byte("2")),
+						},
+					},
+					{Name: ColIdent{val: "name"}, Expr: NewStrVal([]byte("big"))},
+				},
+				Where: &Where{
+					Type: WhereStr,
+					Expr: &ComparisonExpr{
+						Left:     &ColName{Name: ColIdent{val: "size"}},
+						Operator: NotEqualStr,
+						Right:    &UnaryExpr{Operator: UMinusStr, Expr: NewIntVal([]byte("1"))},
+					},
+				},
+			},
+		},
+		{
+			sql: "PRAGMA journal_mode",
+			st:  &Pragma{Name: ColIdent{val: "journal_mode"}},
+		},
+		{
+			sql: "PRAGMA journal_mode = DELETE",
+			st:  &Pragma{Name: ColIdent{val: "journal_mode"}, Value: &ColName{Name: ColIdent{val: "delete"}}},
+		},
+		{
+			sql: "PRAGMA wal_checkpoint(TRUNCATE);",
+			st:  &Pragma{Name: ColIdent{val: "wal_checkpoint"}, Value: &ColName{Name: ColIdent{val: "TRUNCATE"}}},
+		},
+		{
+			sql: "VACUUM",
+			st:  &Vacuum{},
+		},
+		{
+			sql: "vacuum into 'backup.db';",
+			st:  &Vacuum{Into: "backup.db"},
+		},
+		{
+			sql: "CREATE TABLE sqlite_sequence(name,seq)",
+			st: &DDL{
+				Action:  CreateStr,
+				NewName: TableName{Name: TableIdent{v: "sqlite_sequence"}},
+				TableSpec: &TableSpec{Columns: []*ColumnDefinition{
+					{Name: ColIdent{val: "name"}},
+					{Name: ColIdent{val: "seq"}},
+				}},
+			},
+		},
+		{
+			sql: "DROP TABLE IF EXISTS apples",
+			st:  &DDL{Action: DropStr, Table: TableName{Name: TableIdent{v: "apples"}}, IfExists: true},
+		},
+		{
+			sql: "drop index apples_name",
+			st:  &DDL{Action: DropIndexStr, IndexSpec: &IndexSpec{Name: ColIdent{val: "apples_name"}}},
+		},
+		{
+			sql: `ALTER TABLE "old ""apples""" RENAME TO apples`,
+			st: &DDL{
+				Action:  RenameStr,
+				Table:   TableName{Name: TableIdent{v: `old "apples"`}},
+				NewName: TableName{Name: TableIdent{v: "apples"}},
+			},
+		},
+		{
+			sql: "alter table apples rename column name to title",
+			st: &DDL{
+				Action:    RenameColumnStr,
+				Table:     TableName{Name: TableIdent{v: "apples"}},
+				Column:    ColIdent{val: "name"},
+				NewColumn: ColIdent{val: "title"},
+			},
+		},
+		{
+			sql: "alter table apples add size integer",
+			st: &DDL{
+				Action: AddColumnStr,
+				Table:  TableName{Name: TableIdent{v: "apples"}},
+				TableSpec: &TableSpec{Columns: []*ColumnDefinition{
+					{Name: ColIdent{val: "size"}, Type: NewIntegerColumn()},
+				}},
+			},
+		},
+		{
+			sql: "alter table apples add column size integer not null unique",
+			st: &DDL{
+				Action: AddColumnStr,
+				Table:  TableName{Name: TableIdent{v: "apples"}},
+				TableSpec: &TableSpec{Columns: []*ColumnDefinition{
+					{Name: ColIdent{val: "size"}, Type: ColumnType{Type: "INTEGER", NotNull: true, KeyOpt: ColKeyUnique}},
+				}},
+			},
+		},
+		{
+			sql: "alter table apples drop column size;",
+			st: &DDL{
+				Action: DropColumnStr,
+				Table:  TableName{Name: TableIdent{v: "apples"}},
+				Column: ColIdent{val: "size"},
+			},
+		},
 	}
 	//supportedSQL := []string{
 	//,
@@ -294,3 +524,37 @@ func TestListSupportedSQl(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSyntaxError(t *testing.T) {
+	// an unknown character or an unterminated string must not end the statement early
+	for _, sql := range []string{
+		"UPDATE apples SET name = 'big' || name WHERE size > 10",
+		"DELETE FROM apples WHERE name = 'big",
+		"DELETE FROM apples WHERE size ! 1",
+		"CREATE TABLE plums (name text primary key) WITHOUT apples",
+		"CREATE TABLE plums (name text, UNIQUE(name), size integer)",
+	} {
+		t.Run(sql, func(t *testing.T) {
+			_, err := Parse(sql)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, err := Tokenize(`CREATE TABLE "my ""table""" (id integer)`)
+	require.NoError(t, err)
+	require.Equal(t, []Token{
+		{ID: CREATE, Value: "CREATE", Start: 0, End: 6},
+		{ID: TABLE, Value: "TABLE", Start: 7, End: 12},
+		{ID: IDENTIFIER, Value: `my "table"`, Start: 13, End: 27},
+		{ID: LPAREN, Value: "(", Start: 28, End: 29},
+		{ID: IDENTIFIER, Value: "id", Start: 29, End: 31},
+		{ID: INTEGER, Value: "integer", Start: 32, End: 39},
+		{ID: RPAREN, Value: ")", Start: 39, End: 40},
+	}, tokens)
+	require.Equal(t, `"my ""table"""`, QuoteIdentifier(`my "table"`))
+
+	_, err = Tokenize("SELECT 'name")
+	require.Error(t, err)
+}
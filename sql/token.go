@@ -17,4 +17,32 @@ var tokens = map[string]int{
 	"TEXT":          TEXT,
 	"AUTOINCREMENT": AUTOINCREMENT,
 	"TABLE":         TABLE,
+	"INDEX":         INDEX,
+	"UNIQUE":        UNIQUE,
+	"ON":            ON,
+	"ASC":           ASC,
+	"DESC":          DESC,
+	"INSERT":        INSERT,
+	"INTO":          INTO,
+	"VALUES":        VALUES,
+	"NULL":          NULL,
+	"DELETE":        DELETE,
+	"UPDATE":        UPDATE,
+	"SET":           SET,
+	"WHERE":         WHERE,
+	"PRAGMA":        PRAGMA,
+	"VACUUM":        VACUUM,
+	"IF":            IF,
+	"EXISTS":        EXISTS,
+	"DROP":          DROP,
+	"ALTER":         ALTER,
+	"RENAME":        RENAME,
+	"TO":            TO,
+	"ADD":           ADD,
+	"COLUMN":        COLUMN,
+	"WITHOUT":       WITHOUT,
+	"AND":           AND,
+	"OR":            OR,
+	"NOT":           NOT,
+	"IS":            IS,
 }
@@ -0,0 +1,97 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// FS is a read-only VFS over an fs.FS such as an embed.FS.
+type FS struct {
+	fsys fs.FS
+}
+
+func NewFS(fsys fs.FS) *FS {
+	return &FS{fsys: fsys}
+}
+
+func (f *FS) Open(name string, flags OpenFlag) (File, error) {
+	if flags&(OpenReadWrite|OpenCreate) != 0 {
+		return nil, ErrReadOnly
+	}
+	file, err := f.fsys.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if readerAt, ok := file.(io.ReaderAt); ok {
+		return &fsFile{file: file, readerAt: readerAt, size: info.Size()}, nil
+	}
+
+	// the file doesn't support random reads, it's loaded in memory instead
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	return &MemFile{data: &memData{data: data}, readOnly: true}, nil
+}
+
+func (f *FS) Delete(name string) error {
+	return ErrReadOnly
+}
+
+func (f *FS) Exists(name string) (bool, error) {
+	_, err := fs.Stat(f.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+type fsFile struct {
+	file     fs.File
+	readerAt io.ReaderAt
+	size     int64
+}
+
+func (f *fsFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.readerAt.ReadAt(p, off)
+}
+
+func (f *fsFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, ErrReadOnly
+}
+
+func (f *fsFile) Truncate(size int64) error {
+	return ErrReadOnly
+}
+
+func (f *fsFile) Sync() error {
+	return nil
+}
+
+func (f *fsFile) Size() (int64, error) {
+	return f.size, nil
+}
+
+// Lock always succeeds, nobody can write to an fs.FS.
+func (f *fsFile) Lock(level LockLevel) error {
+	return nil
+}
+
+func (f *fsFile) Unlock(level LockLevel) error {
+	return nil
+}
+
+func (f *fsFile) Close() error {
+	return f.file.Close()
+}
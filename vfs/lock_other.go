@@ -0,0 +1,15 @@
+//go:build !unix
+
+package vfs
+
+import "os"
+
+// lockFile doesn't lock anything on the platforms without flock,
+// the database can only be used by a single process there.
+func lockFile(file *os.File, level LockLevel) error {
+	return nil
+}
+
+func unlockFile(file *os.File, level LockLevel) error {
+	return nil
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package vfs
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile uses an advisory flock, a shared lock for the readers
+// and an exclusive one as soon as the connection intends to write.
+func lockFile(file *os.File, level LockLevel) error {
+	how := syscall.LOCK_SH
+	if level >= LockReserved {
+		how = syscall.LOCK_EX
+	}
+	err := syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrBusy
+	}
+	return err
+}
+
+func unlockFile(file *os.File, level LockLevel) error {
+	if level == LockShared {
+		return syscall.Flock(int(file.Fd()), syscall.LOCK_SH)
+	}
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,207 @@
+package vfs
+
+import (
+	"io"
+	"sync"
+)
+
+// MemVFS keeps every file in memory, the files live as long as the MemVFS.
+type MemVFS struct {
+	mu    sync.Mutex
+	files map[string]*memData
+}
+
+func NewMemVFS() *MemVFS {
+	return &MemVFS{files: map[string]*memData{}}
+}
+
+// AddFile stores data as the content of the file name, the slice is owned by the VFS afterward.
+func (m *MemVFS) AddFile(name string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[name] = &memData{data: data}
+}
+
+func (m *MemVFS) Open(name string, flags OpenFlag) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.files[name]
+	if !ok {
+		if flags&OpenCreate == 0 {
+			return nil, ErrNotExist
+		}
+		data = &memData{}
+		m.files[name] = data
+	}
+	return &MemFile{data: data, fs: m, name: name, readOnly: flags&OpenReadWrite == 0}, nil
+}
+
+func (m *MemVFS) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, name)
+	return nil
+}
+
+func (m *MemVFS) Exists(name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.files[name]
+	return ok, nil
+}
+
+// memData is the content of a memory file shared by all its handles.
+type memData struct {
+	mu        sync.RWMutex
+	data      []byte
+	shared    int
+	reserved  bool
+	exclusive bool
+}
+
+// MemFile is a file kept in memory.
+type MemFile struct {
+	data *memData
+	// fs and name are only set for the files opened from a MemVFS
+	fs       *MemVFS
+	name     string
+	readOnly bool
+	level    LockLevel
+}
+
+// NewMemFile returns a writable file backed by data, the slice is owned by the file afterward.
+func NewMemFile(data []byte) *MemFile {
+	return &MemFile{data: &memData{data: data}}
+}
+
+// VFS returns the MemVFS the file has been opened from, nil for a file created by NewMemFile.
+func (f *MemFile) VFS() VFS {
+	if f.fs == nil {
+		return nil
+	}
+	return f.fs
+}
+
+// Name returns the name of the file in its MemVFS, it's empty for a file created by NewMemFile.
+func (f *MemFile) Name() string {
+	return f.name
+}
+
+// Bytes returns the current content of the file.
+func (f *MemFile) Bytes() []byte {
+	f.data.mu.RLock()
+	defer f.data.mu.RUnlock()
+	return f.data.data
+}
+
+func (f *MemFile) ReadAt(p []byte, off int64) (int, error) {
+	f.data.mu.RLock()
+	defer f.data.mu.RUnlock()
+
+	if off >= int64(len(f.data.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.data.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *MemFile) WriteAt(p []byte, off int64) (int, error) {
+	if f.readOnly {
+		return 0, ErrReadOnly
+	}
+	f.data.mu.Lock()
+	defer f.data.mu.Unlock()
+
+	if end := off + int64(len(p)); end > int64(len(f.data.data)) {
+		f.data.data = append(f.data.data, make([]byte, end-int64(len(f.data.data)))...)
+	}
+	return copy(f.data.data[off:], p), nil
+}
+
+func (f *MemFile) Truncate(size int64) error {
+	if f.readOnly {
+		return ErrReadOnly
+	}
+	f.data.mu.Lock()
+	defer f.data.mu.Unlock()
+
+	if size <= int64(len(f.data.data)) {
+		f.data.data = f.data.data[:size]
+		return nil
+	}
+	f.data.data = append(f.data.data, make([]byte, size-int64(len(f.data.data)))...)
+	return nil
+}
+
+func (f *MemFile) Sync() error {
+	return nil
+}
+
+func (f *MemFile) Size() (int64, error) {
+	f.data.mu.RLock()
+	defer f.data.mu.RUnlock()
+	return int64(len(f.data.data)), nil
+}
+
+// Lock follows the sqlite locking rules between the handles of the same file:
+// many SHARED locks, at most one RESERVED lock and an EXCLUSIVE lock once the others are gone.
+func (f *MemFile) Lock(level LockLevel) error {
+	if level <= f.level {
+		return nil
+	}
+	f.data.mu.Lock()
+	defer f.data.mu.Unlock()
+
+	if f.level == LockNone && f.data.exclusive {
+		return ErrBusy
+	}
+	if level >= LockReserved && f.level < LockReserved && f.data.reserved {
+		return ErrBusy
+	}
+
+	if f.level == LockNone {
+		f.data.shared++
+		f.level = LockShared
+	}
+	if level >= LockReserved && f.level < LockReserved {
+		f.data.reserved = true
+		f.level = LockReserved
+	}
+	if level == LockExclusive {
+		if f.data.shared > 1 {
+			f.level = LockPending
+			return ErrBusy
+		}
+		f.data.exclusive = true
+	}
+	f.level = level
+	return nil
+}
+
+func (f *MemFile) Unlock(level LockLevel) error {
+	if level >= f.level {
+		return nil
+	}
+	f.data.mu.Lock()
+	defer f.data.mu.Unlock()
+
+	if f.level == LockExclusive {
+		f.data.exclusive = false
+	}
+	if f.level >= LockReserved {
+		f.data.reserved = false
+	}
+	if level == LockNone {
+		f.data.shared--
+	}
+	f.level = level
+	return nil
+}
+
+func (f *MemFile) Close() error {
+	return f.Unlock(LockNone)
+}
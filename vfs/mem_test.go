@@ -0,0 +1,48 @@
+package vfs
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMemFileLocking(t *testing.T) {
+	fs := NewMemVFS()
+	reader, err := fs.Open("test.db", OpenReadWrite|OpenCreate)
+	require.NoError(t, err)
+	writer, err := fs.Open("test.db", OpenReadWrite)
+	require.NoError(t, err)
+
+	require.NoError(t, reader.Lock(LockShared))
+	require.NoError(t, writer.Lock(LockReserved))
+
+	// only one connection can intend to write
+	require.ErrorIs(t, reader.Lock(LockReserved), ErrBusy)
+
+	// the writer waits for the readers to leave
+	require.ErrorIs(t, writer.Lock(LockExclusive), ErrBusy)
+	require.NoError(t, reader.Unlock(LockNone))
+	require.NoError(t, writer.Lock(LockExclusive))
+
+	// nobody can read while the writer holds the exclusive lock
+	require.ErrorIs(t, reader.Lock(LockShared), ErrBusy)
+	require.NoError(t, writer.Unlock(LockShared))
+	require.NoError(t, reader.Lock(LockShared))
+}
+
+func TestMemFileReadWrite(t *testing.T) {
+	file := NewMemFile(nil)
+	_, err := file.WriteAt([]byte("sqlite"), 4)
+	require.NoError(t, err)
+
+	size, err := file.Size()
+	require.NoError(t, err)
+	require.Equal(t, int64(10), size)
+
+	buf := make([]byte, 6)
+	n, err := file.ReadAt(buf, 4)
+	require.NoError(t, err)
+	require.Equal(t, "sqlite", string(buf[:n]))
+
+	require.NoError(t, file.Truncate(5))
+	require.Equal(t, []byte{0, 0, 0, 0, 's'}, file.Bytes())
+}
@@ -0,0 +1,126 @@
+package vfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+)
+
+// OS is the VFS of the operating system files.
+var OS VFS = osVFS{}
+
+type osVFS struct{}
+
+func (osVFS) Open(name string, flags OpenFlag) (File, error) {
+	osFlags := os.O_RDONLY
+	if flags&OpenReadWrite != 0 {
+		osFlags = os.O_RDWR
+	}
+	if flags&OpenCreate != 0 {
+		osFlags |= os.O_CREATE
+	}
+
+	file, err := os.OpenFile(name, osFlags, 0644)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &osFile{file: file, name: name, readOnly: flags&OpenReadWrite == 0}, nil
+}
+
+func (osVFS) Delete(name string) error {
+	err := os.Remove(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (osVFS) Exists(name string) (bool, error) {
+	_, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NewOSFile wraps an already opened file.
+func NewOSFile(file *os.File) File {
+	return &osFile{file: file, name: file.Name()}
+}
+
+type osFile struct {
+	file     *os.File
+	name     string
+	readOnly bool
+	level    LockLevel
+}
+
+func (f *osFile) VFS() VFS {
+	return OS
+}
+
+func (f *osFile) Name() string {
+	return f.name
+}
+
+func (f *osFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.file.ReadAt(p, off)
+}
+
+func (f *osFile) WriteAt(p []byte, off int64) (int, error) {
+	if f.readOnly {
+		return 0, ErrReadOnly
+	}
+	return f.file.WriteAt(p, off)
+}
+
+func (f *osFile) Truncate(size int64) error {
+	if f.readOnly {
+		return ErrReadOnly
+	}
+	return f.file.Truncate(size)
+}
+
+func (f *osFile) Sync() error {
+	if f.readOnly {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+func (f *osFile) Size() (int64, error) {
+	info, err := f.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (f *osFile) Lock(level LockLevel) error {
+	if level <= f.level {
+		return nil
+	}
+	if err := lockFile(f.file, level); err != nil {
+		return err
+	}
+	f.level = level
+	return nil
+}
+
+func (f *osFile) Unlock(level LockLevel) error {
+	if level >= f.level {
+		return nil
+	}
+	if err := unlockFile(f.file, level); err != nil {
+		return err
+	}
+	f.level = level
+	return nil
+}
+
+func (f *osFile) Close() error {
+	return f.file.Close()
+}
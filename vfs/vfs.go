@@ -0,0 +1,82 @@
+// Package vfs abstracts the storage the database files are read from and written to,
+// so a database can live on disk, in memory, in an embed.FS or in any custom storage.
+package vfs
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	// ErrReadOnly is returned when writing to a storage opened read-only.
+	ErrReadOnly = errors.New("vfs: read-only file")
+	// ErrBusy is returned when a lock is held by another connection.
+	ErrBusy = errors.New("vfs: database is locked")
+	// ErrNotExist is returned when opening a missing file without OpenCreate.
+	ErrNotExist = errors.New("vfs: file does not exist")
+)
+
+// File is an opened database, journal or wal file.
+type File interface {
+	io.ReaderAt
+	io.WriterAt
+	Truncate(size int64) error
+	Sync() error
+	Size() (int64, error)
+	// Lock raises the lock of the file to the given level, it never lowers it.
+	Lock(level LockLevel) error
+	// Unlock lowers the lock of the file to the given level, either LockShared or LockNone.
+	Unlock(level LockLevel) error
+	Close() error
+}
+
+// NamedFile is a file which knows the storage and the name it has been opened with,
+// the journal of a database is found next to it.
+type NamedFile interface {
+	File
+	VFS() VFS
+	Name() string
+}
+
+// VFS opens and manages the files of a storage.
+type VFS interface {
+	Open(name string, flags OpenFlag) (File, error)
+	Delete(name string) error
+	Exists(name string) (bool, error)
+}
+
+type OpenFlag int
+
+const (
+	OpenReadOnly OpenFlag = 1 << iota
+	OpenReadWrite
+	OpenCreate
+)
+
+// LockLevel are the sqlite file locking states,
+// see https://www.sqlite.org/lockingv3.html
+type LockLevel int
+
+const (
+	LockNone LockLevel = iota
+	LockShared
+	LockReserved
+	LockPending
+	LockExclusive
+)
+
+func (l LockLevel) String() string {
+	names := map[LockLevel]string{
+		LockNone:      "NONE",
+		LockShared:    "SHARED",
+		LockReserved:  "RESERVED",
+		LockPending:   "PENDING",
+		LockExclusive: "EXCLUSIVE",
+	}
+
+	s, ok := names[l]
+	if ok {
+		return s
+	}
+	return "unknown"
+}